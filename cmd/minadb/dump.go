@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joacominatel/minadb/internal/app"
+	"github.com/joacominatel/minadb/internal/config"
+)
+
+// runDump implements `minadb dump`, which writes the schema as DDL
+// without needing pg_dump.
+func runDump(service *app.Service, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	dsn := fs.String("dsn", "", "PostgreSQL connection string")
+	conn := fs.String("conn", "", "name of a saved connection (default: the configured default)")
+	schemas := fs.String("schema", "", "comma-separated schemas to dump (default: all user schemas)")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: minadb dump [flags]")
+		fmt.Fprintln(fs.Output(), "Write the schema as dependency-ordered DDL.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	target, err := resolveDSN(cfg, *dsn, *conn)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := service.Connect(ctx, target); err != nil {
+		return err
+	}
+	defer service.Disconnect()
+
+	script, err := service.DumpSchema(ctx, splitList(*schemas))
+	if err != nil {
+		return fmt.Errorf("dump schema: %w", err)
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.WriteString(script)
+		return err
+	}
+	return os.WriteFile(*out, []byte(script), 0644)
+}
+
+// splitList parses a comma-separated flag value.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// resolveDSN picks the connection for a subcommand: an explicit DSN, then
+// a saved connection by name, then the configured default.
+func resolveDSN(cfg *config.Config, dsn, name string) (string, error) {
+	if dsn != "" {
+		return dsn, nil
+	}
+	if name != "" {
+		conn, ok := cfg.Connection(name)
+		if !ok {
+			return "", fmt.Errorf("no saved connection named %q", name)
+		}
+		return conn.DSN(), nil
+	}
+	if conn := config.DefaultConnection(cfg); conn != nil {
+		return conn.DSN(), nil
+	}
+	return "", fmt.Errorf("no connection given: use -dsn or -conn")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joacominatel/minadb/internal/app"
+	"github.com/joacominatel/minadb/internal/config"
+	"github.com/joacominatel/minadb/internal/erd"
+)
+
+// runERD implements `minadb erd`, which writes an entity-relationship
+// diagram as Mermaid, Graphviz DOT or PlantUML text.
+func runERD(service *app.Service, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("erd", flag.ExitOnError)
+	dsn := fs.String("dsn", "", "PostgreSQL connection string")
+	conn := fs.String("conn", "", "name of a saved connection (default: the configured default)")
+	schemas := fs.String("schema", "", "comma-separated schemas to include (default: all user schemas)")
+	tables := fs.String("tables", "", "comma-separated tables to include, bare or schema-qualified (default: all)")
+	format := fs.String("format", "mermaid", "diagram format: mermaid, dot or plantuml")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: minadb erd [flags]")
+		fmt.Fprintln(fs.Output(), "Write an entity-relationship diagram of the schema.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	f, err := erd.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	target, err := resolveDSN(cfg, *dsn, *conn)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := service.Connect(ctx, target); err != nil {
+		return err
+	}
+	defer service.Disconnect()
+
+	cat, err := service.LoadCatalog(ctx, splitList(*schemas))
+	if err != nil {
+		return fmt.Errorf("load schema: %w", err)
+	}
+
+	var include func(schema, table string) bool
+	if names := splitList(*tables); len(names) > 0 {
+		want := make(map[string]bool, len(names))
+		for _, n := range names {
+			want[n] = true
+		}
+		include = func(schema, table string) bool {
+			return want[table] || want[schema+"."+table]
+		}
+	}
+	text := erd.Build(cat, include).Render(f)
+
+	if *out == "" {
+		_, err = os.Stdout.WriteString(text)
+		return err
+	}
+	return os.WriteFile(*out, []byte(text), 0644)
+}
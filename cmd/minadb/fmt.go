@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/joacominatel/minadb/internal/config"
+	"github.com/joacominatel/minadb/internal/sql"
+)
+
+// runFmt implements `minadb fmt`, which pretty-prints SQL files with the
+// same formatter as Ctrl+L in the editor.
+func runFmt(cfg *config.Config, args []string) error {
+	prefs := cfg.Preferences.Format
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result to the files instead of stdout")
+	check := fs.Bool("check", false, "list files that are not formatted and fail if there are any")
+	indent := fs.Int("indent", prefs.Options().Indent, "spaces per indentation level")
+	keywordCase := fs.String("case", prefs.KeywordCase, "keyword case: upper, lower or preserve (default from preferences)")
+	commas := fs.String("commas", prefs.Commas, "list comma placement: trailing or leading (default from preferences)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: minadb fmt [flags] [file.sql ...]")
+		fmt.Fprintln(fs.Output(), "Format SQL files, or stdin when no file is given.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	opts := config.Format{IndentWidth: *indent, KeywordCase: *keywordCase, Commas: *commas}.Options()
+
+	if fs.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.WriteString(formatFile(string(src), opts))
+		return err
+	}
+
+	var unformatted int
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out := formatFile(string(src), opts)
+		switch {
+		case *check:
+			if out != string(src) {
+				fmt.Println(path)
+				unformatted++
+			}
+		case *write:
+			if out != string(src) {
+				if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+					return err
+				}
+			}
+		default:
+			if _, err := os.Stdout.WriteString(out); err != nil {
+				return err
+			}
+		}
+	}
+	if unformatted > 0 {
+		return fmt.Errorf("%d file(s) need formatting", unformatted)
+	}
+	return nil
+}
+
+// formatFile formats a whole file, ending it with a newline.
+func formatFile(src string, opts sql.FormatOptions) string {
+	out := sql.Format(src, opts)
+	if out == "" {
+		return ""
+	}
+	return out + "\n"
+}
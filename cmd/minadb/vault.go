@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/joacominatel/minadb/internal/app"
+	"github.com/joacominatel/minadb/internal/config"
+	"github.com/joacominatel/minadb/internal/vault"
+)
+
+// newPassphraseEnv supplies the new passphrase to `minadb vault rotate`
+// without a prompt.
+const newPassphraseEnv = "MINADB_NEW_PASSPHRASE"
+
+// runVault implements `minadb vault`, which manages the encryption of
+// history and cached schema data.
+func runVault(service *app.Service, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("vault", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: minadb vault <command> [flags]")
+		fmt.Fprintln(fs.Output(), "Manage the encryption of history and cached schema data.")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "Commands:")
+		fmt.Fprintln(fs.Output(), "  status   show how local data is encrypted")
+		fmt.Fprintln(fs.Output(), "  rotate   re-encrypt local data with a new key")
+		fmt.Fprintln(fs.Output(), "  export   write local data in plain text")
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	switch fs.Arg(0) {
+	case "status":
+		return vaultStatus(service, cfg)
+	case "rotate":
+		return vaultRotate(service, cfg, fs.Args()[1:])
+	case "export":
+		return vaultExport(service, cfg, fs.Args()[1:])
+	}
+	return fmt.Errorf("unknown command %q", fs.Arg(0))
+}
+
+func vaultStatus(service *app.Service, cfg *config.Config) error {
+	v, err := openVault(service, cfg)
+	if err != nil {
+		return err
+	}
+	path, _ := config.VaultPath()
+	switch v.Source() {
+	case vault.None:
+		fmt.Println("encryption: none, local data is stored in plain text")
+	case vault.Keyring:
+		fmt.Printf("encryption: AES-256-GCM, key in the OS keyring\nkey id:     %s\n", v.KeyID())
+	case vault.Passphrase:
+		fmt.Printf("encryption: AES-256-GCM, key derived from a passphrase\nkey id:     %s\n", v.KeyID())
+	}
+	if v.Encrypted() {
+		fmt.Printf("vault:      %s\n", path)
+	}
+	if v.Rotating() {
+		fmt.Println("an interrupted rotation left data under the previous key: run `minadb vault rotate` again")
+	}
+	fmt.Printf("history:    %d queries\n", len(service.History()))
+	return nil
+}
+
+func vaultRotate(service *app.Service, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("vault rotate", flag.ExitOnError)
+	source := fs.String("source", "", "where the new key comes from: keyring, passphrase or none (default: unchanged)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: minadb vault rotate [flags]")
+		fmt.Fprintln(fs.Output(), "Re-encrypt history and cached schema data with a new key.")
+		fmt.Fprintf(fs.Output(), "A new passphrase is read from %s or asked for.\n", newPassphraseEnv)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	old, err := openVault(service, cfg)
+	if err != nil {
+		return err
+	}
+	src := old.Source()
+	if *source != "" {
+		if src, err = vault.ParseSource(*source); err != nil {
+			return err
+		}
+	}
+
+	passphrase := ""
+	if src == vault.Passphrase {
+		if passphrase, err = newPassphrase(newPassphraseEnv); err != nil {
+			return err
+		}
+	}
+	v, err := vault.Rotate(old, src, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := service.RekeyData(v); err != nil {
+		return fmt.Errorf("%w (run the command again to finish)", err)
+	}
+	if v.Encrypted() {
+		fmt.Fprintf(os.Stderr, "Local data re-encrypted with key %s\n", v.KeyID())
+	} else {
+		fmt.Fprintln(os.Stderr, "Local data is now stored in plain text")
+	}
+	return nil
+}
+
+func vaultExport(service *app.Service, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("vault export", flag.ExitOnError)
+	out := fs.String("o", "", "directory to write to (required)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: minadb vault export -o DIR")
+		fmt.Fprintln(fs.Output(), "Write the query history and schema caches, decrypted, as JSON.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *out == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if _, err := openVault(service, cfg); err != nil {
+		return err
+	}
+	files, err := service.ExportData(*out)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Fprintln(os.Stderr, "Wrote", f)
+	}
+	return nil
+}
+
+// openVault unlocks local data for the service, asking for the
+// passphrase on the terminal when it is needed and not in the
+// environment.
+func openVault(service *app.Service, cfg *config.Config) (*vault.Vault, error) {
+	prefs := cfg.Preferences.Encryption
+	v, err := vault.Load(prefs, os.Getenv(vault.PassphraseEnv))
+	var passphrase string
+	switch {
+	case errors.Is(err, vault.ErrLocked):
+		if passphrase, err = readPassphrase("Passphrase: ", vault.PassphraseEnv); err != nil {
+			return nil, err
+		}
+		v, err = vault.Load(prefs, passphrase)
+	case errors.Is(err, vault.ErrNewPassphrase):
+		if passphrase, err = newPassphrase(vault.PassphraseEnv); err != nil {
+			return nil, err
+		}
+		v, err = vault.Load(prefs, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := service.UseVault(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// newPassphrase reads the passphrase for a new key from env, or asks for
+// it twice.
+func newPassphrase(env string) (string, error) {
+	if p := os.Getenv(env); p != "" {
+		return p, nil
+	}
+	p, err := readPassphrase("New passphrase: ", env)
+	if err != nil {
+		return "", err
+	}
+	again, err := readPassphrase("Repeat passphrase: ", env)
+	if err != nil {
+		return "", err
+	}
+	if p != again {
+		return "", errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+// readPassphrase prompts on stderr and reads a line from the terminal
+// without echoing it. Without a terminal, env names the variable to set
+// instead.
+func readPassphrase(prompt, env string) (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return "", fmt.Errorf("no terminal to ask for a passphrase: set %s", env)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	p, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(p) == 0 {
+		return "", errors.New("empty passphrase")
+	}
+	return string(p), nil
+}
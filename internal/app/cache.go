@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/joacominatel/minadb/internal/config"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/vault"
+)
+
+// cacheFormat is bumped whenever the cached data changes shape, so caches
+// written by older versions are ignored instead of misread.
+const cacheFormat = 1
+
+// schemaCache is the on-disk copy of a connection's schema tree and the
+// column metadata fetched so far.
+type schemaCache struct {
+	Format   int                          `json:"format"`
+	Database string                       `json:"database"`
+	Schemas  []SchemaNode                 `json:"schemas"`
+	Version  *database.SchemaVersion      `json:"version"`
+	Columns  map[string][]database.Column `json:"columns"` // schema.table -> columns
+	SavedAt  time.Time                    `json:"saved_at"`
+
+	mu    sync.Mutex
+	path  string
+	vault *vault.Vault
+	dirty bool
+}
+
+// SchemaRefresh is the outcome of checking the schema tree for changes.
+type SchemaRefresh struct {
+	Tree    *SchemaTree
+	Changed bool     // the tree or column metadata differs from the cached copy
+	Stale   []string // schema.table of tables whose cached columns were dropped
+}
+
+// cachePath returns the cache file for a DSN. The file name is a hash of
+// the DSN without its password, so it identifies the server, database
+// and user but leaks none of them. Returns "" when caching is
+// unavailable, including before the service has a vault.
+func (s *Service) cachePath(dsn string) string {
+	if s.vault == nil {
+		return ""
+	}
+	conn, err := config.ParseDSN(dsn)
+	if err != nil {
+		return ""
+	}
+	conn.Password = ""
+	dir, err := config.CacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(conn.DSN()))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// readCache loads a cache file sealed with v. A missing or unreadable
+// file, or one sealed with a key v does not have, yields an empty cache,
+// which is simply filled on the next refresh. A plain text cache written
+// before encryption was enabled is read and sealed on the next save.
+func readCache(path string, v *vault.Vault) *schemaCache {
+	c := &schemaCache{path: path, vault: v}
+	if path == "" {
+		return c
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	data, err := v.Open(raw)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, c); err != nil || c.Format != cacheFormat {
+		return &schemaCache{path: path, vault: v}
+	}
+	c.dirty = v.Encrypted() && !vault.IsSealed(raw)
+	return c
+}
+
+// save writes the cache if it changed since it was last written.
+func (c *schemaCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+	c.Format = cacheFormat
+	c.SavedAt = time.Now()
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if data, err = c.vault.Seal(data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	// write then rename so a crash never leaves a truncated cache behind
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+func (c *schemaCache) tree() *SchemaTree {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Version == nil {
+		return nil
+	}
+	return &SchemaTree{Database: c.Database, Schemas: slices.Clone(c.Schemas)}
+}
+
+func (c *schemaCache) columns(schema, table string) ([]database.Column, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cols, ok := c.Columns[schema+"."+table]
+	return cols, ok
+}
+
+func (c *schemaCache) setColumns(schema, table string, cols []database.Column) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Columns == nil {
+		c.Columns = make(map[string][]database.Column)
+	}
+	c.Columns[schema+"."+table] = cols
+	c.dirty = true
+}
+
+// CachedSchemaTree returns the schema tree saved by an earlier session
+// for the current connection, or nil when there is none.
+func (s *Service) CachedSchemaTree() *SchemaTree {
+	if s.cache == nil {
+		return nil
+	}
+	return s.cache.tree()
+}
+
+// RefreshSchemaTree checks the database for DDL changes since the cached
+// snapshot and reloads the tree when anything changed. Column metadata is
+// dropped only for the tables that changed, so it is fetched again on
+// demand. With force set, or without a cached snapshot, every cached
+// column is dropped.
+func (s *Service) RefreshSchemaTree(ctx context.Context, force bool) (*SchemaRefresh, error) {
+	version, err := s.driver.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.cache
+	c.mu.Lock()
+	old := c.Version
+	c.mu.Unlock()
+
+	if force || old == nil {
+		tree, err := s.LoadSchemaTree(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		var stale []string
+		for key := range c.Columns {
+			stale = append(stale, key)
+		}
+		slices.Sort(stale)
+		c.Database = tree.Database
+		c.Schemas = tree.Schemas
+		c.Version = version
+		c.Columns = nil
+		c.dirty = true
+		c.mu.Unlock()
+		return &SchemaRefresh{Tree: tree, Changed: true, Stale: stale}, s.cache.save()
+	}
+
+	// find the tables whose definitions changed or that were dropped
+	changed := false
+	var stale []string
+	for schema, fp := range version.Schemas {
+		if old.Schemas[schema] != fp {
+			changed = true
+		}
+	}
+	for schema := range old.Schemas {
+		if _, ok := version.Schemas[schema]; !ok {
+			changed = true
+		}
+	}
+	for schema, tables := range version.Tables {
+		for table, fp := range tables {
+			if old.Tables[schema][table] != fp {
+				changed = true
+				if _, had := old.Tables[schema][table]; had {
+					stale = append(stale, schema+"."+table)
+				}
+			}
+		}
+	}
+	for schema, tables := range old.Tables {
+		for table := range tables {
+			if _, ok := version.Tables[schema][table]; !ok {
+				changed = true
+				stale = append(stale, schema+"."+table)
+			}
+		}
+	}
+
+	if !changed {
+		return &SchemaRefresh{Tree: c.tree()}, nil
+	}
+
+	tree, err := s.LoadSchemaTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(stale)
+	c.mu.Lock()
+	c.Database = tree.Database
+	c.Schemas = tree.Schemas
+	c.Version = version
+	for _, key := range stale {
+		delete(c.Columns, key)
+	}
+	c.dirty = true
+	c.mu.Unlock()
+
+	return &SchemaRefresh{Tree: tree, Changed: true, Stale: stale}, s.cache.save()
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/sql"
+)
+
+// ParamTypes are the types a query parameter can be given. "auto" leaves
+// the type to the server, which infers it from where the parameter is
+// used; "null" binds NULL whatever the value.
+var ParamTypes = []string{
+	"auto", "text", "integer", "bigint", "numeric", "boolean",
+	"date", "timestamp", "timestamptz", "uuid", "jsonb", "null",
+}
+
+// CheckParam reports a value that cannot be of its parameter's type.
+// Types the server parses with rules of its own, such as dates, are
+// left for it to check.
+func CheckParam(p history.Param) error {
+	v := strings.TrimSpace(p.Value)
+	switch p.Type {
+	case "integer", "bigint":
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("%s: %q is not an integer", p.Name, p.Value)
+		}
+	case "numeric":
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("%s: %q is not a number", p.Name, p.Value)
+		}
+	case "boolean":
+		switch strings.ToLower(v) {
+		case "t", "true", "y", "yes", "on", "1", "f", "false", "n", "no", "off", "0":
+		default:
+			return fmt.Errorf("%s: %q is not a boolean", p.Name, p.Value)
+		}
+	}
+	return nil
+}
+
+// bindParams rewrites query so its parameters are all positional and
+// returns the arguments to run it with, taken from params by label.
+// Values are sent as text for the server to parse as the parameter's
+// type, so nothing is ever interpolated into the query.
+func bindParams(query string, params []history.Param) (string, []any, error) {
+	wanted := sql.Parameters(query)
+	if len(wanted) == 0 {
+		return query, nil, nil
+	}
+	byName := make(map[string]history.Param, len(params))
+	for _, p := range params {
+		byName[p.Name] = p
+	}
+
+	casts := make(map[string]string)
+	args := make([]any, len(wanted))
+	for i, w := range wanted {
+		p, ok := byName[w.String()]
+		if !ok {
+			return "", nil, fmt.Errorf("no value for parameter %s", w)
+		}
+		if err := CheckParam(p); err != nil {
+			return "", nil, err
+		}
+		switch p.Type {
+		case "null":
+			args[i] = nil
+		case "", "auto":
+			args[i] = p.Value
+		case "text":
+			casts[w.String()] = p.Type
+			args[i] = p.Value
+		default:
+			casts[w.String()] = p.Type
+			args[i] = strings.TrimSpace(p.Value)
+		}
+	}
+	return sql.Bind(query, casts), args, nil
+}
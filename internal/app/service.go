@@ -2,8 +2,18 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/joacominatel/minadb/internal/config"
 	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/schemadiff"
+	"github.com/joacominatel/minadb/internal/schemadump"
+	"github.com/joacominatel/minadb/internal/vault"
+	"golang.org/x/sync/errgroup"
 )
 
 // SchemaTree represents the loaded schema hierarchy for the explorer.
@@ -12,21 +22,36 @@ type SchemaTree struct {
 	Schemas  []SchemaNode
 }
 
-// SchemaNode holds a schema name and its tables.
+// SchemaNode holds a schema name and its tables, views and functions.
 type SchemaNode struct {
-	Name   string
-	Tables []string
+	Name      string
+	Tables    []string
+	Views     []string
+	Functions []string
 }
 
+// DriverFactory creates a new, unconnected driver.
+type DriverFactory func() database.Driver
+
 // Service coordinates application-level operations between the TUI and database.
 type Service struct {
-	driver database.Driver
-	dsn    string
+	driver    database.Driver
+	newDriver DriverFactory
+	dsn       string
+	cache     *schemaCache
+	vault     *vault.Vault   // nil until UseVault; nothing is written to disk without it
+	history   *history.Store // nil when the history file cannot be read
+
+	mu     sync.Mutex
+	others map[string]database.Driver // connections opened by ExecuteQueryAt, by DSN
 }
 
-// NewService creates a new application service.
-func NewService(driver database.Driver) *Service {
-	return &Service{driver: driver}
+// NewService creates a new application service. The factory provides the
+// main driver and any extra connections, such as for schema comparison.
+// History and the schema cache are kept in memory only until UseVault
+// provides the key to store them with.
+func NewService(newDriver DriverFactory) *Service {
+	return &Service{driver: newDriver(), newDriver: newDriver}
 }
 
 // Connect establishes a database connection.
@@ -35,17 +60,29 @@ func (s *Service) Connect(ctx context.Context, dsn string) error {
 		return &ErrConnection{Cause: err}
 	}
 	s.dsn = dsn
+	s.cache = readCache(s.cachePath(dsn), s.vault)
 	return nil
 }
 
-// Disconnect closes the database connection.
+// Disconnect closes the database connection, saving any schema metadata
+// fetched since the cache was last written.
 func (s *Service) Disconnect() error {
+	if s.cache != nil {
+		_ = s.cache.save()
+	}
+	s.mu.Lock()
+	for dsn, d := range s.others {
+		_ = d.Close()
+		delete(s.others, dsn)
+	}
+	s.mu.Unlock()
 	return s.driver.Close()
 }
 
-// LoadSchemaTree fetches schemas and their tables for the connected database.
+// LoadSchemaTree fetches schemas and their objects for the connected
+// database in a single catalog query.
 func (s *Service) LoadSchemaTree(ctx context.Context) (*SchemaTree, error) {
-	schemas, err := s.driver.ListSchemas(ctx)
+	schemas, err := s.driver.ListSchemaObjects(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -53,18 +90,14 @@ func (s *Service) LoadSchemaTree(ctx context.Context) (*SchemaTree, error) {
 	tree := &SchemaTree{
 		Database: s.driver.DatabaseName(),
 	}
-
 	for _, schema := range schemas {
-		tables, err := s.driver.ListTables(ctx, schema)
-		if err != nil {
-			return nil, err
-		}
 		tree.Schemas = append(tree.Schemas, SchemaNode{
-			Name:   schema,
-			Tables: tables,
+			Name:      schema.Schema,
+			Tables:    schema.Tables,
+			Views:     schema.Views,
+			Functions: schema.Functions,
 		})
 	}
-
 	return tree, nil
 }
 
@@ -72,7 +105,7 @@ func (s *Service) LoadSchemaTree(ctx context.Context) (*SchemaTree, error) {
 func (s *Service) AllTableNames(tree *SchemaTree) []string {
 	var names []string
 	for _, schema := range tree.Schemas {
-		for _, table := range schema.Tables {
+		for _, table := range slices.Concat(schema.Tables, schema.Views) {
 			// Add both unqualified and schema-qualified names
 			names = append(names, table)
 			if schema.Name != "public" {
@@ -83,9 +116,109 @@ func (s *Service) AllTableNames(tree *SchemaTree) []string {
 	return names
 }
 
-// LoadColumns fetches column metadata for a specific table.
+// LoadColumns returns column metadata for a specific table, from the
+// schema cache when the table has not changed since it was fetched.
 func (s *Service) LoadColumns(ctx context.Context, schema, table string) ([]database.Column, error) {
-	return s.driver.GetColumns(ctx, schema, table)
+	if s.cache != nil {
+		if cols, ok := s.cache.columns(schema, table); ok {
+			return cols, nil
+		}
+	}
+	cols, err := s.driver.GetColumns(ctx, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		s.cache.setColumns(schema, table, cols)
+	}
+	return cols, nil
+}
+
+// ResolveColumns returns the columns of a table named as in a query. An
+// unqualified name resolves through the search path, like PostgreSQL
+// would resolve it.
+func (s *Service) ResolveColumns(ctx context.Context, schema, table string) ([]database.Column, error) {
+	if schema == "" {
+		info, err := s.driver.GetTableInfo(ctx, "", table)
+		if err != nil {
+			return nil, err
+		}
+		schema, table = info.Schema, info.Name
+	}
+	return s.LoadColumns(ctx, schema, table)
+}
+
+// ColumnsBatch is one batch of column metadata loaded by PrefetchColumns.
+type ColumnsBatch struct {
+	Schema  string
+	Columns map[string][]database.Column // table -> columns
+	Done    int                          // tables loaded so far, this batch included
+	Total   int
+}
+
+const (
+	columnBatchSize   = 25
+	columnConcurrency = 4
+)
+
+// PrefetchColumns loads the columns of several tables of a schema in
+// batches, a few batches at a time, and reports each finished batch to
+// progress. Tables found in the schema cache are reported first without
+// a query. progress is never called concurrently.
+func (s *Service) PrefetchColumns(ctx context.Context, schema string, tables []string, progress func(ColumnsBatch)) error {
+	var mu sync.Mutex
+	done, total := 0, len(tables)
+	report := func(cols map[string][]database.Column) {
+		mu.Lock()
+		defer mu.Unlock()
+		done += len(cols)
+		progress(ColumnsBatch{Schema: schema, Columns: cols, Done: done, Total: total})
+	}
+
+	var missing []string
+	cached := make(map[string][]database.Column)
+	for _, t := range tables {
+		if s.cache != nil {
+			if cols, ok := s.cache.columns(schema, t); ok {
+				cached[t] = cols
+				continue
+			}
+		}
+		missing = append(missing, t)
+	}
+	if len(cached) > 0 {
+		report(cached)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(columnConcurrency)
+	for batch := range slices.Chunk(missing, columnBatchSize) {
+		g.Go(func() error {
+			cols, err := s.driver.GetColumnsBatch(gctx, schema, batch)
+			if err != nil {
+				return err
+			}
+			if s.cache != nil {
+				for t, c := range cols {
+					s.cache.setColumns(schema, t, c)
+				}
+			}
+			report(cols)
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// ColumnIndex lists every column of every table and view, for searching
+// columns that have not been loaded into the explorer.
+func (s *Service) ColumnIndex(ctx context.Context) ([]database.ColumnRef, error) {
+	return s.driver.ColumnIndex(ctx)
+}
+
+// LoadTableInfo fetches the column types and keys needed to address rows of a table.
+func (s *Service) LoadTableInfo(ctx context.Context, schema, table string) (*database.TableInfo, error) {
+	return s.driver.GetTableInfo(ctx, schema, table)
 }
 
 // GetTableRowCount returns the approximate row count for a table.
@@ -93,16 +226,157 @@ func (s *Service) GetTableRowCount(ctx context.Context, schema, table string) (i
 	return s.driver.GetTableRowCount(ctx, schema, table)
 }
 
-// ExecuteQuery runs a SQL query and returns the results.
-func (s *Service) ExecuteQuery(ctx context.Context, query string) (*database.QueryResult, error) {
-	result, err := s.driver.ExecuteQuery(ctx, query)
+// ExecuteQuery runs a SQL query and returns the results. The values in
+// params are bound to the query's $1, :name and ${name} parameters by
+// label. The query is recorded in the history, with the values it was
+// run with, whether it succeeds or not.
+func (s *Service) ExecuteQuery(ctx context.Context, query string, params ...history.Param) (*database.QueryResult, error) {
+	return s.ExecuteQueryAt(ctx, s.dsn, query, params...)
+}
+
+// ExecuteQueryAt runs a query like ExecuteQuery on the database dsn
+// points to. Other than the main connection, a connection is opened the
+// first time it is needed and kept for later queries until Disconnect.
+func (s *Service) ExecuteQueryAt(ctx context.Context, dsn, query string, params ...history.Param) (*database.QueryResult, error) {
+	bound, args, err := bindParams(query, params)
+	if err != nil {
+		return nil, &ErrQuery{Query: query, Cause: err}
+	}
+	driver, err := s.driverFor(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result, err := driver.ExecuteQuery(ctx, bound, args...)
+	s.record(driver, dsn, query, params, start, result, err)
 	if err != nil {
 		return nil, &ErrQuery{Query: query, Cause: err}
 	}
 	return result, nil
 }
 
+// driverFor returns the connection to run queries for dsn on.
+func (s *Service) driverFor(ctx context.Context, dsn string) (database.Driver, error) {
+	if dsn == "" || dsn == s.dsn {
+		return s.driver, nil
+	}
+	s.mu.Lock()
+	d, ok := s.others[dsn]
+	s.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	// connect without the lock, so a slow server does not hold up queries
+	// on other connections
+	d = s.newDriver()
+	if err := d.Connect(ctx, dsn); err != nil {
+		return nil, &ErrConnection{Cause: err}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if other, ok := s.others[dsn]; ok {
+		// another query connected first
+		d.Close()
+		return other, nil
+	}
+	if s.others == nil {
+		s.others = make(map[string]database.Driver)
+	}
+	s.others[dsn] = d
+	return d, nil
+}
+
+// record adds an executed query to the history. A history that cannot
+// be written is not worth failing the query for.
+func (s *Service) record(driver database.Driver, dsn, query string, params []history.Param, start time.Time, result *database.QueryResult, err error) {
+	if s.history == nil {
+		return
+	}
+	e := history.Entry{
+		Query:    query,
+		Database: driver.DatabaseName(),
+		At:       start,
+		Duration: time.Since(start),
+		Params:   params,
+	}
+	if conn, perr := config.ParseDSN(dsn); perr == nil {
+		e.Connection = conn.DisplayString()
+	}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Rows = result.RowCount
+		if result.Duration > 0 {
+			e.Duration = result.Duration
+		}
+	}
+	_ = s.history.Add(e)
+}
+
+// History returns the executed queries of every connection, newest first.
+func (s *Service) History() []history.Entry {
+	if s.history == nil {
+		return nil
+	}
+	return s.history.Entries()
+}
+
 // DatabaseName returns the current database name.
 func (s *Service) DatabaseName() string {
 	return s.driver.DatabaseName()
 }
+
+// LoadCatalog snapshots the given schemas of the connected database. No
+// schemas means all user schemas.
+func (s *Service) LoadCatalog(ctx context.Context, schemas []string) (*database.Catalog, error) {
+	return s.driver.Introspect(ctx, schemas)
+}
+
+// DumpSchema returns the DDL that recreates the given schemas of the
+// connected database. No schemas means all user schemas.
+func (s *Service) DumpSchema(ctx context.Context, schemas []string) (string, error) {
+	cat, err := s.driver.Introspect(ctx, schemas)
+	if err != nil {
+		return "", err
+	}
+	return schemadump.Script(cat), nil
+}
+
+// CompareSchemas introspects two databases concurrently over temporary
+// connections and returns the differences that migrating target to match
+// source would resolve. No schemas means all user schemas.
+func (s *Service) CompareSchemas(ctx context.Context, sourceDSN, targetDSN string, schemas []string) (*schemadiff.Diff, error) {
+	var source, target *database.Catalog
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		source, err = s.introspectDSN(gctx, sourceDSN, schemas)
+		if err != nil {
+			return fmt.Errorf("source: %w", err)
+		}
+		return nil
+	})
+	g.Go(func() (err error) {
+		target, err = s.introspectDSN(gctx, targetDSN, schemas)
+		if err != nil {
+			return fmt.Errorf("target: %w", err)
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return schemadiff.Compare(source, target), nil
+}
+
+// introspectDSN opens a short-lived connection and snapshots its catalog.
+func (s *Service) introspectDSN(ctx context.Context, dsn string, schemas []string) (*database.Catalog, error) {
+	driver := s.newDriver()
+	if err := driver.Connect(ctx, dsn); err != nil {
+		return nil, &ErrConnection{Cause: err}
+	}
+	defer driver.Close()
+	return driver.Introspect(ctx, schemas)
+}
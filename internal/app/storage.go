@@ -0,0 +1,260 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/config"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/vault"
+)
+
+// UseVault sets the vault the history and schema cache are stored with
+// and loads the history sealed with it. Schema caches of every connection
+// written in plain text before encryption was enabled are sealed now
+// rather than when each is next used. Until it is called nothing is read
+// from or written to disk.
+func (s *Service) UseVault(v *vault.Vault) error {
+	s.vault = v
+	if s.dsn != "" && (s.cache == nil || s.cache.path == "") {
+		s.cache = readCache(s.cachePath(s.dsn), v)
+	}
+	if v.Encrypted() {
+		if err := sealPlainCaches(v); err != nil {
+			return err
+		}
+	}
+	path, err := config.HistoryPath()
+	if err != nil {
+		return err
+	}
+	h, err := history.Open(path, v)
+	if err != nil {
+		return fmt.Errorf("read history: %w", err)
+	}
+	s.history = h
+	return nil
+}
+
+// RekeyData re-encrypts the history, every schema cache and the column
+// layouts with v, the vault returned by vault.Rotate, and then drops the
+// replaced keys. Cache files in an outdated format are removed; they are
+// rebuilt on the next connection. Data sealed with a key v does not have
+// is left as it is and reported, and the replaced keys are kept.
+func (s *Service) RekeyData(v *vault.Vault) error {
+	if s.history == nil {
+		if err := s.UseVault(v); err != nil {
+			return err
+		}
+	}
+	s.vault = v
+	if err := s.history.Rekey(v); err != nil {
+		return fmt.Errorf("re-encrypt history: %w", err)
+	}
+
+	var unreadable []string
+	if n := s.history.Unreadable(); n > 0 {
+		unreadable = append(unreadable, fmt.Sprintf("%d history entries", n))
+	}
+
+	files, err := cacheFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		if raw, err := os.ReadFile(path); err == nil {
+			if _, err := v.Open(raw); err != nil {
+				unreadable = append(unreadable, path)
+				continue
+			}
+		}
+		c := readCache(path, v)
+		if c.Version == nil {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+		c.dirty = true
+		if err := c.save(); err != nil {
+			return fmt.Errorf("re-encrypt %s: %w", path, err)
+		}
+	}
+	if s.cache != nil {
+		s.cache.mu.Lock()
+		s.cache.vault = v
+		s.cache.mu.Unlock()
+	}
+	data, err := s.readLayouts()
+	switch {
+	case err != nil:
+		unreadable = append(unreadable, "column layouts")
+	case data != nil:
+		if err := s.SaveLayouts(data); err != nil {
+			return fmt.Errorf("re-encrypt layouts: %w", err)
+		}
+	}
+	if len(unreadable) > 0 {
+		return fmt.Errorf("no key decrypts %s: they were left as they are and the previous keys kept; remove them",
+			strings.Join(unreadable, ", "))
+	}
+	return v.Finish()
+}
+
+// ExportData writes the history, schema caches and column layouts,
+// decrypted, to dir: history.jsonl with one JSON entry per line, oldest
+// first, cache/<name>.json for each connection's schema cache and
+// layouts.json.
+func (s *Service) ExportData(dir string) ([]string, error) {
+	if s.history == nil {
+		return nil, errors.New("local data is not available")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "cache"), 0o700); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	var b strings.Builder
+	entries := s.history.Entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		line, err := json.Marshal(entries[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	path := filepath.Join(dir, "history.jsonl")
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return nil, err
+	}
+	written = append(written, path)
+
+	files, err := cacheFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range files {
+		c := readCache(src, s.vault)
+		if c.Version == nil {
+			continue
+		}
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, "cache", filepath.Base(src))
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+
+	if data := s.Layouts(); data != nil {
+		path := filepath.Join(dir, "layouts.json")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// Layouts returns the result column layouts saved by SaveLayouts, or nil
+// when there are none or they cannot be read.
+func (s *Service) Layouts() []byte {
+	data, _ := s.readLayouts()
+	return data
+}
+
+// readLayouts returns the saved column layouts, or nil when there are
+// none or no vault to open them with.
+func (s *Service) readLayouts() ([]byte, error) {
+	if s.vault == nil {
+		return nil, nil
+	}
+	path, err := config.LayoutsPath()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.vault.Open(raw)
+}
+
+// SaveLayouts writes the result column layouts sealed with the vault.
+// Without a vault nothing is saved, as for the history, and layouts
+// sealed with a key the vault does not have are not overwritten.
+func (s *Service) SaveLayouts(data []byte) error {
+	if s.vault == nil {
+		return nil
+	}
+	if _, err := s.readLayouts(); err != nil {
+		// sealed with a key the vault does not have
+		return fmt.Errorf("keep unreadable layouts: %w", err)
+	}
+	path, err := config.LayoutsPath()
+	if err != nil {
+		return err
+	}
+	if data, err = s.vault.Seal(data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// sealPlainCaches rewrites the cache files still in plain text sealed
+// with v.
+func sealPlainCaches(v *vault.Vault) error {
+	files, err := cacheFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		if c := readCache(path, v); c.dirty {
+			if err := c.save(); err != nil {
+				return fmt.Errorf("encrypt %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// cacheFiles lists the schema cache files of every connection.
+func cacheFiles() ([]string, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	return files, nil
+}
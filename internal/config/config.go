@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/sql"
 )
 
 // Config represents the application configuration.
@@ -26,8 +29,62 @@ type Connection struct {
 
 // Preferences holds user preferences.
 type Preferences struct {
-	Theme             string `mapstructure:"theme" yaml:"theme"`
-	DefaultConnection string `mapstructure:"default_connection" yaml:"default_connection"`
+	Theme             string     `mapstructure:"theme" yaml:"theme"`
+	DefaultConnection string     `mapstructure:"default_connection" yaml:"default_connection"`
+	Format            Format     `mapstructure:"format" yaml:"format"`
+	Encryption        Encryption `mapstructure:"encryption" yaml:"encryption"`
+	// QueriesDir holds the saved queries, one .sql file each, so a team
+	// can share them through git. Defaults to ~/.minadb/queries.
+	QueriesDir string `mapstructure:"queries_dir" yaml:"queries_dir,omitempty"`
+	// ResultsMemoryMB caps the memory taken by past and pinned query
+	// results kept to flip back to; the oldest are evicted first.
+	ResultsMemoryMB int `mapstructure:"results_memory_mb" yaml:"results_memory_mb"`
+}
+
+// DefaultResultsMemoryMB is the results memory cap when none is set.
+const DefaultResultsMemoryMB = 256
+
+// ResultsMemory returns the results memory cap in bytes.
+func (p Preferences) ResultsMemory() int64 {
+	mb := p.ResultsMemoryMB
+	if mb <= 0 {
+		mb = DefaultResultsMemoryMB
+	}
+	return int64(mb) << 20
+}
+
+// Encryption chooses where the key protecting history and cached schema
+// data comes from: "keyring" (a random key in the OS keyring, the
+// default), "passphrase" (a key derived from a passphrase asked for at
+// startup or read from MINADB_PASSPHRASE) or "none" (plain files). It
+// applies when the store is first created; `minadb vault rotate` changes
+// it afterwards.
+type Encryption struct {
+	KeySource string `mapstructure:"key_source" yaml:"key_source"`
+}
+
+// Format holds the SQL formatter style used by Ctrl+L and `minadb fmt`.
+type Format struct {
+	IndentWidth int    `mapstructure:"indent_width" yaml:"indent_width"`
+	KeywordCase string `mapstructure:"keyword_case" yaml:"keyword_case"` // upper, lower or preserve
+	Commas      string `mapstructure:"commas" yaml:"commas"`             // trailing or leading
+}
+
+// Options converts the preferences to formatter options. Unset or
+// unknown values fall back to the formatter's defaults.
+func (f Format) Options() sql.FormatOptions {
+	opts := sql.DefaultFormatOptions
+	if f.IndentWidth > 0 {
+		opts.Indent = f.IndentWidth
+	}
+	switch strings.ToLower(f.KeywordCase) {
+	case "lower":
+		opts.KeywordCase = sql.LowerKeywords
+	case "preserve":
+		opts.KeywordCase = sql.PreserveKeywords
+	}
+	opts.LeadingCommas = strings.EqualFold(f.Commas, "leading")
+	return opts
 }
 
 // DSN builds a PostgreSQL connection string from the connection profile.
@@ -108,6 +165,16 @@ func (cfg *Config) HasConnection(name string) bool {
 	return false
 }
 
+// Connection returns the saved connection with the given name.
+func (cfg *Config) Connection(name string) (Connection, bool) {
+	for _, c := range cfg.Connections {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Connection{}, false
+}
+
 // AddConnection appends a connection if it doesn't already exist.
 func (cfg *Config) AddConnection(conn Connection) {
 	if !cfg.HasConnection(conn.Name) {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	keyring "github.com/zalando/go-keyring"
 
@@ -12,6 +13,12 @@ import (
 
 const (
 	configDir      = ".minadb"
+	cacheDir       = "cache"
+	historyFile    = "history.jsonl"
+	vaultFile      = "vault.json"
+	layoutsFile    = "layouts.json"
+	queriesDir     = "queries"
+	dataKeyAccount = ":data-key" // cannot clash with a connection name, which never starts with ':'
 	configFile     = "config"
 	configType     = "yaml"
 	keyringService = "minadb"
@@ -31,6 +38,11 @@ func Load() (*Config, error) {
 
 	// Defaults
 	viper.SetDefault("preferences.theme", "default")
+	viper.SetDefault("preferences.format.indent_width", 4)
+	viper.SetDefault("preferences.format.keyword_case", "upper")
+	viper.SetDefault("preferences.format.commas", "trailing")
+	viper.SetDefault("preferences.encryption.key_source", "keyring")
+	viper.SetDefault("preferences.results_memory_mb", DefaultResultsMemoryMB)
 
 	cfg := &Config{}
 
@@ -127,6 +139,83 @@ func GetPassword(connName string) (string, error) {
 	return keyring.Get(keyringService, connName)
 }
 
+// SaveDataKey stores a key that encrypts local data in the OS keyring,
+// under its id so a rotation never overwrites the key it replaces.
+func SaveDataKey(id, key string) error {
+	return keyring.Set(keyringService, dataKeyAccount+":"+id, key)
+}
+
+// GetDataKey retrieves a key that encrypts local data from the OS
+// keyring.
+func GetDataKey(id string) (string, error) {
+	return keyring.Get(keyringService, dataKeyAccount+":"+id)
+}
+
+// DeleteDataKey removes a data key from the OS keyring.
+func DeleteDataKey(id string) error {
+	return keyring.Delete(keyringService, dataKeyAccount+":"+id)
+}
+
+// CacheDir returns ~/.minadb/cache, where per-connection metadata is kept.
+func CacheDir() (string, error) {
+	dir, err := configDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cacheDir), nil
+}
+
+// HistoryPath returns ~/.minadb/history.jsonl, the query history of all
+// connections.
+func HistoryPath() (string, error) {
+	dir, err := configDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyFile), nil
+}
+
+// LayoutsPath returns ~/.minadb/layouts.json, the result column layouts
+// remembered by table and by query.
+func LayoutsPath() (string, error) {
+	dir, err := configDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, layoutsFile), nil
+}
+
+// QueriesDir returns the directory of saved queries: the configured one,
+// with a leading ~ expanded, or ~/.minadb/queries.
+func QueriesDir(prefs Preferences) (string, error) {
+	dir := prefs.QueriesDir
+	if dir == "" {
+		base, err := configDirPath()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(base, queriesDir), nil
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	return dir, nil
+}
+
+// VaultPath returns ~/.minadb/vault.json, which describes how local data
+// is encrypted.
+func VaultPath() (string, error) {
+	dir, err := configDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, vaultFile), nil
+}
+
 func configDirPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
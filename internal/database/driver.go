@@ -20,14 +20,41 @@ type Driver interface {
 	// ListTables returns all table names in a schema.
 	ListTables(ctx context.Context, schema string) ([]string, error)
 
+	// ListSchemaObjects returns every user schema with the names of its
+	// tables, views and functions, including empty schemas, in a single
+	// round-trip.
+	ListSchemaObjects(ctx context.Context) ([]SchemaObjects, error)
+
+	// ColumnIndex lists the columns of every user table and view in a
+	// single round-trip, for searching.
+	ColumnIndex(ctx context.Context) ([]ColumnRef, error)
+
 	// GetColumns returns all columns for a table.
 	GetColumns(ctx context.Context, schema, table string) ([]Column, error)
 
+	// GetColumnsBatch returns the columns of several tables of a schema,
+	// keyed by table name, in a single round-trip.
+	GetColumnsBatch(ctx context.Context, schema string, tables []string) (map[string][]Column, error)
+
+	// GetTableInfo returns columns, row-identifying keys and foreign keys
+	// (in both directions) for a table.
+	// An empty schema resolves the table through the search path.
+	GetTableInfo(ctx context.Context, schema, table string) (*TableInfo, error)
+
 	// GetTableRowCount returns the approximate row count for a table.
 	GetTableRowCount(ctx context.Context, schema, table string) (int64, error)
 
-	// ExecuteQuery runs a SQL query and returns results.
-	ExecuteQuery(ctx context.Context, query string) (*QueryResult, error)
+	// SchemaVersion fingerprints every schema and table so callers can
+	// tell which ones changed since an earlier snapshot.
+	SchemaVersion(ctx context.Context) (*SchemaVersion, error)
+
+	// Introspect returns a snapshot of the objects in the given schemas,
+	// or in all user schemas when none are given.
+	Introspect(ctx context.Context, schemas []string) (*Catalog, error)
+
+	// ExecuteQuery runs a SQL query and returns results. args are bound
+	// to its $1, $2, ... parameters.
+	ExecuteQuery(ctx context.Context, query string, args ...any) (*QueryResult, error)
 
 	// DatabaseName returns the name of the connected database.
 	DatabaseName() string
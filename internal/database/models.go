@@ -10,6 +10,8 @@ type Column struct {
 	IsPrimary  bool
 	Default    string
 	OrdinalPos int
+	Identity   string // "a" for GENERATED ALWAYS, "d" for BY DEFAULT, "" otherwise
+	Generated  string // "s" for a stored generated column, whose Default is the expression
 }
 
 // QueryResult holds the result of a SQL query execution.
@@ -19,3 +21,200 @@ type QueryResult struct {
 	RowCount int
 	Duration time.Duration
 }
+
+// TableInfo holds the metadata needed to address individual rows of a table.
+type TableInfo struct {
+	Schema     string
+	Name       string
+	Columns    []Column // DataType holds the full type, e.g. "character varying(20)"
+	PrimaryKey []string
+	UniqueKeys [][]string // non-partial unique indexes, excluding the primary key
+
+	ForeignKeys  []ForeignKey // constraints on this table referencing others
+	ReferencedBy []ForeignKey // constraints on other tables referencing this one
+}
+
+// ForeignKey describes a foreign-key constraint from a child table to the
+// parent table it references. Columns and RefColumns pair up by position.
+type ForeignKey struct {
+	Name       string
+	Schema     string
+	Table      string
+	Columns    []string
+	RefSchema  string
+	RefTable   string
+	RefColumns []string
+}
+
+// Column returns the column with the given name, if present.
+func (t *TableInfo) Column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// Catalog is a snapshot of the objects defined in a set of schemas,
+// used for comparing databases and generating DDL.
+type Catalog struct {
+	Database  string
+	Schemas   []string
+	Types     []Type
+	Sequences []Sequence
+	Tables    []CatalogTable
+	Views     []View
+	Functions []Function
+	Grants    []Grant
+}
+
+// CatalogTable is a table with everything defined on it.
+type CatalogTable struct {
+	Schema      string
+	Name        string
+	Columns     []Column
+	Indexes     []Index      // indexes not backing a constraint
+	Constraints []Constraint // primary key, unique, check, exclusion and foreign keys
+
+	PartitionKey string // e.g. "RANGE (created_at)" for a partitioned table
+
+	// partitions name their parent and the bound of the rows they hold,
+	// e.g. "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')" or "DEFAULT";
+	// their columns and inherited constraints and indexes come from it
+	ParentSchema   string
+	ParentName     string
+	PartitionBound string
+}
+
+// IsPartition reports whether the table is a partition of another.
+func (t CatalogTable) IsPartition() bool {
+	return t.ParentName != ""
+}
+
+// Index is an index as reported by pg_get_indexdef.
+type Index struct {
+	Name       string
+	Definition string
+	IsUnique   bool
+}
+
+// ConstraintKind identifies a table constraint type.
+type ConstraintKind string
+
+const (
+	ConstraintPrimaryKey ConstraintKind = "p"
+	ConstraintUnique     ConstraintKind = "u"
+	ConstraintCheck      ConstraintKind = "c"
+	ConstraintExclusion  ConstraintKind = "x"
+	ConstraintForeignKey ConstraintKind = "f"
+)
+
+// Constraint is a table constraint; Definition is the text that follows
+// ADD CONSTRAINT name, e.g. "PRIMARY KEY (id)".
+type Constraint struct {
+	Name       string
+	Kind       ConstraintKind
+	Definition string
+	Columns    []string // constrained columns, empty for checks on expressions
+
+	// referenced side, for foreign keys only
+	RefSchema  string
+	RefTable   string
+	RefColumns []string
+}
+
+// View is a plain or materialized view.
+type View struct {
+	Schema       string
+	Name         string
+	Definition   string // the SELECT body
+	Materialized bool
+	DependsOn    []string // other views it reads from, as schema.name
+}
+
+// Function is a function or procedure. Arguments holds the identity
+// argument list that distinguishes overloads.
+type Function struct {
+	Schema     string
+	Name       string
+	Arguments  string
+	Definition string   // complete CREATE OR REPLACE statement
+	DependsOn  []string // types in its signature, including table row types, as schema.name
+}
+
+// Signature returns the name and identity arguments, e.g. add(integer, integer).
+func (f Function) Signature() string {
+	return f.Name + "(" + f.Arguments + ")"
+}
+
+// TypeKind identifies a user-defined type.
+type TypeKind string
+
+const (
+	TypeEnum      TypeKind = "e"
+	TypeDomain    TypeKind = "d"
+	TypeComposite TypeKind = "c"
+	TypeRange     TypeKind = "r"
+)
+
+// Type is an enum, domain, composite or range type. Definition is the
+// text that follows the type name in CREATE TYPE or CREATE DOMAIN, e.g.
+// "AS ENUM ('draft', 'published')".
+type Type struct {
+	Schema     string
+	Name       string
+	Kind       TypeKind
+	Definition string
+	DependsOn  []string // other types it is built on, as schema.name
+}
+
+// Sequence is a standalone or serial sequence. Identity sequences are not
+// listed; they belong to their column.
+type Sequence struct {
+	Schema    string
+	Name      string
+	DataType  string
+	Start     int64
+	Increment int64
+	Min       int64
+	Max       int64
+	Cache     int64
+	Cycle     bool
+	OwnedBy   string // quoted schema.table.column, empty when not owned
+}
+
+// Grant is a set of privileges given to one role on one object.
+type Grant struct {
+	ObjectType      string // TABLE, SEQUENCE, FUNCTION, PROCEDURE or SCHEMA
+	Schema          string
+	Name            string // empty for schemas
+	Arguments       string // identity arguments for functions and procedures
+	Grantee         string // role name, or PUBLIC
+	Privileges      []string
+	WithGrantOption bool
+}
+
+// SchemaObjects is a schema and the names of the objects the explorer lists.
+type SchemaObjects struct {
+	Schema    string
+	Tables    []string
+	Views     []string
+	Functions []string
+}
+
+// ColumnRef is a column of a table or view, as listed by the column index.
+type ColumnRef struct {
+	Schema   string
+	Table    string
+	Name     string
+	DataType string
+}
+
+// SchemaVersion holds a fingerprint per schema and per table or view. A
+// fingerprint changes whenever DDL touches the object, so comparing two
+// versions shows what must be reloaded.
+type SchemaVersion struct {
+	Schemas map[string]string            // schema -> fingerprint
+	Tables  map[string]map[string]string // schema -> table or view -> fingerprint
+}
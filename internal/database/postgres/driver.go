@@ -87,6 +87,48 @@ func (d *Driver) ListSchemas(ctx context.Context) ([]string, error) {
 	return schemas, rows.Err()
 }
 
+// SchemaVersion fingerprints schemas and tables from their catalog row versions.
+func (d *Driver) SchemaVersion(ctx context.Context) (*database.SchemaVersion, error) {
+	v := &database.SchemaVersion{
+		Schemas: make(map[string]string),
+		Tables:  make(map[string]map[string]string),
+	}
+
+	rows, err := d.pool.Query(ctx, querySchemaVersions)
+	if err != nil {
+		return nil, fmt.Errorf("schema versions: %w", err)
+	}
+	for rows.Next() {
+		var name, version string
+		if err := rows.Scan(&name, &version); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan schema version: %w", err)
+		}
+		v.Schemas[name] = version
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("schema versions: %w", err)
+	}
+
+	rows, err = d.pool.Query(ctx, queryTableVersions)
+	if err != nil {
+		return nil, fmt.Errorf("table versions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, table, version string
+		if err := rows.Scan(&schema, &table, &version); err != nil {
+			return nil, fmt.Errorf("scan table version: %w", err)
+		}
+		if v.Tables[schema] == nil {
+			v.Tables[schema] = make(map[string]string)
+		}
+		v.Tables[schema][table] = version
+	}
+	return v, rows.Err()
+}
+
 // ListTables returns all table names in a schema.
 func (d *Driver) ListTables(ctx context.Context, schema string) ([]string, error) {
 	rows, err := d.pool.Query(ctx, queryListTables, schema)
@@ -127,6 +169,164 @@ func (d *Driver) GetColumns(ctx context.Context, schema, table string) ([]databa
 	return columns, rows.Err()
 }
 
+// ListSchemaObjects returns all user schemas with their tables, views and
+// functions in one query.
+func (d *Driver) ListSchemaObjects(ctx context.Context) ([]database.SchemaObjects, error) {
+	rows, err := d.pool.Query(ctx, queryListSchemaObjects)
+	if err != nil {
+		return nil, fmt.Errorf("list schema objects: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []database.SchemaObjects
+	for rows.Next() {
+		var schema string
+		var kind, name *string
+		if err := rows.Scan(&schema, &kind, &name); err != nil {
+			return nil, fmt.Errorf("scan schema object: %w", err)
+		}
+		if n := len(schemas); n == 0 || schemas[n-1].Schema != schema {
+			schemas = append(schemas, database.SchemaObjects{Schema: schema})
+		}
+		if kind == nil || name == nil {
+			continue
+		}
+		last := &schemas[len(schemas)-1]
+		switch *kind {
+		case "r":
+			last.Tables = append(last.Tables, *name)
+		case "v":
+			last.Views = append(last.Views, *name)
+		case "f":
+			last.Functions = append(last.Functions, *name)
+		}
+	}
+	return schemas, rows.Err()
+}
+
+// ColumnIndex lists the columns of every table and view the user can read.
+func (d *Driver) ColumnIndex(ctx context.Context) ([]database.ColumnRef, error) {
+	rows, err := d.pool.Query(ctx, queryColumnIndex)
+	if err != nil {
+		return nil, fmt.Errorf("column index: %w", err)
+	}
+	defer rows.Close()
+
+	var cols []database.ColumnRef
+	for rows.Next() {
+		var c database.ColumnRef
+		if err := rows.Scan(&c.Schema, &c.Table, &c.Name, &c.DataType); err != nil {
+			return nil, fmt.Errorf("scan column index: %w", err)
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// GetColumnsBatch returns column metadata for several tables of a schema.
+func (d *Driver) GetColumnsBatch(ctx context.Context, schema string, tables []string) (map[string][]database.Column, error) {
+	rows, err := d.pool.Query(ctx, queryGetColumnsBatch, schema, tables)
+	if err != nil {
+		return nil, fmt.Errorf("get columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]database.Column, len(tables))
+	for _, t := range tables {
+		columns[t] = nil
+	}
+	for rows.Next() {
+		var table string
+		var col database.Column
+		var nullable string
+		if err := rows.Scan(&table, &col.Name, &col.DataType, &nullable, &col.Default, &col.OrdinalPos, &col.IsPrimary); err != nil {
+			return nil, fmt.Errorf("scan column: %w", err)
+		}
+		col.IsNullable = nullable == "YES"
+		columns[table] = append(columns[table], col)
+	}
+	return columns, rows.Err()
+}
+
+// GetTableInfo returns column types, unique keys and foreign keys for a table.
+func (d *Driver) GetTableInfo(ctx context.Context, schema, table string) (*database.TableInfo, error) {
+	name := database.QuoteIdent(table)
+	if schema != "" {
+		name = database.QuoteIdent(schema) + "." + name
+	}
+
+	info := &database.TableInfo{}
+	var oid uint32
+	err := d.pool.QueryRow(ctx, queryResolveTable, name).Scan(&info.Schema, &info.Name, &oid)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("table %s not found", name)
+		}
+		return nil, fmt.Errorf("resolve table: %w", err)
+	}
+
+	rows, err := d.pool.Query(ctx, queryTableColumns, oid)
+	if err != nil {
+		return nil, fmt.Errorf("table columns: %w", err)
+	}
+	for rows.Next() {
+		var col database.Column
+		if err := rows.Scan(&col.Name, &col.DataType, &col.IsNullable, &col.Default, &col.OrdinalPos, &col.IsPrimary); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan column: %w", err)
+		}
+		info.Columns = append(info.Columns, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("table columns: %w", err)
+	}
+
+	rows, err = d.pool.Query(ctx, queryTableKeys, oid)
+	if err != nil {
+		return nil, fmt.Errorf("table keys: %w", err)
+	}
+	for rows.Next() {
+		var primary bool
+		var cols []string
+		if err := rows.Scan(&primary, &cols); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan key: %w", err)
+		}
+		if primary {
+			info.PrimaryKey = cols
+		} else {
+			info.UniqueKeys = append(info.UniqueKeys, cols)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("table keys: %w", err)
+	}
+
+	rows, err = d.pool.Query(ctx, queryTableForeignKeys, oid)
+	if err != nil {
+		return nil, fmt.Errorf("foreign keys: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fk database.ForeignKey
+		var outgoing, incoming bool
+		if err := rows.Scan(&fk.Name, &fk.Schema, &fk.Table, &fk.Columns,
+			&fk.RefSchema, &fk.RefTable, &fk.RefColumns, &outgoing, &incoming); err != nil {
+			return nil, fmt.Errorf("scan foreign key: %w", err)
+		}
+		// a self-reference is both
+		if outgoing {
+			info.ForeignKeys = append(info.ForeignKeys, fk)
+		}
+		if incoming {
+			info.ReferencedBy = append(info.ReferencedBy, fk)
+		}
+	}
+	return info, rows.Err()
+}
+
 // GetTableRowCount returns the approximate row count using pg_class statistics.
 func (d *Driver) GetTableRowCount(ctx context.Context, schema, table string) (int64, error) {
 	var count int64
@@ -143,11 +343,12 @@ func (d *Driver) GetTableRowCount(ctx context.Context, schema, table string) (in
 	return count, nil
 }
 
-// ExecuteQuery runs a SQL query and returns the results.
-func (d *Driver) ExecuteQuery(ctx context.Context, query string) (*database.QueryResult, error) {
+// ExecuteQuery runs a SQL query with args bound to its parameters and
+// returns the results.
+func (d *Driver) ExecuteQuery(ctx context.Context, query string, args ...any) (*database.QueryResult, error) {
 	start := time.Now()
 
-	rows, err := d.pool.Query(ctx, query)
+	rows, err := d.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("execute: %w", err)
 	}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+// Introspect loads types, sequences, tables, columns, indexes,
+// constraints, views, functions and grants for the given schemas with one
+// query per object kind.
+func (d *Driver) Introspect(ctx context.Context, schemas []string) (*database.Catalog, error) {
+	if len(schemas) == 0 {
+		all, err := d.ListSchemas(ctx)
+		if err != nil {
+			return nil, err
+		}
+		schemas = all
+	}
+
+	cat := &database.Catalog{
+		Database: d.dbName,
+		Schemas:  schemas,
+	}
+
+	// tables are indexed by schema.name so the per-table queries can attach to them
+	tableIdx := make(map[string]int)
+	err := d.scanAll(ctx, queryCatalogTables, schemas, func(rows pgx.Rows) error {
+		var t database.CatalogTable
+		if err := rows.Scan(&t.Schema, &t.Name, &t.PartitionKey,
+			&t.ParentSchema, &t.ParentName, &t.PartitionBound); err != nil {
+			return err
+		}
+		tableIdx[t.Schema+"."+t.Name] = len(cat.Tables)
+		cat.Tables = append(cat.Tables, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect tables: %w", err)
+	}
+
+	table := func(schema, name string) *database.CatalogTable {
+		if i, ok := tableIdx[schema+"."+name]; ok {
+			return &cat.Tables[i]
+		}
+		return nil
+	}
+
+	err = d.scanAll(ctx, queryCatalogColumns, schemas, func(rows pgx.Rows) error {
+		var schema, rel string
+		var col database.Column
+		if err := rows.Scan(&schema, &rel, &col.Name, &col.DataType, &col.IsNullable,
+			&col.Default, &col.OrdinalPos, &col.Identity, &col.Generated); err != nil {
+			return err
+		}
+		if t := table(schema, rel); t != nil {
+			t.Columns = append(t.Columns, col)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect columns: %w", err)
+	}
+
+	err = d.scanAll(ctx, queryCatalogIndexes, schemas, func(rows pgx.Rows) error {
+		var schema, rel string
+		var idx database.Index
+		if err := rows.Scan(&schema, &rel, &idx.Name, &idx.Definition, &idx.IsUnique); err != nil {
+			return err
+		}
+		if t := table(schema, rel); t != nil {
+			t.Indexes = append(t.Indexes, idx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect indexes: %w", err)
+	}
+
+	err = d.scanAll(ctx, queryCatalogConstraints, schemas, func(rows pgx.Rows) error {
+		var schema, rel, kind string
+		var con database.Constraint
+		if err := rows.Scan(&schema, &rel, &con.Name, &kind, &con.Definition,
+			&con.Columns, &con.RefSchema, &con.RefTable, &con.RefColumns); err != nil {
+			return err
+		}
+		con.Kind = database.ConstraintKind(kind)
+		if t := table(schema, rel); t != nil {
+			t.Constraints = append(t.Constraints, con)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect constraints: %w", err)
+	}
+
+	err = d.scanAll(ctx, queryCatalogViews, schemas, func(rows pgx.Rows) error {
+		var v database.View
+		if err := rows.Scan(&v.Schema, &v.Name, &v.Materialized, &v.Definition, &v.DependsOn); err != nil {
+			return err
+		}
+		cat.Views = append(cat.Views, v)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect views: %w", err)
+	}
+
+	err = d.scanAll(ctx, queryCatalogFunctions, schemas, func(rows pgx.Rows) error {
+		var f database.Function
+		if err := rows.Scan(&f.Schema, &f.Name, &f.Arguments, &f.Definition, &f.DependsOn); err != nil {
+			return err
+		}
+		cat.Functions = append(cat.Functions, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect functions: %w", err)
+	}
+
+	err = d.scanAll(ctx, queryCatalogTypes, schemas, func(rows pgx.Rows) error {
+		var t database.Type
+		var kind string
+		if err := rows.Scan(&t.Schema, &t.Name, &kind, &t.Definition, &t.DependsOn); err != nil {
+			return err
+		}
+		t.Kind = database.TypeKind(kind)
+		cat.Types = append(cat.Types, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect types: %w", err)
+	}
+
+	err = d.scanAll(ctx, queryCatalogSequences, schemas, func(rows pgx.Rows) error {
+		var s database.Sequence
+		if err := rows.Scan(&s.Schema, &s.Name, &s.DataType, &s.Start, &s.Increment,
+			&s.Min, &s.Max, &s.Cache, &s.Cycle, &s.OwnedBy); err != nil {
+			return err
+		}
+		cat.Sequences = append(cat.Sequences, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect sequences: %w", err)
+	}
+
+	err = d.scanAll(ctx, queryCatalogGrants, schemas, func(rows pgx.Rows) error {
+		var g database.Grant
+		if err := rows.Scan(&g.ObjectType, &g.Schema, &g.Name, &g.Arguments, &g.Grantee,
+			&g.Privileges, &g.WithGrantOption); err != nil {
+			return err
+		}
+		cat.Grants = append(cat.Grants, g)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("introspect grants: %w", err)
+	}
+
+	return cat, nil
+}
+
+// scanAll runs a query and calls fn for every row.
+func (d *Driver) scanAll(ctx context.Context, query string, arg any, fn func(pgx.Rows) error) error {
+	rows, err := d.pool.Query(ctx, query, arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -15,6 +15,38 @@ const (
 		  AND table_type = 'BASE TABLE'
 		ORDER BY table_name`
 
+	// Empty schemas come back once with a NULL kind and name.
+	queryListSchemaObjects = `
+		SELECT s.schema_name, o.kind, o.name
+		FROM information_schema.schemata s
+		LEFT JOIN (
+			SELECT table_schema AS schema_name,
+				CASE table_type WHEN 'VIEW' THEN 'v' ELSE 'r' END AS kind,
+				table_name AS name
+			FROM information_schema.tables
+			WHERE table_type IN ('BASE TABLE', 'VIEW')
+			UNION
+			SELECT routine_schema, 'f', routine_name
+			FROM information_schema.routines
+			WHERE routine_type = 'FUNCTION'
+		) o ON o.schema_name = s.schema_name
+		WHERE s.schema_name NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+		ORDER BY s.schema_name, o.kind, o.name`
+
+	queryColumnIndex = `
+		SELECT n.nspname, c.relname, a.attname, format_type(a.atttypid, a.atttypmod)
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+		  AND n.nspname NOT LIKE 'pg_temp_%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
+		  AND has_table_privilege(c.oid, 'SELECT')
+		ORDER BY n.nspname, c.relname, a.attnum`
+
 	queryGetColumns = `
 		SELECT
 			c.column_name,
@@ -38,10 +70,413 @@ const (
 		  AND c.table_name = $2
 		ORDER BY c.ordinal_position`
 
+	queryGetColumnsBatch = `
+		SELECT
+			c.table_name,
+			c.column_name,
+			c.data_type,
+			c.is_nullable,
+			COALESCE(c.column_default, ''),
+			c.ordinal_position,
+			CASE WHEN pk.column_name IS NOT NULL THEN true ELSE false END AS is_primary
+		FROM information_schema.columns c
+		LEFT JOIN (
+			SELECT ku.table_name, ku.column_name
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage ku
+				ON tc.constraint_name = ku.constraint_name
+				AND tc.table_schema = ku.table_schema
+				AND tc.table_name = ku.table_name
+			WHERE tc.constraint_type = 'PRIMARY KEY'
+				AND tc.table_schema = $1
+				AND tc.table_name = ANY($2)
+		) pk ON c.table_name = pk.table_name AND c.column_name = pk.column_name
+		WHERE c.table_schema = $1
+		  AND c.table_name = ANY($2)
+		ORDER BY c.table_name, c.ordinal_position`
+
 	queryTableRowCount = `
 		SELECT COALESCE(reltuples, 0)::bigint
 		FROM pg_class c
 		JOIN pg_namespace n ON n.oid = c.relnamespace
 		WHERE c.relname = $1
 		  AND n.nspname = $2`
+
+	queryResolveTable = `
+		SELECT n.nspname, c.relname, c.oid
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass($1)`
+
+	queryTableColumns = `
+		SELECT
+			a.attname,
+			format_type(a.atttypid, a.atttypmod),
+			NOT a.attnotnull,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), ''),
+			a.attnum::int,
+			EXISTS (
+				SELECT 1 FROM pg_index i
+				WHERE i.indrelid = a.attrelid
+				  AND i.indisprimary
+				  AND a.attnum = ANY(i.indkey)
+			)
+		FROM pg_attribute a
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = $1
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		ORDER BY a.attnum`
+
+	queryTableKeys = `
+		SELECT
+			i.indisprimary,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+				WHERE k.ord <= i.indnkeyatts
+				ORDER BY k.ord
+			)
+		FROM pg_index i
+		WHERE i.indrelid = $1
+		  AND i.indisunique
+		  AND i.indisvalid
+		  AND i.indpred IS NULL
+		  AND i.indexprs IS NULL
+		ORDER BY i.indisprimary DESC, i.indnkeyatts, i.indexrelid`
+
+	queryTableForeignKeys = `
+		SELECT
+			con.conname,
+			n.nspname,
+			c.relname,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			rn.nspname,
+			rc.relname,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			con.conrelid = $1,
+			con.confrelid = $1
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class rc ON rc.oid = con.confrelid
+		JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+		WHERE con.contype = 'f'
+		  AND (con.conrelid = $1 OR con.confrelid = $1)
+		ORDER BY n.nspname, c.relname, con.conname`
+
+	// Catalog row versions (xmin) change with every DDL statement that
+	// rewrites the row, which makes them cheap change detectors.
+	querySchemaVersions = `
+		SELECT
+			n.nspname,
+			md5(
+				n.xmin::text
+				|| '|' || COALESCE((
+					SELECT string_agg(p.oid::text || ':' || p.xmin::text, ',' ORDER BY p.oid)
+					FROM pg_proc p
+					WHERE p.pronamespace = n.oid
+				), '')
+			)
+		FROM pg_namespace n
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+		  AND n.nspname NOT LIKE 'pg_temp_%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%'`
+
+	queryTableVersions = `
+		SELECT
+			n.nspname,
+			c.relname,
+			md5(
+				c.xmin::text
+				|| '|' || COALESCE((
+					SELECT string_agg(a.attnum::text || ':' || a.xmin::text, ',' ORDER BY a.attnum)
+					FROM pg_attribute a
+					WHERE a.attrelid = c.oid AND a.attnum > 0
+				), '')
+				|| '|' || COALESCE((
+					SELECT string_agg(d.adnum::text || ':' || d.xmin::text, ',' ORDER BY d.adnum)
+					FROM pg_attrdef d
+					WHERE d.adrelid = c.oid
+				), '')
+			)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm')
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+		  AND n.nspname NOT LIKE 'pg_temp_%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%'`
+
+	// Catalog introspection. Each query covers every schema in $1 at once.
+
+	queryCatalogTables = `
+		SELECT
+			n.nspname,
+			c.relname,
+			COALESCE(pg_get_partkeydef(c.oid), ''),
+			COALESCE(pn.nspname, ''),
+			COALESCE(pc.relname, ''),
+			COALESCE(pg_get_expr(c.relpartbound, c.oid), '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_inherits inh ON inh.inhrelid = c.oid AND c.relispartition
+		LEFT JOIN pg_class pc ON pc.oid = inh.inhparent
+		LEFT JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE c.relkind IN ('r', 'p')
+		  AND n.nspname = ANY($1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, c.relname`
+
+	queryCatalogColumns = `
+		SELECT
+			n.nspname,
+			c.relname,
+			a.attname,
+			format_type(a.atttypid, a.atttypmod),
+			NOT a.attnotnull,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), ''),
+			a.attnum::int,
+			a.attidentity::text,
+			a.attgenerated::text
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE c.relkind IN ('r', 'p')
+		  AND n.nspname = ANY($1)
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		ORDER BY n.nspname, c.relname, a.attnum`
+
+	queryCatalogIndexes = `
+		SELECT n.nspname, c.relname, ic.relname, pg_get_indexdef(i.indexrelid), i.indisunique
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p')
+		  AND n.nspname = ANY($1)
+		  AND NOT ic.relispartition
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_constraint con
+			WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x')
+		  )
+		ORDER BY n.nspname, c.relname, ic.relname`
+
+	queryCatalogConstraints = `
+		SELECT
+			n.nspname,
+			c.relname,
+			con.conname,
+			con.contype::text,
+			pg_get_constraintdef(con.oid),
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			COALESCE(rn.nspname, ''),
+			COALESCE(rc.relname, ''),
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_class rc ON rc.oid = con.confrelid
+		LEFT JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+		WHERE con.contype IN ('p', 'u', 'c', 'x', 'f')
+		  AND n.nspname = ANY($1)
+		  AND (con.conislocal OR NOT c.relispartition)
+		ORDER BY n.nspname, c.relname, con.conname`
+
+	queryCatalogViews = `
+		SELECT
+			n.nspname,
+			c.relname,
+			c.relkind = 'm',
+			pg_get_viewdef(c.oid, true),
+			ARRAY(
+				SELECT DISTINCT rn.nspname || '.' || rc.relname
+				FROM pg_rewrite r
+				JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.objid = r.oid
+				JOIN pg_class rc ON rc.oid = d.refobjid AND d.refclassid = 'pg_class'::regclass
+				JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+				WHERE r.ev_class = c.oid
+				  AND rc.oid <> c.oid
+				  AND rc.relkind IN ('v', 'm')
+			)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm')
+		  AND n.nspname = ANY($1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, c.relname`
+
+	queryCatalogFunctions = `
+		SELECT
+			n.nspname,
+			p.proname,
+			pg_get_function_identity_arguments(p.oid),
+			pg_get_functiondef(p.oid),
+			ARRAY(
+				SELECT DISTINCT tn.nspname || '.' || t.typname
+				FROM pg_depend d
+				JOIN pg_type t ON t.oid = d.refobjid AND d.refclassid = 'pg_type'::regclass
+				JOIN pg_namespace tn ON tn.oid = t.typnamespace
+				WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'n'
+			)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.prokind IN ('f', 'p')
+		  AND n.nspname = ANY($1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, p.proname, 3`
+
+	queryCatalogTypes = `
+		SELECT
+			n.nspname,
+			t.typname,
+			t.typtype::text,
+			CASE t.typtype
+				WHEN 'e' THEN 'AS ENUM (' || COALESCE((
+					SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder)
+					FROM pg_enum e WHERE e.enumtypid = t.oid
+				), '') || ')'
+				WHEN 'd' THEN 'AS ' || format_type(t.typbasetype, t.typtypmod)
+					|| COALESCE(' DEFAULT ' || t.typdefault, '')
+					|| CASE WHEN t.typnotnull THEN ' NOT NULL' ELSE '' END
+					|| COALESCE((
+						SELECT string_agg(' CONSTRAINT ' || quote_ident(con.conname) || ' ' || pg_get_constraintdef(con.oid), '' ORDER BY con.conname)
+						FROM pg_constraint con WHERE con.contypid = t.oid
+					), '')
+				WHEN 'c' THEN 'AS (' || COALESCE((
+					SELECT string_agg(quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod), ', ' ORDER BY a.attnum)
+					FROM pg_attribute a
+					WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+				), '') || ')'
+				WHEN 'r' THEN 'AS RANGE (SUBTYPE = ' || (
+					SELECT format_type(r.rngsubtype, NULL) FROM pg_range r WHERE r.rngtypid = t.oid
+				) || ')'
+			END,
+			ARRAY(
+				SELECT DISTINCT dn.nspname || '.' || dt.typname
+				FROM pg_type bt
+				JOIN pg_type dt ON dt.oid = CASE WHEN bt.typelem <> 0 AND bt.typlen = -1 THEN bt.typelem ELSE bt.oid END
+				JOIN pg_namespace dn ON dn.oid = dt.typnamespace
+				WHERE bt.oid = t.typbasetype
+				   OR bt.oid IN (
+					SELECT a.atttypid FROM pg_attribute a
+					WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+				   )
+			)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		LEFT JOIN pg_class c ON c.oid = t.typrelid
+		WHERE n.nspname = ANY($1)
+		  AND (t.typtype IN ('e', 'd', 'r') OR (t.typtype = 'c' AND c.relkind = 'c'))
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_type'::regclass AND d.objid = t.oid AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, t.typname`
+
+	queryCatalogSequences = `
+		SELECT
+			n.nspname,
+			c.relname,
+			format_type(s.seqtypid, NULL),
+			s.seqstart,
+			s.seqincrement,
+			s.seqmin,
+			s.seqmax,
+			s.seqcache,
+			s.seqcycle,
+			COALESCE((
+				SELECT quote_ident(tn.nspname) || '.' || quote_ident(tc.relname) || '.' || quote_ident(a.attname)
+				FROM pg_depend d
+				JOIN pg_class tc ON tc.oid = d.refobjid
+				JOIN pg_namespace tn ON tn.oid = tc.relnamespace
+				JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid
+				  AND d.refclassid = 'pg_class'::regclass AND d.deptype = 'a'
+				LIMIT 1
+			), '')
+		FROM pg_sequence s
+		JOIN pg_class c ON c.oid = s.seqrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ANY($1)
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype IN ('i', 'e')
+		  )
+		ORDER BY n.nspname, c.relname`
+
+	// queryCatalogGrants lists explicit privileges, leaving out those the
+	// owner holds implicitly.
+	queryCatalogGrants = `
+		SELECT kind, schema, name, args, grantee, array_agg(privilege ORDER BY privilege), grantable
+		FROM (
+			SELECT
+				CASE c.relkind WHEN 'S' THEN 'SEQUENCE' ELSE 'TABLE' END AS kind,
+				n.nspname AS schema, c.relname AS name, '' AS args,
+				COALESCE(r.rolname, 'PUBLIC') AS grantee,
+				a.privilege_type AS privilege, a.is_grantable AS grantable
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			CROSS JOIN LATERAL aclexplode(c.relacl) a
+			LEFT JOIN pg_roles r ON r.oid = a.grantee
+			WHERE c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+			  AND n.nspname = ANY($1)
+			  AND a.grantee <> c.relowner
+			UNION ALL
+			SELECT
+				CASE p.prokind WHEN 'p' THEN 'PROCEDURE' ELSE 'FUNCTION' END,
+				n.nspname, p.proname, pg_get_function_identity_arguments(p.oid),
+				COALESCE(r.rolname, 'PUBLIC'),
+				a.privilege_type, a.is_grantable
+			FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			CROSS JOIN LATERAL aclexplode(p.proacl) a
+			LEFT JOIN pg_roles r ON r.oid = a.grantee
+			WHERE p.prokind IN ('f', 'p')
+			  AND n.nspname = ANY($1)
+			  AND a.grantee <> p.proowner
+			UNION ALL
+			SELECT
+				'SCHEMA', n.nspname, '', '',
+				COALESCE(r.rolname, 'PUBLIC'),
+				a.privilege_type, a.is_grantable
+			FROM pg_namespace n
+			CROSS JOIN LATERAL aclexplode(n.nspacl) a
+			LEFT JOIN pg_roles r ON r.oid = a.grantee
+			WHERE n.nspname = ANY($1)
+			  AND a.grantee <> n.nspowner
+		) g
+		GROUP BY kind, schema, name, args, grantee, grantable
+		ORDER BY schema, kind, name, args, grantee, grantable`
 )
@@ -0,0 +1,119 @@
+package database
+
+import "strings"
+
+// reservedWords are keywords that cannot be used as bare identifiers.
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true, "both": true,
+	"case": true, "cast": true, "check": true, "collate": true, "column": true,
+	"constraint": true, "create": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "default": true, "deferrable": true, "desc": true,
+	"distinct": true, "do": true, "else": true, "end": true, "except": true,
+	"false": true, "fetch": true, "for": true, "foreign": true, "from": true,
+	"grant": true, "group": true, "having": true, "in": true, "initially": true,
+	"intersect": true, "into": true, "lateral": true, "leading": true, "limit": true,
+	"localtime": true, "localtimestamp": true, "not": true, "null": true,
+	"offset": true, "on": true, "only": true, "or": true, "order": true,
+	"placing": true, "primary": true, "references": true, "returning": true,
+	"select": true, "session_user": true, "some": true, "symmetric": true,
+	"table": true, "then": true, "to": true, "trailing": true, "true": true,
+	"union": true, "unique": true, "user": true, "using": true, "variadic": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+// QuoteIdent quotes an identifier when it would not survive unquoted:
+// mixed case, special characters, a leading digit or a reserved word.
+func QuoteIdent(name string) string {
+	if name == "" {
+		return `""`
+	}
+	needsQuote := reservedWords[name] || (name[0] >= '0' && name[0] <= '9')
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_') {
+			needsQuote = true
+			break
+		}
+	}
+	if !needsQuote {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QualifiedName returns schema.table with both parts quoted as needed.
+// The public schema is left implicit, matching how queries are usually typed.
+func QualifiedName(schema, table string) string {
+	if schema == "" || schema == "public" {
+		return QuoteIdent(table)
+	}
+	return QuoteIdent(schema) + "." + QuoteIdent(table)
+}
+
+// QuoteLiteral returns s as a single-quoted SQL string literal.
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// SplitQualifiedName splits a possibly quoted, possibly schema-qualified
+// name such as public."Order Items" into its schema and table parts.
+// Unquoted parts are folded to lower case like the server does.
+func SplitQualifiedName(name string) (schema, table string, ok bool) {
+	var parts []string
+	var cur strings.Builder
+	inQuote := false
+	quoted := false
+
+	flush := func() {
+		part := cur.String()
+		if !quoted {
+			part = strings.ToLower(part)
+		}
+		parts = append(parts, part)
+		cur.Reset()
+		quoted = false
+	}
+
+	runes := []rune(strings.TrimSpace(name))
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case inQuote && c == '"':
+			if i+1 < len(runes) && runes[i+1] == '"' {
+				cur.WriteRune('"')
+				i++
+				continue
+			}
+			inQuote = false
+		case inQuote:
+			cur.WriteRune(c)
+		case c == '"':
+			inQuote = true
+			quoted = true
+		case c == '.':
+			flush()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	if inQuote {
+		return "", "", false
+	}
+	flush()
+
+	for _, p := range parts {
+		if p == "" {
+			return "", "", false
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		return "", parts[0], true
+	case 2:
+		return parts[0], parts[1], true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,316 @@
+// Package datadiff compares two query results row by row, matching rows
+// on one or more key columns.
+package datadiff
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+// Status says how a row differs between the two results.
+type Status int
+
+const (
+	Unchanged Status = iota
+	Added            // only in the new result
+	Removed          // only in the old result
+	Changed          // in both, with different values
+)
+
+// Symbol returns the marker shown next to the row.
+func (s Status) Symbol() string {
+	switch s {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	case Changed:
+		return "~"
+	default:
+		return " "
+	}
+}
+
+func (s Status) String() string {
+	switch s {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	default:
+		return "unchanged"
+	}
+}
+
+// Row is one matched row. Old is nil for added rows and New is nil for
+// removed rows. Changed flags the columns whose values differ.
+type Row struct {
+	Status  Status
+	Old     []string
+	New     []string
+	Changed []bool
+}
+
+// Value returns the cell to display: the new value, or the old one for
+// removed rows.
+func (r Row) Value(col int) string {
+	cells := r.New
+	if cells == nil {
+		cells = r.Old
+	}
+	if col < 0 || col >= len(cells) {
+		return ""
+	}
+	return cells[col]
+}
+
+// Result is the comparison of two query results.
+type Result struct {
+	Columns []string
+	Keys    []string
+	Rows    []Row
+
+	Added     int
+	Removed   int
+	Changed   int
+	Unchanged int
+}
+
+// Compare matches the rows of before and after on the key columns.
+// Columns are aligned by name; a column present on only one side reads as
+// null on the other and is not compared. Rows of after keep their order,
+// removed rows follow at the end.
+func Compare(before, after *database.QueryResult, keys []string) (*Result, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no key column chosen")
+	}
+
+	res := &Result{Keys: keys}
+	res.Columns = append(res.Columns, after.Columns...)
+	for _, c := range before.Columns {
+		if indexOf(after.Columns, c) < 0 {
+			res.Columns = append(res.Columns, c)
+		}
+	}
+
+	oldRows, err := align(before, res.Columns)
+	if err != nil {
+		return nil, err
+	}
+	newRows, err := align(after, res.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	// only columns present on both sides can differ
+	shared := make([]bool, len(res.Columns))
+	for i, c := range res.Columns {
+		shared[i] = indexOf(before.Columns, c) >= 0 && indexOf(after.Columns, c) >= 0
+	}
+
+	keyIdx := make([]int, len(keys))
+	for i, k := range keys {
+		keyIdx[i] = indexOf(res.Columns, k)
+		if keyIdx[i] < 0 {
+			return nil, fmt.Errorf("key column %q is not in the results", k)
+		}
+	}
+
+	oldByKey := make(map[string]int, len(oldRows))
+	for i, row := range oldRows {
+		k := rowKey(row, keyIdx)
+		if _, dup := oldByKey[k]; dup {
+			return nil, fmt.Errorf("key %s is not unique in the old result", describeKey(keys, row, keyIdx))
+		}
+		oldByKey[k] = i
+	}
+
+	matched := make([]bool, len(oldRows))
+	seen := make(map[string]bool, len(newRows))
+	for _, row := range newRows {
+		k := rowKey(row, keyIdx)
+		if seen[k] {
+			return nil, fmt.Errorf("key %s is not unique in the new result", describeKey(keys, row, keyIdx))
+		}
+		seen[k] = true
+
+		oi, ok := oldByKey[k]
+		if !ok {
+			res.Rows = append(res.Rows, Row{Status: Added, New: row, Changed: make([]bool, len(row))})
+			res.Added++
+			continue
+		}
+		matched[oi] = true
+		old := oldRows[oi]
+
+		d := Row{Status: Unchanged, Old: old, New: row, Changed: make([]bool, len(row))}
+		for i := range row {
+			if shared[i] && row[i] != old[i] {
+				d.Changed[i] = true
+				d.Status = Changed
+			}
+		}
+		if d.Status == Changed {
+			res.Changed++
+		} else {
+			res.Unchanged++
+		}
+		res.Rows = append(res.Rows, d)
+	}
+
+	for i, row := range oldRows {
+		if !matched[i] {
+			res.Rows = append(res.Rows, Row{Status: Removed, Old: row, Changed: make([]bool, len(row))})
+			res.Removed++
+		}
+	}
+
+	return res, nil
+}
+
+// Identical reports whether the results hold the same rows.
+func (r *Result) Identical() bool {
+	return r.Added == 0 && r.Removed == 0 && r.Changed == 0
+}
+
+// Differences returns only the rows that were added, removed or changed.
+func (r *Result) Differences() []Row {
+	var out []Row
+	for _, row := range r.Rows {
+		if row.Status != Unchanged {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// WriteCSV writes the differences with a leading status column. Changed
+// cells are written as "old → new".
+func (r *Result) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"status"}, r.Columns...)); err != nil {
+		return err
+	}
+	for _, row := range r.Differences() {
+		record := make([]string, 0, len(r.Columns)+1)
+		record = append(record, row.Status.String())
+		for i := range r.Columns {
+			if row.Changed[i] {
+				record = append(record, row.Old[i]+" → "+row.New[i])
+			} else {
+				record = append(record, row.Value(i))
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonRow struct {
+	Status string         `json:"status"`
+	Key    map[string]any `json:"key"`
+	Old    map[string]any `json:"old,omitempty"`
+	New    map[string]any `json:"new,omitempty"`
+}
+
+// WriteJSON writes the differences as an array of objects holding the
+// key plus the old and new versions of each row.
+func (r *Result) WriteJSON(w io.Writer) error {
+	out := []jsonRow{}
+	for _, row := range r.Differences() {
+		jr := jsonRow{Status: row.Status.String(), Key: map[string]any{}}
+		for _, k := range r.Keys {
+			i := indexOf(r.Columns, k)
+			jr.Key[k] = jsonValue(row.Value(i))
+		}
+		if row.Old != nil {
+			jr.Old = r.object(row.Old)
+		}
+		if row.New != nil {
+			jr.New = r.object(row.New)
+		}
+		out = append(out, jr)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func (r *Result) object(cells []string) map[string]any {
+	obj := make(map[string]any, len(cells))
+	for i, c := range r.Columns {
+		obj[c] = jsonValue(cells[i])
+	}
+	return obj
+}
+
+// jsonValue maps the grid's null marker to a JSON null.
+func jsonValue(v string) any {
+	if v == "null" {
+		return nil
+	}
+	return v
+}
+
+// align reorders the cells of every row to match columns.
+func align(r *database.QueryResult, columns []string) ([][]string, error) {
+	pos := make([]int, len(columns))
+	for i, c := range columns {
+		pos[i] = indexOf(r.Columns, c)
+	}
+	for i, c := range r.Columns {
+		if indexOf(r.Columns[:i], c) >= 0 {
+			return nil, fmt.Errorf("column %q appears more than once", c)
+		}
+	}
+
+	rows := make([][]string, len(r.Rows))
+	for ri, row := range r.Rows {
+		out := make([]string, len(columns))
+		for i, p := range pos {
+			if p >= 0 && p < len(row) {
+				out[i] = row[p]
+			} else {
+				out[i] = "null"
+			}
+		}
+		rows[ri] = out
+	}
+	return rows, nil
+}
+
+func rowKey(row []string, keyIdx []int) string {
+	parts := make([]string, len(keyIdx))
+	for i, k := range keyIdx {
+		parts[i] = row[k]
+	}
+	// the unit separator cannot appear in ordinary text values
+	return strings.Join(parts, "\x1f")
+}
+
+func describeKey(keys []string, row []string, keyIdx []int) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + row[keyIdx[i]]
+	}
+	return strings.Join(parts, ", ")
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,146 @@
+package datadiff
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+func result(cols []string, rows ...[]string) *database.QueryResult {
+	return &database.QueryResult{Columns: cols, Rows: rows, RowCount: len(rows)}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     *database.QueryResult
+		after      *database.QueryResult
+		keys       []string
+		columns    []string
+		statuses   string // one Symbol per row
+		counts     [4]int // added, removed, changed, unchanged
+		changedCol []string
+	}{
+		{
+			name:     "identical",
+			before:   result([]string{"id", "v"}, []string{"1", "a"}, []string{"2", "b"}),
+			after:    result([]string{"id", "v"}, []string{"1", "a"}, []string{"2", "b"}),
+			keys:     []string{"id"},
+			columns:  []string{"id", "v"},
+			statuses: "  ",
+			counts:   [4]int{0, 0, 0, 2},
+		},
+		{
+			name:       "added, removed and changed",
+			before:     result([]string{"id", "v"}, []string{"1", "a"}, []string{"2", "b"}),
+			after:      result([]string{"id", "v"}, []string{"3", "c"}, []string{"1", "x"}),
+			keys:       []string{"id"},
+			columns:    []string{"id", "v"},
+			statuses:   "+~-",
+			counts:     [4]int{1, 1, 1, 0},
+			changedCol: []string{"v"},
+		},
+		{
+			name:     "columns aligned by name",
+			before:   result([]string{"v", "id"}, []string{"a", "1"}),
+			after:    result([]string{"id", "v"}, []string{"1", "a"}),
+			keys:     []string{"id"},
+			columns:  []string{"id", "v"},
+			statuses: " ",
+			counts:   [4]int{0, 0, 0, 1},
+		},
+		{
+			name:     "column on one side only is not compared",
+			before:   result([]string{"id", "gone"}, []string{"1", "x"}),
+			after:    result([]string{"id", "new"}, []string{"1", "y"}),
+			keys:     []string{"id"},
+			columns:  []string{"id", "new", "gone"},
+			statuses: " ",
+			counts:   [4]int{0, 0, 0, 1},
+		},
+		{
+			name:     "composite key",
+			before:   result([]string{"a", "b", "v"}, []string{"1", "1", "x"}, []string{"1", "2", "y"}),
+			after:    result([]string{"a", "b", "v"}, []string{"1", "2", "y"}, []string{"2", "1", "z"}),
+			keys:     []string{"a", "b"},
+			columns:  []string{"a", "b", "v"},
+			statuses: " +-",
+			counts:   [4]int{1, 1, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		res, err := Compare(tt.before, tt.after, tt.keys)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(res.Columns, tt.columns) {
+			t.Errorf("%s: columns = %q, want %q", tt.name, res.Columns, tt.columns)
+		}
+		var statuses strings.Builder
+		var changed []string
+		for _, row := range res.Rows {
+			statuses.WriteString(row.Status.Symbol())
+			for i, c := range row.Changed {
+				if c {
+					changed = append(changed, res.Columns[i])
+				}
+			}
+		}
+		if statuses.String() != tt.statuses {
+			t.Errorf("%s: statuses = %q, want %q", tt.name, statuses.String(), tt.statuses)
+		}
+		if got := [4]int{res.Added, res.Removed, res.Changed, res.Unchanged}; got != tt.counts {
+			t.Errorf("%s: counts = %v, want %v", tt.name, got, tt.counts)
+		}
+		if !slices.Equal(changed, tt.changedCol) {
+			t.Errorf("%s: changed columns = %q, want %q", tt.name, changed, tt.changedCol)
+		}
+		if res.Identical() != (tt.counts[0]+tt.counts[1]+tt.counts[2] == 0) {
+			t.Errorf("%s: Identical() = %v", tt.name, res.Identical())
+		}
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	cols := []string{"id", "v"}
+	tests := []struct {
+		name          string
+		before, after *database.QueryResult
+		keys          []string
+		want          string
+	}{
+		{"no key", result(cols), result(cols), nil, "no key column"},
+		{"missing key", result(cols), result(cols), []string{"nope"}, `"nope" is not in the results`},
+		{"duplicate old key", result(cols, []string{"1", "a"}, []string{"1", "b"}), result(cols), []string{"id"}, "not unique in the old result"},
+		{"duplicate new key", result(cols), result(cols, []string{"1", "a"}, []string{"1", "b"}), []string{"id"}, "not unique in the new result"},
+		{"repeated column", result([]string{"id", "id"}), result(cols), []string{"id"}, "appears more than once"},
+	}
+	for _, tt := range tests {
+		_, err := Compare(tt.before, tt.after, tt.keys)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	res, err := Compare(
+		result([]string{"id", "v"}, []string{"1", "a"}, []string{"2", "b"}),
+		result([]string{"id", "v"}, []string{"1", "x"}, []string{"3", "c"}),
+		[]string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := res.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "status,id,v\nchanged,1,a → x\nadded,3,c\nremoved,2,b\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,314 @@
+// Package ddl renders catalog objects as PostgreSQL DDL statements.
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+// Name returns the schema-qualified, quoted name of an object. Unlike
+// database.QualifiedName the schema is always included, so scripts do
+// not depend on the search path of whoever runs them.
+func Name(schema, name string) string {
+	return database.QuoteIdent(schema) + "." + database.QuoteIdent(name)
+}
+
+// ColumnDefinition renders a column as it appears in CREATE TABLE.
+func ColumnDefinition(c database.Column) string {
+	var b strings.Builder
+	b.WriteString(database.QuoteIdent(c.Name))
+	b.WriteString(" ")
+	b.WriteString(c.DataType)
+	switch c.Identity {
+	case "a":
+		b.WriteString(" GENERATED ALWAYS AS IDENTITY")
+	case "d":
+		b.WriteString(" GENERATED BY DEFAULT AS IDENTITY")
+	}
+	switch {
+	case c.Generated == "s":
+		b.WriteString(" GENERATED ALWAYS AS (")
+		b.WriteString(c.Default)
+		b.WriteString(") STORED")
+	case c.Default != "":
+		b.WriteString(" DEFAULT ")
+		b.WriteString(c.Default)
+	}
+	if !c.IsNullable {
+		b.WriteString(" NOT NULL")
+	}
+	return b.String()
+}
+
+// CreateTable renders CREATE TABLE with the table's columns and its
+// primary key, unique, check and exclusion constraints inline. Foreign
+// keys and indexes are separate statements so they can be ordered after
+// every table exists. A partition is created as PARTITION OF its parent,
+// which must exist first, and lists only its own constraints.
+func CreateTable(t database.CatalogTable) string {
+	var lines []string
+	if !t.IsPartition() {
+		for _, c := range t.Columns {
+			lines = append(lines, "    "+ColumnDefinition(c))
+		}
+	}
+	for _, con := range t.Constraints {
+		if con.Kind == database.ConstraintForeignKey {
+			continue
+		}
+		lines = append(lines, "    CONSTRAINT "+database.QuoteIdent(con.Name)+" "+con.Definition)
+	}
+
+	stmt := "CREATE TABLE " + Name(t.Schema, t.Name)
+	if t.IsPartition() {
+		stmt += " PARTITION OF " + Name(t.ParentSchema, t.ParentName)
+	}
+	if len(lines) > 0 || !t.IsPartition() {
+		stmt += " (\n" + strings.Join(lines, ",\n") + "\n)"
+	}
+	if t.IsPartition() {
+		stmt += " " + t.PartitionBound
+	}
+	if t.PartitionKey != "" {
+		stmt += " PARTITION BY " + t.PartitionKey
+	}
+	return stmt + ";"
+}
+
+// TableOrder returns the indexes of tables sorted so every partition
+// follows its parent, keeping the given order otherwise.
+func TableOrder(tables []database.CatalogTable) []int {
+	index := make(map[string]int, len(tables))
+	for i, t := range tables {
+		index[t.Schema+"."+t.Name] = i
+	}
+	placed := make([]bool, len(tables))
+	order := make([]int, 0, len(tables))
+	var place func(i int)
+	place = func(i int) {
+		if placed[i] {
+			return
+		}
+		placed[i] = true
+		t := tables[i]
+		if j, ok := index[t.ParentSchema+"."+t.ParentName]; ok && t.IsPartition() {
+			place(j)
+		}
+		order = append(order, i)
+	}
+	for i := range tables {
+		place(i)
+	}
+	return order
+}
+
+// TableDefinition renders the table with its indexes and foreign keys,
+// for display.
+func TableDefinition(t database.CatalogTable) string {
+	parts := []string{CreateTable(t)}
+	for _, idx := range t.Indexes {
+		parts = append(parts, CreateIndex(idx))
+	}
+	for _, con := range t.Constraints {
+		if con.Kind == database.ConstraintForeignKey {
+			parts = append(parts, AddConstraint(t.Schema, t.Name, con))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// DropTable renders DROP TABLE.
+func DropTable(schema, table string) string {
+	return "DROP TABLE " + Name(schema, table) + ";"
+}
+
+// AddColumn renders ALTER TABLE ... ADD COLUMN.
+func AddColumn(schema, table string, c database.Column) string {
+	return "ALTER TABLE " + Name(schema, table) + " ADD COLUMN " + ColumnDefinition(c) + ";"
+}
+
+// DropColumn renders ALTER TABLE ... DROP COLUMN.
+func DropColumn(schema, table, column string) string {
+	return "ALTER TABLE " + Name(schema, table) + " DROP COLUMN " + database.QuoteIdent(column) + ";"
+}
+
+// AlterColumn renders the statements that turn column from into column to.
+func AlterColumn(schema, table string, from, to database.Column) []string {
+	prefix := "ALTER TABLE " + Name(schema, table) + " ALTER COLUMN " + database.QuoteIdent(to.Name) + " "
+	var stmts []string
+
+	if from.DataType != to.DataType {
+		stmts = append(stmts, prefix+"TYPE "+to.DataType+
+			" USING "+database.QuoteIdent(to.Name)+"::"+to.DataType+";")
+	}
+	if from.Identity != to.Identity {
+		switch {
+		case to.Identity == "":
+			stmts = append(stmts, prefix+"DROP IDENTITY IF EXISTS;")
+		case from.Identity == "":
+			kind := "ALWAYS"
+			if to.Identity == "d" {
+				kind = "BY DEFAULT"
+			}
+			stmts = append(stmts, prefix+"ADD GENERATED "+kind+" AS IDENTITY;")
+		default:
+			kind := "ALWAYS"
+			if to.Identity == "d" {
+				kind = "BY DEFAULT"
+			}
+			stmts = append(stmts, prefix+"SET GENERATED "+kind+";")
+		}
+	}
+	switch {
+	case from.Generated != to.Generated && to.Generated == "":
+		stmts = append(stmts, prefix+"DROP EXPRESSION;")
+		if to.Default != "" {
+			stmts = append(stmts, prefix+"SET DEFAULT "+to.Default+";")
+		}
+	case from.Generated != to.Generated:
+		// an existing column cannot become generated
+		stmts = append(stmts, "-- "+Name(schema, table)+"."+database.QuoteIdent(to.Name)+
+			" becomes generated: drop it and add it again as "+ColumnDefinition(to))
+	case to.Generated != "":
+		if from.Default != to.Default {
+			stmts = append(stmts, prefix+"SET EXPRESSION AS ("+to.Default+");")
+		}
+	case from.Default != to.Default:
+		if to.Default == "" {
+			stmts = append(stmts, prefix+"DROP DEFAULT;")
+		} else {
+			stmts = append(stmts, prefix+"SET DEFAULT "+to.Default+";")
+		}
+	}
+	if from.IsNullable != to.IsNullable {
+		if to.IsNullable {
+			stmts = append(stmts, prefix+"DROP NOT NULL;")
+		} else {
+			stmts = append(stmts, prefix+"SET NOT NULL;")
+		}
+	}
+	return stmts
+}
+
+// AddConstraint renders ALTER TABLE ... ADD CONSTRAINT.
+func AddConstraint(schema, table string, con database.Constraint) string {
+	return "ALTER TABLE " + Name(schema, table) + " ADD CONSTRAINT " +
+		database.QuoteIdent(con.Name) + " " + con.Definition + ";"
+}
+
+// DropConstraint renders ALTER TABLE ... DROP CONSTRAINT.
+func DropConstraint(schema, table, name string) string {
+	return "ALTER TABLE " + Name(schema, table) + " DROP CONSTRAINT " + database.QuoteIdent(name) + ";"
+}
+
+// CreateIndex renders the index definition as a statement.
+func CreateIndex(idx database.Index) string {
+	return idx.Definition + ";"
+}
+
+// DropIndex renders DROP INDEX.
+func DropIndex(schema, name string) string {
+	return "DROP INDEX " + Name(schema, name) + ";"
+}
+
+// CreateView renders CREATE VIEW or CREATE MATERIALIZED VIEW.
+func CreateView(v database.View) string {
+	kind := "VIEW"
+	if v.Materialized {
+		kind = "MATERIALIZED VIEW"
+	}
+	body := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v.Definition), ";"))
+	return "CREATE " + kind + " " + Name(v.Schema, v.Name) + " AS\n" + body + ";"
+}
+
+// DropView renders DROP VIEW or DROP MATERIALIZED VIEW.
+func DropView(v database.View) string {
+	kind := "VIEW"
+	if v.Materialized {
+		kind = "MATERIALIZED VIEW"
+	}
+	return "DROP " + kind + " " + Name(v.Schema, v.Name) + ";"
+}
+
+// CreateFunction renders the function definition, which the server
+// already reports as a complete CREATE OR REPLACE statement.
+func CreateFunction(f database.Function) string {
+	def := strings.TrimSpace(f.Definition)
+	if !strings.HasSuffix(def, ";") {
+		def += ";"
+	}
+	return def
+}
+
+// DropFunction renders DROP ROUTINE, which covers functions and procedures.
+func DropFunction(f database.Function) string {
+	return "DROP ROUTINE " + database.QuoteIdent(f.Schema) + "." +
+		database.QuoteIdent(f.Name) + "(" + f.Arguments + ");"
+}
+
+// CreateSchema renders CREATE SCHEMA.
+func CreateSchema(name string) string {
+	return "CREATE SCHEMA " + database.QuoteIdent(name) + ";"
+}
+
+// CreateType renders CREATE TYPE, or CREATE DOMAIN for domains.
+func CreateType(t database.Type) string {
+	kind := "TYPE"
+	if t.Kind == database.TypeDomain {
+		kind = "DOMAIN"
+	}
+	return "CREATE " + kind + " " + Name(t.Schema, t.Name) + " " + t.Definition + ";"
+}
+
+// CreateSequence renders CREATE SEQUENCE with every option spelled out.
+func CreateSequence(s database.Sequence) string {
+	stmt := fmt.Sprintf("CREATE SEQUENCE %s AS %s START WITH %d INCREMENT BY %d MINVALUE %d MAXVALUE %d CACHE %d",
+		Name(s.Schema, s.Name), s.DataType, s.Start, s.Increment, s.Min, s.Max, s.Cache)
+	if s.Cycle {
+		stmt += " CYCLE"
+	}
+	return stmt + ";"
+}
+
+// SequenceOwnedBy renders ALTER SEQUENCE ... OWNED BY, which ties a serial
+// sequence to its column once the table exists.
+func SequenceOwnedBy(s database.Sequence) string {
+	return "ALTER SEQUENCE " + Name(s.Schema, s.Name) + " OWNED BY " + s.OwnedBy + ";"
+}
+
+// Grant renders GRANT for one grantee on one object.
+func Grant(g database.Grant) string {
+	var target string
+	switch g.ObjectType {
+	case "SCHEMA":
+		target = database.QuoteIdent(g.Schema)
+	case "FUNCTION", "PROCEDURE":
+		target = Name(g.Schema, g.Name) + "(" + g.Arguments + ")"
+	default:
+		target = Name(g.Schema, g.Name)
+	}
+	grantee := g.Grantee
+	if grantee != "PUBLIC" {
+		grantee = database.QuoteIdent(grantee)
+	}
+	stmt := "GRANT " + strings.Join(g.Privileges, ", ") + " ON " + g.ObjectType + " " + target + " TO " + grantee
+	if g.WithGrantOption {
+		stmt += " WITH GRANT OPTION"
+	}
+	return stmt + ";"
+}
+
+var nextvalPattern = regexp.MustCompile(`nextval\('((?:[^']|'')+)'(?:::regclass)?\)`)
+
+// SequenceReference returns the sequence a column default draws from,
+// e.g. public.users_id_seq for a serial column.
+func SequenceReference(def string) (string, bool) {
+	m := nextvalPattern.FindStringSubmatch(def)
+	if m == nil {
+		return "", false
+	}
+	return strings.ReplaceAll(m[1], "''", "'"), true
+}
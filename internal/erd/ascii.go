@@ -0,0 +1,208 @@
+package erd
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ASCII draws one table between the tables it references (left) and the
+// tables referencing it (right), followed by the list of relationships.
+// The columns are stacked instead when they do not fit in width.
+func (d *Diagram) ASCII(focus string, width int) string {
+	center, ok := d.Table(focus)
+	if !ok {
+		return "table " + focus + " is not in the diagram\n"
+	}
+
+	var parents, children []string
+	var relations []string
+	for _, e := range d.Edges {
+		switch {
+		case e.From == focus && e.To == focus:
+			relations = append(relations, describeEdge(e)+"  (self)")
+			continue
+		case e.From == focus:
+			parents = appendUnique(parents, e.To)
+		case e.To == focus:
+			children = appendUnique(children, e.From)
+		default:
+			continue
+		}
+		relations = append(relations, describeEdge(e))
+	}
+
+	left := d.boxes(parents, focus)
+	middle := box(center)
+	right := d.boxes(children, focus)
+
+	var lines []string
+	if wide := joinColumns([][]string{left, middle, right}, "   "); maxWidth(wide) <= width {
+		lines = wide
+	} else {
+		if len(left) > 0 {
+			lines = append(lines, "References:")
+			lines = append(lines, left...)
+		}
+		lines = append(lines, middle...)
+		if len(right) > 0 {
+			lines = append(lines, "Referenced by:")
+			lines = append(lines, right...)
+		}
+	}
+
+	if len(relations) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, relations...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// boxes stacks compact boxes for the given tables, showing only the
+// columns that take part in a relationship with focus.
+func (d *Diagram) boxes(keys []string, focus string) []string {
+	var lines []string
+	for _, k := range keys {
+		t, ok := d.Table(k)
+		if !ok {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, box(d.related(t, focus))...)
+	}
+	return lines
+}
+
+// related keeps the primary key and the columns of edges between t and focus.
+func (d *Diagram) related(t Table, focus string) Table {
+	keep := make(map[string]bool)
+	for _, e := range d.Edges {
+		if e.From == t.Key() && e.To == focus {
+			for _, c := range e.FromColumns {
+				keep[c] = true
+			}
+		}
+		if e.To == t.Key() && e.From == focus {
+			for _, c := range e.ToColumns {
+				keep[c] = true
+			}
+		}
+	}
+	out := Table{Schema: t.Schema, Name: t.Name}
+	for _, c := range t.Columns {
+		if c.PK || keep[c.Name] {
+			out.Columns = append(out.Columns, c)
+		}
+	}
+	if hidden := len(t.Columns) - len(out.Columns); hidden > 0 {
+		out.Columns = append(out.Columns, Column{Name: fmt.Sprintf("… %d more", hidden)})
+	}
+	return out
+}
+
+func box(t Table) []string {
+	nameW, typeW := 0, 0
+	for _, c := range t.Columns {
+		nameW = max(nameW, utf8.RuneCountInString(c.Name))
+		typeW = max(typeW, utf8.RuneCountInString(c.Type))
+	}
+
+	rows := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		marker := "   "
+		switch {
+		case c.PK && c.FK:
+			marker = "PF "
+		case c.PK:
+			marker = "PK "
+		case c.FK:
+			marker = "FK "
+		}
+		row := marker + pad(c.Name, nameW)
+		if typeW > 0 {
+			row += "  " + c.Type
+		}
+		rows = append(rows, strings.TrimRight(row, " "))
+	}
+
+	title := " " + t.Key() + " "
+	inner := utf8.RuneCountInString(title) + 2
+	for _, r := range rows {
+		inner = max(inner, utf8.RuneCountInString(r)+2)
+	}
+
+	lines := []string{"┌─" + title + strings.Repeat("─", inner-utf8.RuneCountInString(title)-1) + "┐"}
+	for _, r := range rows {
+		lines = append(lines, "│ "+pad(r, inner-2)+" │")
+	}
+	lines = append(lines, "└"+strings.Repeat("─", inner)+"┘")
+	return lines
+}
+
+func describeEdge(e Edge) string {
+	arrow := "──▶"
+	if e.Optional {
+		arrow = "╌╌▶"
+	}
+	return fmt.Sprintf("%s(%s) %s %s(%s)", e.From, strings.Join(e.FromColumns, ", "),
+		arrow, e.To, strings.Join(e.ToColumns, ", "))
+}
+
+// joinColumns lays blocks of lines side by side, skipping empty blocks.
+func joinColumns(blocks [][]string, gap string) []string {
+	var cols [][]string
+	for _, b := range blocks {
+		if len(b) > 0 {
+			cols = append(cols, b)
+		}
+	}
+	height := 0
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		height = max(height, len(c))
+		widths[i] = maxWidth(c)
+	}
+
+	lines := make([]string, height)
+	for row := range lines {
+		var b strings.Builder
+		for i, c := range cols {
+			if i > 0 {
+				b.WriteString(gap)
+			}
+			cell := ""
+			if row < len(c) {
+				cell = c[row]
+			}
+			b.WriteString(pad(cell, widths[i]))
+		}
+		lines[row] = strings.TrimRight(b.String(), " ")
+	}
+	return lines
+}
+
+func maxWidth(lines []string) int {
+	w := 0
+	for _, l := range lines {
+		w = max(w, utf8.RuneCountInString(l))
+	}
+	return w
+}
+
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
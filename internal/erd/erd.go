@@ -0,0 +1,167 @@
+// Package erd builds entity-relationship diagrams from catalog metadata
+// and renders them as Mermaid, Graphviz DOT, PlantUML or plain text.
+package erd
+
+import (
+	"slices"
+
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+// Diagram is a set of tables and the foreign keys between them.
+type Diagram struct {
+	Tables []Table
+	Edges  []Edge
+}
+
+// Table is an entity in the diagram.
+type Table struct {
+	Schema  string
+	Name    string
+	Columns []Column
+}
+
+// Column is a table column with its key markers.
+type Column struct {
+	Name     string
+	Type     string
+	Nullable bool
+	PK       bool
+	FK       bool
+}
+
+// Edge is a foreign key from a child table to the parent it references.
+type Edge struct {
+	Name        string
+	From        string // child table, as schema.name
+	FromColumns []string
+	To          string // parent table, as schema.name
+	ToColumns   []string
+	Optional    bool // a child may reference no parent
+	OneToOne    bool // the foreign key columns are unique in the child
+}
+
+// Key returns the table's schema.name identifier.
+func (t Table) Key() string {
+	return t.Schema + "." + t.Name
+}
+
+// Build creates a diagram of the tables accepted by include, with every
+// foreign key whose two ends are both included. A nil include keeps every
+// table.
+func Build(cat *database.Catalog, include func(schema, table string) bool) *Diagram {
+	d := &Diagram{}
+	in := make(map[string]bool)
+	for _, t := range cat.Tables {
+		if include == nil || include(t.Schema, t.Name) {
+			in[t.Schema+"."+t.Name] = true
+		}
+	}
+
+	for _, t := range cat.Tables {
+		if !in[t.Schema+"."+t.Name] {
+			continue
+		}
+
+		var pk []string
+		fkCols := make(map[string]bool)
+		var uniques [][]string
+		for _, con := range t.Constraints {
+			switch con.Kind {
+			case database.ConstraintPrimaryKey:
+				pk = con.Columns
+				uniques = append(uniques, con.Columns)
+			case database.ConstraintUnique:
+				uniques = append(uniques, con.Columns)
+			case database.ConstraintForeignKey:
+				for _, c := range con.Columns {
+					fkCols[c] = true
+				}
+			}
+		}
+
+		table := Table{Schema: t.Schema, Name: t.Name}
+		nullable := make(map[string]bool)
+		for _, c := range t.Columns {
+			nullable[c.Name] = c.IsNullable
+			table.Columns = append(table.Columns, Column{
+				Name:     c.Name,
+				Type:     c.DataType,
+				Nullable: c.IsNullable,
+				PK:       slices.Contains(pk, c.Name),
+				FK:       fkCols[c.Name],
+			})
+		}
+		d.Tables = append(d.Tables, table)
+
+		for _, con := range t.Constraints {
+			parent := con.RefSchema + "." + con.RefTable
+			if con.Kind != database.ConstraintForeignKey || !in[parent] {
+				continue
+			}
+			e := Edge{
+				Name:        con.Name,
+				From:        table.Key(),
+				FromColumns: con.Columns,
+				To:          parent,
+				ToColumns:   con.RefColumns,
+			}
+			for _, c := range con.Columns {
+				if nullable[c] {
+					e.Optional = true
+				}
+			}
+			for _, u := range uniques {
+				if sameSet(u, con.Columns) {
+					e.OneToOne = true
+				}
+			}
+			d.Edges = append(d.Edges, e)
+		}
+	}
+	return d
+}
+
+// Neighbourhood creates a diagram of one table and every table it
+// references or is referenced by.
+func Neighbourhood(cat *database.Catalog, schema, table string) *Diagram {
+	focus := schema + "." + table
+	near := map[string]bool{focus: true}
+	for _, t := range cat.Tables {
+		for _, con := range t.Constraints {
+			if con.Kind != database.ConstraintForeignKey {
+				continue
+			}
+			child, parent := t.Schema+"."+t.Name, con.RefSchema+"."+con.RefTable
+			if child == focus {
+				near[parent] = true
+			}
+			if parent == focus {
+				near[child] = true
+			}
+		}
+	}
+	return Build(cat, func(s, t string) bool { return near[s+"."+t] })
+}
+
+// Table returns the table with the given schema.name key.
+func (d *Diagram) Table(key string) (Table, bool) {
+	for _, t := range d.Tables {
+		if t.Key() == key {
+			return t, true
+		}
+	}
+	return Table{}, false
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		if !slices.Contains(b, x) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+package erd
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Format is a diagram text format.
+type Format string
+
+const (
+	FormatMermaid  Format = "mermaid"
+	FormatDOT      Format = "dot"
+	FormatPlantUML Format = "plantuml"
+)
+
+// Extension returns the usual file extension for the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatDOT:
+		return ".dot"
+	case FormatPlantUML:
+		return ".puml"
+	default:
+		return ".mmd"
+	}
+}
+
+// ParseFormat accepts a format name or its common short forms.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "mermaid", "mmd":
+		return FormatMermaid, nil
+	case "dot", "graphviz", "gv":
+		return FormatDOT, nil
+	case "plantuml", "puml", "uml":
+		return FormatPlantUML, nil
+	}
+	return "", fmt.Errorf("unknown diagram format %q (want mermaid, dot or plantuml)", s)
+}
+
+// Render returns the diagram in the given format.
+func (d *Diagram) Render(f Format) string {
+	switch f {
+	case FormatDOT:
+		return d.DOT()
+	case FormatPlantUML:
+		return d.PlantUML()
+	default:
+		return d.Mermaid()
+	}
+}
+
+// Mermaid renders the diagram as a Mermaid erDiagram.
+func (d *Diagram) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, t := range d.Tables {
+		fmt.Fprintf(&b, "    %s {\n", entityID(t.Key()))
+		for _, c := range t.Columns {
+			fmt.Fprintf(&b, "        %s %s", mermaidType(c.Type), entityID(c.Name))
+			var keys []string
+			if c.PK {
+				keys = append(keys, "PK")
+			}
+			if c.FK {
+				keys = append(keys, "FK")
+			}
+			if len(keys) > 0 {
+				b.WriteString(" " + strings.Join(keys, ", "))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, e := range d.Edges {
+		// child side first: zero-or-many children, one parent
+		child := "}o"
+		if e.OneToOne {
+			child = "|o"
+		}
+		parent := "||"
+		if e.Optional {
+			parent = "o|"
+		}
+		fmt.Fprintf(&b, "    %s %s--%s %s : %q\n",
+			entityID(e.From), child, parent, entityID(e.To), strings.Join(e.FromColumns, ", "))
+	}
+	return b.String()
+}
+
+// DOT renders the diagram as a Graphviz digraph with one record-like
+// HTML table per entity and edges between the key columns.
+func (d *Diagram) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph erd {\n")
+	b.WriteString("    graph [rankdir=LR];\n")
+	b.WriteString("    node [shape=plaintext, fontname=\"Helvetica\"];\n")
+	b.WriteString("    edge [arrowhead=crow, arrowtail=tee, dir=both];\n\n")
+
+	for _, t := range d.Tables {
+		fmt.Fprintf(&b, "    %q [label=<\n", t.Key())
+		b.WriteString("        <table border=\"0\" cellborder=\"1\" cellspacing=\"0\">\n")
+		fmt.Fprintf(&b, "        <tr><td bgcolor=\"lightgrey\" colspan=\"2\"><b>%s</b></td></tr>\n", html.EscapeString(t.Key()))
+		for _, c := range t.Columns {
+			name := html.EscapeString(c.Name)
+			if c.PK {
+				name = "<u>" + name + "</u>"
+			}
+			fmt.Fprintf(&b, "        <tr><td port=%q align=\"left\">%s%s</td><td align=\"left\">%s</td></tr>\n",
+				c.Name, keyMarker(c), name, html.EscapeString(c.Type))
+		}
+		b.WriteString("        </table>\n    >];\n")
+	}
+
+	if len(d.Edges) > 0 {
+		b.WriteString("\n")
+	}
+	for _, e := range d.Edges {
+		from, to := fmt.Sprintf("%q", e.From), fmt.Sprintf("%q", e.To)
+		if len(e.FromColumns) == 1 && len(e.ToColumns) == 1 {
+			from += fmt.Sprintf(":%q", e.FromColumns[0])
+			to += fmt.Sprintf(":%q", e.ToColumns[0])
+		}
+		style := ""
+		if e.Optional {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "    %s -> %s [label=%q%s];\n", from, to, e.Name, style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// PlantUML renders the diagram with PlantUML's entity notation. Mandatory
+// columns are starred.
+func (d *Diagram) PlantUML() string {
+	var b strings.Builder
+	b.WriteString("@startuml\n")
+	b.WriteString("hide circle\n")
+	b.WriteString("skinparam linetype ortho\n\n")
+
+	for _, t := range d.Tables {
+		fmt.Fprintf(&b, "entity %q as %s {\n", t.Key(), entityID(t.Key()))
+		var keys, rest []Column
+		for _, c := range t.Columns {
+			if c.PK {
+				keys = append(keys, c)
+			} else {
+				rest = append(rest, c)
+			}
+		}
+		for _, c := range keys {
+			b.WriteString("  " + plantColumn(c) + "\n")
+		}
+		if len(keys) > 0 && len(rest) > 0 {
+			b.WriteString("  --\n")
+		}
+		for _, c := range rest {
+			b.WriteString("  " + plantColumn(c) + "\n")
+		}
+		b.WriteString("}\n")
+	}
+
+	if len(d.Edges) > 0 {
+		b.WriteString("\n")
+	}
+	for _, e := range d.Edges {
+		child := "}o"
+		if e.OneToOne {
+			child = "|o"
+		}
+		parent := "||"
+		if e.Optional {
+			parent = "o|"
+		}
+		fmt.Fprintf(&b, "%s %s--%s %s : %s\n",
+			entityID(e.From), child, parent, entityID(e.To), strings.Join(e.FromColumns, ", "))
+	}
+	b.WriteString("@enduml\n")
+	return b.String()
+}
+
+func plantColumn(c Column) string {
+	s := ""
+	if !c.Nullable {
+		s = "* "
+	}
+	s += c.Name + " : " + c.Type
+	if c.PK {
+		s += " <<PK>>"
+	}
+	if c.FK {
+		s += " <<FK>>"
+	}
+	return s
+}
+
+func keyMarker(c Column) string {
+	switch {
+	case c.PK && c.FK:
+		return "PK FK "
+	case c.PK:
+		return "PK "
+	case c.FK:
+		return "FK "
+	}
+	return ""
+}
+
+// entityID turns a name into an identifier every format accepts unquoted.
+func entityID(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// mermaidType squeezes a type name into Mermaid's single-word attribute
+// type, e.g. "character varying(20)" becomes "character_varying(20)".
+func mermaidType(t string) string {
+	var b strings.Builder
+	for _, r := range t {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '-', r == '(', r == ')', r == '[', r == ']':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,152 @@
+// Package fuzzy implements subsequence matching with scoring, for finders
+// where typing a few characters of a name should bring it to the top.
+package fuzzy
+
+import (
+	"math"
+	"sort"
+	"unicode"
+)
+
+// Match is a successful match of a pattern against a text.
+type Match struct {
+	Index     int   // position of the text in the ranked slice
+	Score     int   // higher is better
+	Positions []int // rune offsets of the matched characters in the text
+}
+
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 24 // match at the start of a word
+	bonusConsecutive = 12 // match right after the previous one
+	bonusFirst       = 8  // match on the very first character
+	penaltyGap       = 1  // per skipped character between matches
+)
+
+// MatchText reports whether every rune of pattern appears in text, in
+// order and ignoring case, and scores the best such alignment. Spaces in
+// the pattern are ignored.
+func MatchText(pattern, text string) (Match, bool) {
+	var pat []rune
+	for _, r := range pattern {
+		if r != ' ' {
+			pat = append(pat, unicode.ToLower(r))
+		}
+	}
+	if len(pat) == 0 {
+		return Match{}, true
+	}
+	runes := []rune(text)
+	if len(pat) > len(runes) {
+		return Match{}, false
+	}
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// cheap subsequence check first; most texts do not match at all
+	k := 0
+	for _, r := range lower {
+		if k < len(pat) && r == pat[k] {
+			k++
+		}
+	}
+	if k < len(pat) {
+		return Match{}, false
+	}
+
+	// best[i][j] is the best score of pat[:i+1] with pat[i] matched at
+	// text[j], and from[i][j] where pat[i-1] was matched for it
+	const none = math.MinInt / 2
+	n := len(runes)
+	best := make([][]int, len(pat))
+	from := make([][]int, len(pat))
+	for i := range pat {
+		best[i] = make([]int, n)
+		from[i] = make([]int, n)
+		// running max of best[i-1][k] + k*penaltyGap over k < j-1, so a
+		// gap of j-k-1 costs (j-k-1)*penaltyGap
+		runMax, runArg := none, -1
+		for j := range n {
+			best[i][j] = none
+			if i > 0 && j >= 2 && best[i-1][j-2] != none {
+				if v := best[i-1][j-2] + (j-2)*penaltyGap; v > runMax {
+					runMax, runArg = v, j-2
+				}
+			}
+			if lower[j] != pat[i] {
+				continue
+			}
+			base := scoreMatch
+			if j == 0 {
+				base += bonusFirst
+			}
+			if isBoundary(runes, j) {
+				base += bonusBoundary
+			}
+			if i == 0 {
+				best[i][j] = base
+				continue
+			}
+			if j > 0 && best[i-1][j-1] != none {
+				best[i][j] = base + best[i-1][j-1] + bonusConsecutive
+				from[i][j] = j - 1
+			}
+			if runArg >= 0 {
+				if v := base + runMax - (j-1)*penaltyGap; v > best[i][j] {
+					best[i][j] = v
+					from[i][j] = runArg
+				}
+			}
+		}
+	}
+
+	last := len(pat) - 1
+	end := -1
+	for j := range n {
+		if best[last][j] != none && (end < 0 || best[last][j] > best[last][end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return Match{}, false
+	}
+	positions := make([]int, len(pat))
+	for i, j := last, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	// among equal matches, prefer shorter texts
+	return Match{Score: best[last][end] - n/4, Positions: positions}, true
+}
+
+// isBoundary reports whether the rune at i starts a word: the first rune,
+// one after a separator, or an upper-case letter after a lower-case one.
+func isBoundary(text []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, cur := text[i-1], text[i]
+	switch prev {
+	case '.', '_', '-', ' ', '/', '(':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// Rank matches pattern against every text and returns the matches best
+// first. Ties keep the original order.
+func Rank(pattern string, texts []string) []Match {
+	var matches []Match
+	for i, t := range texts {
+		if m, ok := MatchText(pattern, t); ok {
+			m.Index = i
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].Score > matches[b].Score
+	})
+	return matches
+}
@@ -0,0 +1,263 @@
+// Package history records executed queries in an append-only file so
+// they can be searched and run again in later sessions. Each line is
+// sealed with the vault, so queries and their literals are not readable
+// on disk when encryption is enabled.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joacominatel/minadb/internal/vault"
+)
+
+// MaxEntries is how many entries are kept; older ones are dropped as new
+// ones are added, and from the file once it holds a tenth more.
+const MaxEntries = 5000
+
+// Entry is one executed query.
+type Entry struct {
+	Query      string        `json:"query"`
+	Connection string        `json:"connection"` // user@host:port/db, never the password
+	Database   string        `json:"database"`
+	At         time.Time     `json:"at"`
+	Duration   time.Duration `json:"duration"`
+	Rows       int           `json:"rows"`
+	Error      string        `json:"error,omitempty"`
+	Params     []Param       `json:"params,omitempty"` // values bound to the query's parameters
+}
+
+// Param is the value a query parameter was bound to.
+type Param struct {
+	Name  string `json:"name"`           // as labelled in the form: $1 or :name
+	Type  string `json:"type,omitempty"` // "" when the server inferred it
+	Value string `json:"value"`
+}
+
+// OK reports whether the query succeeded.
+func (e Entry) OK() bool {
+	return e.Error == ""
+}
+
+// Store is the query history of all connections. It is safe for
+// concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	vault   *vault.Vault
+	entries []Entry  // oldest first
+	sealed  []string // lines the vault has no key for, kept as they are
+	lines   int      // lines in the file, which keeps dropped entries a while
+}
+
+// Open reads the history file at path, opening its lines with v. A
+// missing file is an empty history; lines that cannot be parsed are
+// skipped, and lines sealed with a key the vault does not have are kept
+// in the file but not read. Lines written in plain text before encryption was enabled are
+// read and the file is rewritten sealed.
+func Open(path string, v *vault.Vault) (*Store, error) {
+	s := &Store{path: path, vault: v}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	plain := false
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		s.lines++
+		data, err := v.OpenLine(line)
+		if err != nil {
+			s.sealed = append(s.sealed, line)
+			continue
+		}
+		var e Entry
+		if json.Unmarshal(data, &e) == nil && e.Query != "" {
+			s.entries = append(s.entries, e)
+			plain = plain || strings.HasPrefix(line, "{")
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(s.entries) > MaxEntries || (plain && v.Encrypted()) {
+		if len(s.entries) > MaxEntries {
+			s.entries = s.entries[len(s.entries)-MaxEntries:]
+		}
+		if err := s.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add records an entry and appends it to the file.
+func (s *Store) Add(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	line, err := s.vault.SealLine(data)
+	if err != nil {
+		return err
+	}
+	s.entries = append(s.entries, e)
+	if len(s.entries) > MaxEntries {
+		s.entries = s.entries[len(s.entries)-MaxEntries:]
+	}
+	if s.lines-len(s.sealed) >= MaxEntries+MaxEntries/10 {
+		return s.rewrite()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	s.lines++
+	return f.Close()
+}
+
+// Entries returns the history, newest first.
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Entry, len(s.entries))
+	for i, e := range s.entries {
+		out[len(out)-1-i] = e
+	}
+	return out
+}
+
+// Unreadable returns how many lines of the file were sealed with a key
+// the vault does not have.
+func (s *Store) Unreadable() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sealed)
+}
+
+// Rekey rewrites the file sealed with v, after a key rotation. Lines
+// that could not be read are written back unchanged.
+func (s *Store) Rekey(v *vault.Vault) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vault = v
+	if len(s.entries) == 0 {
+		return nil
+	}
+	return s.rewrite()
+}
+
+// rewrite replaces the file with the entries in memory, after the lines
+// that could not be read.
+func (s *Store) rewrite() error {
+	var b strings.Builder
+	for _, line := range s.sealed {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	for _, e := range s.entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		line, err := s.vault.SealLine(data)
+		if err != nil {
+			return err
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.lines = len(s.sealed) + len(s.entries)
+	return nil
+}
+
+// Filter returns the entries, newest first, whose query contains text,
+// ignoring case, and that ran on the given connection. Empty text or
+// connection matches everything. With unique set, only the newest run of
+// each distinct query is kept.
+func Filter(entries []Entry, text, connection string, unique bool) []Entry {
+	text = strings.ToLower(text)
+	seen := make(map[string]bool)
+	var out []Entry
+	for _, e := range entries {
+		if connection != "" && e.Connection != connection {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(e.Query), text) {
+			continue
+		}
+		if unique {
+			if seen[e.Query] {
+				continue
+			}
+			seen[e.Query] = true
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// Connections returns the distinct connections in entries, in order of
+// first appearance.
+func Connections(entries []Entry) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, e := range entries {
+		if e.Connection != "" && !seen[e.Connection] {
+			seen[e.Connection] = true
+			out = append(out, e.Connection)
+		}
+	}
+	return out
+}
+
+// LastParams returns the newest value given to each parameter in
+// entries, which are newest first, keyed by its name.
+func LastParams(entries []Entry) map[string]Param {
+	out := make(map[string]Param)
+	for _, e := range entries {
+		for _, p := range e.Params {
+			if _, ok := out[p.Name]; !ok {
+				out[p.Name] = p
+			}
+		}
+	}
+	return out
+}
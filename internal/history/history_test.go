@@ -0,0 +1,49 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joacominatel/minadb/internal/config"
+	"github.com/joacominatel/minadb/internal/vault"
+)
+
+func TestRekeyKeepsUnreadableLines(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	other, err := vault.Load(config.Encryption{KeySource: "passphrase"}, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lost, _ := other.SealLine([]byte(`{"query":"select 'lost'"}`))
+	t.Setenv("HOME", t.TempDir())
+	v, err := vault.Load(config.Encryption{KeySource: "passphrase"}, "mine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine, _ := v.SealLine([]byte(`{"query":"select 1"}`))
+	if err := os.WriteFile(path, []byte(lost+"\n"+mine+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Entries()); n != 1 {
+		t.Errorf("read %d entries, want 1", n)
+	}
+	if n := s.Unreadable(); n != 1 {
+		t.Errorf("Unreadable() = %d, want 1", n)
+	}
+	if err := s.Rekey(v); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), lost+"\n") {
+		t.Error("Rekey dropped a line it could not read")
+	}
+}
@@ -0,0 +1,97 @@
+package queries
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// placeholderRe matches ${1}, ${1:default}, ${name} and ${name:default}.
+var placeholderRe = regexp.MustCompile(`\\?\$\{([0-9]+|[A-Za-z_][A-Za-z0-9_]*)(?::([^}]*))?\}`)
+
+// Field is a placeholder of an expanded body: the byte range of its text
+// and the key that links placeholders sharing one value.
+type Field struct {
+	Start, End int
+	Key        string // the number or name between the braces
+}
+
+// Expand replaces the placeholders of body with their default text and
+// returns them in the order Tab visits them: numbered ones by number,
+// then named ones as they appear, then ${0}, which only marks where the
+// cursor ends up. A named placeholder without a default shows its name,
+// a numbered one shows nothing. Placeholders with the same key mirror
+// each other and all take the first default given. \${ is a literal ${,
+// so \${name} in a body inserts a ${name} query parameter.
+func Expand(body string) (string, []Field) {
+	matches := placeholderRe.FindAllStringSubmatchIndex(body, -1)
+
+	defaults := make(map[string]string)
+	given := make(map[string]bool) // the default came from the body
+	for _, m := range matches {
+		if body[m[0]] == '\\' {
+			continue
+		}
+		key := body[m[2]:m[3]]
+		switch {
+		case given[key]:
+		case m[4] >= 0:
+			defaults[key] = body[m[4]:m[5]]
+			given[key] = true
+		case isNumber(key):
+			defaults[key] = ""
+		default:
+			defaults[key] = key
+		}
+	}
+
+	var b strings.Builder
+	var fields []Field
+	last := 0
+	for _, m := range matches {
+		b.WriteString(body[last:m[0]])
+		last = m[1]
+		if body[m[0]] == '\\' {
+			b.WriteString(body[m[0]+1 : m[1]])
+			continue
+		}
+		key := body[m[2]:m[3]]
+		start := b.Len()
+		b.WriteString(defaults[key])
+		fields = append(fields, Field{Start: start, End: b.Len(), Key: key})
+	}
+	b.WriteString(body[last:])
+
+	slices.SortStableFunc(fields, func(a, b Field) int {
+		return rank(a.Key) - rank(b.Key)
+	})
+	return b.String(), fields
+}
+
+// rank orders keys for Tab: numbers first by value, names after, 0 last.
+func rank(key string) int {
+	n, err := strconv.Atoi(key)
+	switch {
+	case err != nil:
+		return 1 << 30
+	case n == 0:
+		return 1<<30 + 1
+	}
+	return min(n, 1<<29)
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// HasPlaceholders reports whether body has anything for Expand to fill.
+func HasPlaceholders(body string) bool {
+	for _, m := range placeholderRe.FindAllStringIndex(body, -1) {
+		if body[m[0]] != '\\' {
+			return true
+		}
+	}
+	return false
+}
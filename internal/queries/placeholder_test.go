@@ -0,0 +1,87 @@
+package queries
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		body   string
+		text   string
+		fields []string // the text of each field, in Tab order
+		keys   []string
+	}{
+		{"SELECT 1", "SELECT 1", nil, nil},
+		{
+			"SELECT * FROM ${table} LIMIT ${1:100}",
+			"SELECT * FROM table LIMIT 100",
+			[]string{"100", "table"},
+			[]string{"1", "table"},
+		},
+		{
+			"SELECT ${2:b}, ${1:a}, ${0}",
+			"SELECT b, a, ",
+			[]string{"a", "b", ""},
+			[]string{"1", "2", "0"},
+		},
+		{
+			"SELECT ${col:id} FROM t ORDER BY ${col}",
+			"SELECT id FROM t ORDER BY id",
+			[]string{"id", "id"},
+			[]string{"col", "col"},
+		},
+		{
+			"SELECT ${1} + ${1:x}",
+			"SELECT x + x",
+			[]string{"x", "x"},
+			[]string{"1", "1"},
+		},
+		{
+			`SELECT * FROM t WHERE id = \${id}`,
+			"SELECT * FROM t WHERE id = ${id}",
+			nil,
+			nil,
+		},
+		{
+			"SELECT '${x:é}' || ${y}",
+			"SELECT 'é' || y",
+			[]string{"é", "y"},
+			[]string{"x", "y"},
+		},
+	}
+	for _, tt := range tests {
+		text, fields := Expand(tt.body)
+		if text != tt.text {
+			t.Errorf("Expand(%q) text = %q, want %q", tt.body, text, tt.text)
+			continue
+		}
+		var got, keys []string
+		for _, f := range fields {
+			got = append(got, text[f.Start:f.End])
+			keys = append(keys, f.Key)
+		}
+		if !slices.Equal(got, tt.fields) || !slices.Equal(keys, tt.keys) {
+			t.Errorf("Expand(%q) fields = %q %q, want %q %q", tt.body, got, keys, tt.fields, tt.keys)
+		}
+	}
+}
+
+func TestHasPlaceholders(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{"SELECT 1", false},
+		{"SELECT ${1}", true},
+		{"SELECT ${table}", true},
+		{"SELECT ${n:5}", true},
+		{`SELECT \${id}`, false},
+		{"SELECT $1, :id, ${", false},
+	}
+	for _, tt := range tests {
+		if got := HasPlaceholders(tt.body); got != tt.want {
+			t.Errorf("HasPlaceholders(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package queries is the library of saved queries: plain .sql files whose
+// leading comment lines name and describe them, kept in a directory a
+// team can share through git.
+//
+//	-- name: Blocking locks
+//	-- description: Sessions waiting on a lock and who holds it
+//	-- tags: locks, diagnostics
+//	SELECT ...
+//
+// The body may hold placeholders filled in when the query is inserted
+// into the editor: ${1}, ${1:default}, ${table} and ${name:default};
+// see Expand. A query parameter, prompted for when the query runs, is
+// written :name or $1 in a body, or \${name} to keep the ${ as typed.
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Query is one saved query.
+type Query struct {
+	Name        string
+	Description string
+	Tags        []string
+	Body        string
+	Path        string // file it was read from, relative to the library directory
+}
+
+// Load reads every .sql file under dir, including subdirectories, sorted
+// by name. A missing directory is an empty library.
+func Load(dir string) ([]Query, error) {
+	var out []Query
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir // .git and the like
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".sql") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		out = append(out, Parse(rel, string(data)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(out, func(a, b Query) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return out, nil
+}
+
+// Parse reads a saved query from the content of a file. The header is
+// the run of "-- name:", "-- description:" and "-- tags:" comment lines at
+// the top; any other line starts the body. Without a name the file name
+// is used.
+func Parse(path, content string) Query {
+	q := Query{Path: path}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	rest := content
+header:
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		key, value, _ := headerLine(line)
+		switch key {
+		case "name":
+			q.Name = value
+		case "description":
+			q.Description = value
+		case "tags":
+			q.Tags = ParseTags(value)
+		default:
+			break header
+		}
+		rest = next
+	}
+	q.Body = strings.TrimSpace(rest)
+	if q.Name == "" {
+		q.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return q
+}
+
+// headerLine splits a "-- key: value" line.
+func headerLine(line string) (key, value string, ok bool) {
+	text, ok := strings.CutPrefix(strings.TrimSpace(line), "--")
+	if !ok {
+		return "", "", false
+	}
+	key, value, ok = strings.Cut(text, ":")
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !ok || key == "" || strings.ContainsFunc(key, unicode.IsSpace) {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(value), true
+}
+
+// ParseTags splits a list of tags separated by commas or spaces. Tags
+// are lower case, without a leading #.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		t = strings.ToLower(strings.TrimPrefix(t, "#"))
+		if t != "" && !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// Format renders q as the content of its file.
+func Format(q Query) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- name: %s\n", oneLine(q.Name))
+	if q.Description != "" {
+		fmt.Fprintf(&b, "-- description: %s\n", oneLine(q.Description))
+	}
+	if len(q.Tags) > 0 {
+		fmt.Fprintf(&b, "-- tags: %s\n", strings.Join(q.Tags, ", "))
+	}
+	b.WriteString(strings.TrimSpace(q.Body))
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// Save writes q to q.Path in dir, or for a new query to a file named
+// after it, which must not exist yet. It returns the path relative to
+// dir.
+func Save(dir string, q Query) (string, error) {
+	if strings.TrimSpace(q.Name) == "" {
+		return "", errors.New("a saved query needs a name")
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	rel := q.Path
+	if rel == "" {
+		rel = Slug(q.Name) + ".sql"
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("%s already exists", rel)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(Format(q)); err != nil {
+		f.Close()
+		return "", err
+	}
+	return rel, f.Close()
+}
+
+// Slug turns a name into a file name: lower case words joined by dashes.
+func Slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "query"
+	}
+	return b.String()
+}
+
+// Filter returns the queries matching text, ignoring case: every word
+// must appear in the name, description or tags, and a word starting with
+// # must be one of the tags.
+func Filter(list []Query, text string) []Query {
+	words := strings.Fields(strings.ToLower(text))
+	var out []Query
+outer:
+	for _, q := range list {
+		haystack := strings.ToLower(q.Name + "\n" + q.Description + "\n" + strings.Join(q.Tags, " "))
+		for _, w := range words {
+			if tag, ok := strings.CutPrefix(w, "#"); ok {
+				if tag != "" && !slices.Contains(q.Tags, tag) {
+					continue outer
+				}
+				continue
+			}
+			if !strings.Contains(haystack, w) {
+				continue outer
+			}
+		}
+		out = append(out, q)
+	}
+	return out
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,406 @@
+// Package schemadiff compares two catalog snapshots and generates the DDL
+// that brings a target database in line with a source.
+package schemadiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/ddl"
+)
+
+// ChangeKind says how an object differs between source and target.
+type ChangeKind int
+
+const (
+	Added    ChangeKind = iota // only in source, must be created in target
+	Removed                    // only in target, must be dropped
+	Modified                   // in both, with different definitions
+)
+
+// Symbol returns the diff marker for the change.
+func (k ChangeKind) Symbol() string {
+	switch k {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// ObjectKind identifies the type of a changed object.
+type ObjectKind int
+
+const (
+	ObjectSchema ObjectKind = iota
+	ObjectTable
+	ObjectColumn
+	ObjectConstraint
+	ObjectIndex
+	ObjectView
+	ObjectFunction
+)
+
+func (k ObjectKind) String() string {
+	switch k {
+	case ObjectSchema:
+		return "schema"
+	case ObjectTable:
+		return "table"
+	case ObjectColumn:
+		return "column"
+	case ObjectConstraint:
+		return "constraint"
+	case ObjectIndex:
+		return "index"
+	case ObjectView:
+		return "view"
+	case ObjectFunction:
+		return "function"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a single difference between source and target.
+type Change struct {
+	Kind   ChangeKind
+	Object ObjectKind
+	Schema string
+	Table  string // owning table for columns, constraints and indexes
+	Name   string
+
+	// Source and Target are the object definitions on each side, empty
+	// when the object does not exist there.
+	Source string
+	Target string
+
+	// originals, kept for generating DDL
+	srcColumn, tgtColumn         database.Column
+	srcConstraint, tgtConstraint database.Constraint
+	srcIndex, tgtIndex           database.Index
+	srcTable, tgtTable           database.CatalogTable
+	srcView, tgtView             database.View
+	srcFunc, tgtFunc             database.Function
+}
+
+// Path returns the qualified object name, e.g. public.users.email.
+func (c Change) Path() string {
+	parts := []string{c.Schema}
+	if c.Table != "" {
+		parts = append(parts, c.Table)
+	}
+	if c.Object != ObjectSchema {
+		parts = append(parts, c.Name)
+	}
+	return strings.Join(parts, ".")
+}
+
+// owner is the top-level object a change belongs to, for grouping.
+func (c Change) owner() string {
+	if c.Table != "" {
+		return c.Table
+	}
+	if c.Object == ObjectSchema {
+		return ""
+	}
+	return c.Name
+}
+
+// Summary returns a one-line description for lists.
+func (c Change) Summary() string {
+	s := fmt.Sprintf("%s %-10s %s", c.Kind.Symbol(), c.Object, c.Path())
+	if c.Kind == Modified && c.Object == ObjectColumn {
+		prefix := database.QuoteIdent(c.Name) + " "
+		s += "  " + strings.TrimPrefix(c.Target, prefix) + " → " + strings.TrimPrefix(c.Source, prefix)
+	}
+	return s
+}
+
+// Diff is the ordered list of changes between two catalogs.
+type Diff struct {
+	Source  string // database names, for display
+	Target  string
+	Changes []Change
+}
+
+// Empty reports whether the schemas are identical.
+func (d *Diff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Compare finds every difference between source and target. Changes are
+// grouped by schema and by table, so a table's columns, constraints and
+// indexes are listed together.
+func Compare(source, target *database.Catalog) *Diff {
+	d := &Diff{Source: source.Database, Target: target.Database}
+
+	diffNames(source.Schemas, target.Schemas, func(kind ChangeKind, name string) {
+		d.Changes = append(d.Changes, Change{Kind: kind, Object: ObjectSchema, Schema: name, Name: name})
+	})
+
+	d.compareTables(source.Tables, target.Tables)
+	d.compareViews(source.Views, target.Views)
+	d.compareFunctions(source.Functions, target.Functions)
+
+	sort.SliceStable(d.Changes, func(i, j int) bool {
+		a, b := d.Changes[i], d.Changes[j]
+		if a.Schema != b.Schema {
+			return a.Schema < b.Schema
+		}
+		if a.owner() != b.owner() {
+			return a.owner() < b.owner()
+		}
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		return a.Name < b.Name
+	})
+	return d
+}
+
+func diffNames(source, target []string, fn func(ChangeKind, string)) {
+	inTarget := make(map[string]bool, len(target))
+	for _, t := range target {
+		inTarget[t] = true
+	}
+	inSource := make(map[string]bool, len(source))
+	for _, s := range source {
+		inSource[s] = true
+		if !inTarget[s] {
+			fn(Added, s)
+		}
+	}
+	for _, t := range target {
+		if !inSource[t] {
+			fn(Removed, t)
+		}
+	}
+}
+
+func (d *Diff) compareTables(source, target []database.CatalogTable) {
+	tgt := make(map[string]database.CatalogTable, len(target))
+	for _, t := range target {
+		tgt[t.Schema+"."+t.Name] = t
+	}
+	seen := make(map[string]bool, len(source))
+
+	for _, s := range source {
+		key := s.Schema + "." + s.Name
+		seen[key] = true
+		t, ok := tgt[key]
+		if !ok {
+			d.Changes = append(d.Changes, Change{
+				Kind: Added, Object: ObjectTable, Schema: s.Schema, Name: s.Name,
+				Source: ddl.TableDefinition(s), srcTable: s,
+			})
+			continue
+		}
+		// a partition's columns follow its parent's
+		if !s.IsPartition() || !t.IsPartition() {
+			d.compareColumns(s, t)
+		}
+		d.compareConstraints(s, t)
+		d.compareIndexes(s, t)
+	}
+
+	for _, t := range target {
+		if !seen[t.Schema+"."+t.Name] {
+			d.Changes = append(d.Changes, Change{
+				Kind: Removed, Object: ObjectTable, Schema: t.Schema, Name: t.Name,
+				Target: ddl.TableDefinition(t), tgtTable: t,
+			})
+		}
+	}
+}
+
+func (d *Diff) compareColumns(source, target database.CatalogTable) {
+	tgt := make(map[string]database.Column, len(target.Columns))
+	for _, c := range target.Columns {
+		tgt[c.Name] = c
+	}
+	seen := make(map[string]bool)
+
+	for _, s := range source.Columns {
+		seen[s.Name] = true
+		ch := Change{
+			Object: ObjectColumn, Schema: source.Schema, Table: source.Name, Name: s.Name,
+			Source: ddl.ColumnDefinition(s), srcColumn: s,
+		}
+		t, ok := tgt[s.Name]
+		if !ok {
+			ch.Kind = Added
+			d.Changes = append(d.Changes, ch)
+			continue
+		}
+		ch.Target = ddl.ColumnDefinition(t)
+		ch.tgtColumn = t
+		if ch.Source != ch.Target {
+			ch.Kind = Modified
+			d.Changes = append(d.Changes, ch)
+		}
+	}
+
+	for _, t := range target.Columns {
+		if !seen[t.Name] {
+			d.Changes = append(d.Changes, Change{
+				Kind: Removed, Object: ObjectColumn, Schema: target.Schema, Table: target.Name, Name: t.Name,
+				Target: ddl.ColumnDefinition(t), tgtColumn: t,
+			})
+		}
+	}
+}
+
+func (d *Diff) compareConstraints(source, target database.CatalogTable) {
+	tgt := make(map[string]database.Constraint, len(target.Constraints))
+	for _, c := range target.Constraints {
+		tgt[c.Name] = c
+	}
+	seen := make(map[string]bool)
+
+	for _, s := range source.Constraints {
+		seen[s.Name] = true
+		ch := Change{
+			Object: ObjectConstraint, Schema: source.Schema, Table: source.Name, Name: s.Name,
+			Source: s.Definition, srcConstraint: s,
+		}
+		t, ok := tgt[s.Name]
+		switch {
+		case !ok:
+			ch.Kind = Added
+		case t.Definition != s.Definition:
+			ch.Kind = Modified
+			ch.Target = t.Definition
+			ch.tgtConstraint = t
+		default:
+			continue
+		}
+		d.Changes = append(d.Changes, ch)
+	}
+
+	for _, t := range target.Constraints {
+		if !seen[t.Name] {
+			d.Changes = append(d.Changes, Change{
+				Kind: Removed, Object: ObjectConstraint, Schema: target.Schema, Table: target.Name, Name: t.Name,
+				Target: t.Definition, tgtConstraint: t,
+			})
+		}
+	}
+}
+
+func (d *Diff) compareIndexes(source, target database.CatalogTable) {
+	tgt := make(map[string]database.Index, len(target.Indexes))
+	for _, i := range target.Indexes {
+		tgt[i.Name] = i
+	}
+	seen := make(map[string]bool)
+
+	for _, s := range source.Indexes {
+		seen[s.Name] = true
+		ch := Change{
+			Object: ObjectIndex, Schema: source.Schema, Table: source.Name, Name: s.Name,
+			Source: s.Definition, srcIndex: s,
+		}
+		t, ok := tgt[s.Name]
+		switch {
+		case !ok:
+			ch.Kind = Added
+		case t.Definition != s.Definition:
+			ch.Kind = Modified
+			ch.Target = t.Definition
+			ch.tgtIndex = t
+		default:
+			continue
+		}
+		d.Changes = append(d.Changes, ch)
+	}
+
+	for _, t := range target.Indexes {
+		if !seen[t.Name] {
+			d.Changes = append(d.Changes, Change{
+				Kind: Removed, Object: ObjectIndex, Schema: target.Schema, Table: target.Name, Name: t.Name,
+				Target: t.Definition, tgtIndex: t,
+			})
+		}
+	}
+}
+
+func (d *Diff) compareViews(source, target []database.View) {
+	tgt := make(map[string]database.View, len(target))
+	for _, v := range target {
+		tgt[v.Schema+"."+v.Name] = v
+	}
+	seen := make(map[string]bool)
+
+	for _, s := range source {
+		key := s.Schema + "." + s.Name
+		seen[key] = true
+		ch := Change{
+			Object: ObjectView, Schema: s.Schema, Name: s.Name,
+			Source: ddl.CreateView(s), srcView: s,
+		}
+		t, ok := tgt[key]
+		switch {
+		case !ok:
+			ch.Kind = Added
+		case t.Definition != s.Definition || t.Materialized != s.Materialized:
+			ch.Kind = Modified
+			ch.Target = ddl.CreateView(t)
+			ch.tgtView = t
+		default:
+			continue
+		}
+		d.Changes = append(d.Changes, ch)
+	}
+
+	for _, t := range target {
+		if !seen[t.Schema+"."+t.Name] {
+			d.Changes = append(d.Changes, Change{
+				Kind: Removed, Object: ObjectView, Schema: t.Schema, Name: t.Name,
+				Target: ddl.CreateView(t), tgtView: t,
+			})
+		}
+	}
+}
+
+func (d *Diff) compareFunctions(source, target []database.Function) {
+	tgt := make(map[string]database.Function, len(target))
+	for _, f := range target {
+		tgt[f.Schema+"."+f.Signature()] = f
+	}
+	seen := make(map[string]bool)
+
+	for _, s := range source {
+		key := s.Schema + "." + s.Signature()
+		seen[key] = true
+		ch := Change{
+			Object: ObjectFunction, Schema: s.Schema, Name: s.Signature(),
+			Source: s.Definition, srcFunc: s,
+		}
+		t, ok := tgt[key]
+		switch {
+		case !ok:
+			ch.Kind = Added
+		case t.Definition != s.Definition:
+			ch.Kind = Modified
+			ch.Target = t.Definition
+			ch.tgtFunc = t
+		default:
+			continue
+		}
+		d.Changes = append(d.Changes, ch)
+	}
+
+	for _, t := range target {
+		if !seen[t.Schema+"."+t.Signature()] {
+			d.Changes = append(d.Changes, Change{
+				Kind: Removed, Object: ObjectFunction, Schema: t.Schema, Name: t.Signature(),
+				Target: t.Definition, tgtFunc: t,
+			})
+		}
+	}
+}
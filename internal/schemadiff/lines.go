@@ -0,0 +1,72 @@
+package schemadiff
+
+import "strings"
+
+// LineOp marks a line in a definition diff.
+type LineOp int
+
+const (
+	LineSame LineOp = iota
+	LineAdded
+	LineRemoved
+)
+
+// Line is one line of a definition diff.
+type Line struct {
+	Op   LineOp
+	Text string
+}
+
+// DiffLines compares two definitions line by line using the longest
+// common subsequence. Lines only in target are Removed, lines only in
+// source are Added.
+func DiffLines(target, source string) []Line {
+	a := splitLines(target)
+	b := splitLines(source)
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []Line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, Line{Op: LineSame, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Line{Op: LineRemoved, Text: a[i]})
+			i++
+		default:
+			out = append(out, Line{Op: LineAdded, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, Line{Op: LineRemoved, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, Line{Op: LineAdded, Text: b[j]})
+	}
+	return out
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
@@ -0,0 +1,204 @@
+package schemadiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/ddl"
+)
+
+// MigrationScript returns the DDL that turns the target schema into the
+// source schema. Statements are ordered so dependencies exist before they
+// are used and are dropped after their dependents: views and foreign keys
+// go first, tables are created before constraints reference them, and
+// functions precede the views that may call them. Objects in schemas only
+// the target has are left alone, their drops listed commented out.
+func (d *Diff) MigrationScript() string {
+	var (
+		createSchemas []string
+		dropViews     []string
+		dropFuncs     []string
+		dropFKs       []string
+		dropOthers    []string
+		dropTables    []string
+		dropColumns   []string
+		sequences     []string
+		createTables  []string
+		alterColumns  []string
+		addOthers     []string
+		addIndexes    []string
+		addFKs        []string
+		createFuncs   []string
+		createViews   []string
+		dropSchemas   []string
+	)
+
+	seqSeen := make(map[string]bool)
+	needSequence := func(cols ...database.Column) {
+		for _, c := range cols {
+			if seq, ok := ddl.SequenceReference(c.Default); ok && !seqSeen[seq] {
+				seqSeen[seq] = true
+				sequences = append(sequences, "CREATE SEQUENCE IF NOT EXISTS "+seq+";")
+			}
+		}
+	}
+
+	targetOnly := make(map[string]bool)
+	dropped := make(map[string]bool)
+	for _, c := range d.Changes {
+		switch {
+		case c.Kind != Removed:
+		case c.Object == ObjectSchema:
+			targetOnly[c.Name] = true
+		case c.Object == ObjectTable:
+			dropped[c.Schema+"."+c.Name] = true
+		}
+	}
+	// drop adds a drop statement to list, or comments it out when the
+	// object's schema is not dropped
+	drop := func(list *[]string, schema, stmt string) {
+		if targetOnly[schema] {
+			dropSchemas = append(dropSchemas, "-- "+stmt)
+			return
+		}
+		*list = append(*list, stmt)
+	}
+
+	var added []database.CatalogTable
+	for _, c := range d.Changes {
+		switch c.Object {
+		case ObjectSchema:
+			if c.Kind == Added {
+				createSchemas = append(createSchemas, ddl.CreateSchema(c.Name))
+			} else {
+				// never drop a whole schema implicitly
+				dropSchemas = append(dropSchemas, "-- DROP SCHEMA "+database.QuoteIdent(c.Name)+";")
+			}
+
+		case ObjectTable:
+			if c.Kind == Added {
+				added = append(added, c.srcTable)
+				continue
+			}
+			// dropping a partitioned table drops its partitions
+			t := c.tgtTable
+			if t.IsPartition() && dropped[t.ParentSchema+"."+t.ParentName] {
+				continue
+			}
+			drop(&dropTables, c.Schema, ddl.DropTable(c.Schema, c.Name))
+
+		case ObjectColumn:
+			switch c.Kind {
+			case Added:
+				needSequence(c.srcColumn)
+				alterColumns = append(alterColumns, ddl.AddColumn(c.Schema, c.Table, c.srcColumn))
+			case Removed:
+				dropColumns = append(dropColumns, ddl.DropColumn(c.Schema, c.Table, c.Name))
+			case Modified:
+				needSequence(c.srcColumn)
+				alterColumns = append(alterColumns, ddl.AlterColumn(c.Schema, c.Table, c.tgtColumn, c.srcColumn)...)
+			}
+
+		case ObjectConstraint:
+			fk := c.srcConstraint.Kind == database.ConstraintForeignKey ||
+				c.tgtConstraint.Kind == database.ConstraintForeignKey
+			if c.Kind != Added {
+				stmt := ddl.DropConstraint(c.Schema, c.Table, c.Name)
+				if fk {
+					dropFKs = append(dropFKs, stmt)
+				} else {
+					dropOthers = append(dropOthers, stmt)
+				}
+			}
+			if c.Kind != Removed {
+				stmt := ddl.AddConstraint(c.Schema, c.Table, c.srcConstraint)
+				if fk {
+					addFKs = append(addFKs, stmt)
+				} else {
+					addOthers = append(addOthers, stmt)
+				}
+			}
+
+		case ObjectIndex:
+			if c.Kind != Added {
+				dropOthers = append(dropOthers, ddl.DropIndex(c.Schema, c.Name))
+			}
+			if c.Kind != Removed {
+				addIndexes = append(addIndexes, ddl.CreateIndex(c.srcIndex))
+			}
+
+		case ObjectView:
+			if c.Kind != Added {
+				drop(&dropViews, c.Schema, ddl.DropView(c.tgtView))
+			}
+			if c.Kind != Removed {
+				createViews = append(createViews, ddl.CreateView(c.srcView))
+			}
+
+		case ObjectFunction:
+			switch c.Kind {
+			case Removed:
+				drop(&dropFuncs, c.Schema, ddl.DropFunction(c.tgtFunc))
+			default:
+				// CREATE OR REPLACE handles both new and changed bodies
+				createFuncs = append(createFuncs, ddl.CreateFunction(c.srcFunc))
+			}
+		}
+	}
+
+	// partitions are created after their parents
+	for _, i := range ddl.TableOrder(added) {
+		t := added[i]
+		needSequence(t.Columns...)
+		createTables = append(createTables, ddl.CreateTable(t))
+		for _, idx := range t.Indexes {
+			addIndexes = append(addIndexes, ddl.CreateIndex(idx))
+		}
+		for _, con := range t.Constraints {
+			if con.Kind == database.ConstraintForeignKey {
+				addFKs = append(addFKs, ddl.AddConstraint(t.Schema, t.Name, con))
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Migration from %s to match %s\n", d.Target, d.Source)
+	b.WriteString("-- Generated by minadb. Review before running: drops are destructive.\n\n")
+	b.WriteString("BEGIN;\n")
+
+	sections := []struct {
+		title string
+		stmts []string
+	}{
+		{"Schemas", createSchemas},
+		{"Drop dependent views", dropViews},
+		{"Drop removed functions", dropFuncs},
+		{"Drop foreign keys", dropFKs},
+		{"Drop constraints and indexes", dropOthers},
+		{"Drop removed tables", dropTables},
+		{"Drop removed columns", dropColumns},
+		{"Sequences used by column defaults", sequences},
+		{"Create tables", createTables},
+		{"Add and alter columns", alterColumns},
+		{"Constraints", addOthers},
+		{"Indexes", addIndexes},
+		{"Foreign keys", addFKs},
+		{"Functions", createFuncs},
+		{"Views", createViews},
+		{"Schemas only in target (not dropped automatically)", dropSchemas},
+	}
+	for _, s := range sections {
+		if len(s.stmts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n-- %s\n", s.title)
+		for _, stmt := range s.stmts {
+			b.WriteString(stmt)
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\nCOMMIT;\n")
+	return b.String()
+}
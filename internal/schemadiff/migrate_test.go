@@ -0,0 +1,146 @@
+package schemadiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+func col(name, typ string) database.Column {
+	return database.Column{Name: name, DataType: typ, IsNullable: true}
+}
+
+func table(schema, name string, cols ...database.Column) database.CatalogTable {
+	return database.CatalogTable{Schema: schema, Name: name, Columns: cols}
+}
+
+func fk(name, column, refTable string) database.Constraint {
+	return database.Constraint{
+		Name:       name,
+		Kind:       database.ConstraintForeignKey,
+		Definition: "FOREIGN KEY (" + column + ") REFERENCES public." + refTable + "(id)",
+		Columns:    []string{column},
+		RefSchema:  "public",
+		RefTable:   refTable,
+		RefColumns: []string{"id"},
+	}
+}
+
+func TestMigrationScriptOrder(t *testing.T) {
+	users := table("public", "users", col("id", "integer"), col("name", "text"))
+	orders := table("public", "orders", col("id", "integer"), col("user_id", "integer"))
+	orders.Constraints = []database.Constraint{fk("orders_user_fk", "user_id", "users")}
+	events := table("public", "events", col("id", "integer"), col("at", "date"))
+	events.PartitionKey = "RANGE (at)"
+	events2024 := table("public", "events_2024")
+	events2024.ParentSchema, events2024.ParentName = "public", "events"
+	events2024.PartitionBound = "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')"
+	oldView := database.View{Schema: "public", Name: "old_names", Definition: "SELECT name FROM users"}
+	newView := database.View{Schema: "public", Name: "user_orders", Definition: "SELECT * FROM orders"}
+	legacy := table("legacy", "stuff", col("id", "integer"))
+	gone := table("public", "gone", col("id", "integer"))
+	gonePart := table("public", "gone_1")
+	gonePart.ParentSchema, gonePart.ParentName = "public", "gone"
+
+	source := &database.Catalog{
+		Database: "src",
+		Schemas:  []string{"public"},
+		// partitions listed before their parent
+		Tables: []database.CatalogTable{events2024, orders, events, users},
+		Views:  []database.View{newView},
+	}
+	target := &database.Catalog{
+		Database: "tgt",
+		Schemas:  []string{"legacy", "public"},
+		Tables:   []database.CatalogTable{gone, gonePart, legacy, table("public", "users", col("id", "integer"))},
+		Views:    []database.View{oldView},
+	}
+	script := Compare(source, target).MigrationScript()
+
+	// each fragment must come after the one before it
+	order := []string{
+		`DROP VIEW`,
+		`DROP TABLE public.gone`,
+		`CREATE TABLE public.events (`,
+		`CREATE TABLE public.events_2024 PARTITION OF public.events`,
+		`CREATE TABLE public.orders`,
+		`ALTER TABLE public.users ADD COLUMN name`,
+		`ADD CONSTRAINT orders_user_fk`,
+		`CREATE VIEW public.user_orders`,
+		`-- DROP TABLE legacy.stuff`,
+	}
+	at := 0
+	for _, frag := range order {
+		i := strings.Index(script[at:], frag)
+		if i < 0 {
+			t.Fatalf("%q missing or out of order in:\n%s", frag, script)
+		}
+		at += i + len(frag)
+	}
+
+	for _, frag := range []string{
+		"DROP TABLE public.gone_1", // dropped with its parent
+		"\nDROP TABLE legacy",      // only commented out
+		"\nDROP SCHEMA",
+	} {
+		if strings.Contains(script, frag) {
+			t.Errorf("script contains %q:\n%s", frag, script)
+		}
+	}
+}
+
+func TestMigrationScriptColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   database.Column
+		to     database.Column
+		want   []string
+		absent []string
+	}{
+		{
+			name: "type change",
+			from: col("n", "integer"),
+			to:   col("n", "bigint"),
+			want: []string{"ALTER COLUMN n TYPE bigint"},
+		},
+		{
+			name: "not null",
+			from: col("n", "integer"),
+			to:   database.Column{Name: "n", DataType: "integer"},
+			want: []string{"ALTER COLUMN n SET NOT NULL"},
+		},
+		{
+			name: "serial default needs its sequence",
+			from: col("id", "integer"),
+			to:   database.Column{Name: "id", DataType: "integer", IsNullable: true, Default: "nextval('t_id_seq'::regclass)"},
+			want: []string{"CREATE SEQUENCE IF NOT EXISTS", "SET DEFAULT nextval"},
+		},
+		{
+			name:   "generated expression",
+			from:   database.Column{Name: "g", DataType: "integer", IsNullable: true, Default: "(a + 1)", Generated: "s"},
+			to:     database.Column{Name: "g", DataType: "integer", IsNullable: true, Default: "(a + 2)", Generated: "s"},
+			want:   []string{"SET EXPRESSION AS ((a + 2))"},
+			absent: []string{"SET DEFAULT"},
+		},
+	}
+	for _, tt := range tests {
+		source := &database.Catalog{Schemas: []string{"public"}, Tables: []database.CatalogTable{table("public", "t", tt.to)}}
+		target := &database.Catalog{Schemas: []string{"public"}, Tables: []database.CatalogTable{table("public", "t", tt.from)}}
+		script := Compare(source, target).MigrationScript()
+		at := 0
+		for _, frag := range tt.want {
+			i := strings.Index(script[at:], frag)
+			if i < 0 {
+				t.Errorf("%s: %q missing or out of order in:\n%s", tt.name, frag, script)
+				break
+			}
+			at += i + len(frag)
+		}
+		for _, frag := range tt.absent {
+			if strings.Contains(script, frag) {
+				t.Errorf("%s: script contains %q:\n%s", tt.name, frag, script)
+			}
+		}
+	}
+}
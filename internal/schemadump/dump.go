@@ -0,0 +1,182 @@
+// Package schemadump renders a catalog snapshot as a schema-only SQL
+// script that recreates it on an empty database.
+package schemadump
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/ddl"
+)
+
+// Script returns the DDL for every object in the catalog, ordered so each
+// statement only refers to objects created before it. The output has no
+// timestamps and a stable order, so dumps of an unchanged schema are
+// byte-identical and diff cleanly under version control.
+func Script(cat *database.Catalog) string {
+	tables := make(map[string]bool, len(cat.Tables))
+	for _, t := range cat.Tables {
+		tables[t.Schema+"."+t.Name] = true
+	}
+
+	var (
+		schemas        []string
+		types          []string
+		sequences      []string
+		functions      []string
+		createTables   []string
+		ownedBy        []string
+		indexes        []string
+		foreignKeys    []string
+		tableFunctions []string
+		views          []string
+		grants         []string
+	)
+
+	for _, s := range cat.Schemas {
+		// public exists in every new database
+		if s != "public" {
+			schemas = append(schemas, ddl.CreateSchema(s))
+		}
+	}
+
+	typeKeys := make([]string, len(cat.Types))
+	for i, t := range cat.Types {
+		typeKeys[i] = t.Schema + "." + t.Name
+	}
+	for _, i := range dependencyOrder(typeKeys, func(i int) []string { return cat.Types[i].DependsOn }) {
+		types = append(types, ddl.CreateType(cat.Types[i]))
+	}
+
+	for _, s := range cat.Sequences {
+		sequences = append(sequences, ddl.CreateSequence(s))
+		if s.OwnedBy != "" {
+			ownedBy = append(ownedBy, ddl.SequenceOwnedBy(s))
+		}
+	}
+
+	for _, f := range cat.Functions {
+		// a function taking or returning a table's row type needs the table
+		// first; any other function goes before tables so column defaults
+		// and check constraints can call it
+		needsTable := false
+		for _, dep := range f.DependsOn {
+			if tables[dep] {
+				needsTable = true
+				break
+			}
+		}
+		if needsTable {
+			tableFunctions = append(tableFunctions, ddl.CreateFunction(f))
+		} else {
+			functions = append(functions, ddl.CreateFunction(f))
+		}
+	}
+
+	for _, i := range ddl.TableOrder(cat.Tables) {
+		t := cat.Tables[i]
+		createTables = append(createTables, ddl.CreateTable(t))
+		for _, idx := range t.Indexes {
+			indexes = append(indexes, ddl.CreateIndex(idx))
+		}
+		for _, con := range t.Constraints {
+			if con.Kind == database.ConstraintForeignKey {
+				foreignKeys = append(foreignKeys, ddl.AddConstraint(t.Schema, t.Name, con))
+			}
+		}
+	}
+
+	viewKeys := make([]string, len(cat.Views))
+	for i, v := range cat.Views {
+		viewKeys[i] = v.Schema + "." + v.Name
+	}
+	for _, i := range dependencyOrder(viewKeys, func(i int) []string { return cat.Views[i].DependsOn }) {
+		views = append(views, ddl.CreateView(cat.Views[i]))
+	}
+
+	for _, g := range cat.Grants {
+		grants = append(grants, ddl.Grant(g))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema dump of %s (%s)\n", cat.Database, strings.Join(cat.Schemas, ", "))
+	b.WriteString("-- Generated by minadb. Schema only, no data.\n\n")
+	// bodies may reference tables that are created further down
+	b.WriteString("SET check_function_bodies = false;\n")
+
+	sections := []struct {
+		title string
+		stmts []string
+	}{
+		{"Schemas", schemas},
+		{"Types", types},
+		{"Sequences", sequences},
+		{"Functions", functions},
+		{"Tables", createTables},
+		{"Sequence ownership", ownedBy},
+		{"Indexes", indexes},
+		{"Foreign keys", foreignKeys},
+		{"Functions using table row types", tableFunctions},
+		{"Views", views},
+		{"Grants", grants},
+	}
+	for _, s := range sections {
+		if len(s.stmts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n-- %s\n", s.title)
+		for _, stmt := range s.stmts {
+			b.WriteString("\n")
+			b.WriteString(stmt)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// dependencyOrder returns the indexes of keys sorted so that every item
+// follows the items it depends on, breaking ties by key. Dependencies on
+// keys outside the list are ignored, and cycles are broken arbitrarily.
+func dependencyOrder(keys []string, deps func(i int) []string) []int {
+	index := make(map[string]int, len(keys))
+	for i, k := range keys {
+		index[k] = i
+	}
+
+	byKey := make([]int, len(keys))
+	for i := range byKey {
+		byKey[i] = i
+	}
+	sort.SliceStable(byKey, func(a, b int) bool { return keys[byKey[a]] < keys[byKey[b]] })
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(keys))
+	order := make([]int, 0, len(keys))
+
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != unvisited {
+			return
+		}
+		state[i] = visiting
+		d := append([]string(nil), deps(i)...)
+		sort.Strings(d)
+		for _, dep := range d {
+			if j, ok := index[dep]; ok {
+				visit(j)
+			}
+		}
+		state[i] = done
+		order = append(order, i)
+	}
+	for _, i := range byKey {
+		visit(i)
+	}
+	return order
+}
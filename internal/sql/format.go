@@ -0,0 +1,634 @@
+package sql
+
+import "strings"
+
+// KeywordCase says how Format writes keywords.
+type KeywordCase int
+
+const (
+	UpperKeywords    KeywordCase = iota // SELECT
+	LowerKeywords                       // select
+	PreserveKeywords                    // as written
+)
+
+// FormatOptions controls the layout Format produces.
+type FormatOptions struct {
+	Indent        int // spaces per indentation level
+	KeywordCase   KeywordCase
+	LeadingCommas bool // put list commas at the start of lines
+}
+
+// DefaultFormatOptions are the options used when none are configured.
+var DefaultFormatOptions = FormatOptions{Indent: 4, KeywordCase: UpperKeywords}
+
+// Format lays out a script one clause per line. Lists of more than one
+// item get a line per item, subqueries and CTE bodies are indented inside
+// their parentheses, JOINs line up with FROM with their ON condition
+// indented below, AND and OR in conditions start new lines, and CASE
+// expressions get a line per branch. Comments are kept, and a comment
+// that had its own line still does. Statements are separated by a blank
+// line. The text of literals, identifiers and dollar-quoted bodies is
+// never changed.
+func Format(src string, opts FormatOptions) string {
+	if opts.Indent <= 0 {
+		opts.Indent = DefaultFormatOptions.Indent
+	}
+	f := &formatter{opts: opts, ownLine: map[int]bool{0: true}}
+	for _, t := range Lex(src) {
+		if t.Kind == Whitespace {
+			if strings.Contains(t.Text, "\n") {
+				f.ownLine[len(f.toks)] = true
+			}
+			continue
+		}
+		f.toks = append(f.toks, t)
+	}
+	f.run()
+	return f.String()
+}
+
+type frameKind int
+
+const (
+	queryFrame  frameKind = iota // statement or parenthesised subquery
+	inlineFrame                  // call arguments, value lists, expressions
+	blockFrame                   // CREATE TABLE column list
+)
+
+// frame is an open statement or parenthesis.
+type frame struct {
+	kind    frameKind
+	indent  int    // indentation of the line that opened the frame
+	level   int    // indentation of the frame's clauses
+	clause  string // current clause, lower-case
+	list    bool   // the clause puts one item per line
+	between bool   // inside BETWEEN, waiting for its AND
+	cases   []int  // indentation of the lines open CASE expressions start on
+}
+
+type formatter struct {
+	opts    FormatOptions
+	toks    []Token      // tokens without whitespace
+	ownLine map[int]bool // tokens that start a line in the source
+
+	stack []*frame
+	pos   int  // index of the token being written
+	start bool // the next token starts a new statement
+
+	out       strings.Builder
+	line      strings.Builder
+	indent    int
+	last      Token
+	lastUnary bool
+	mustBreak bool // a line comment ends the current line
+}
+
+func (f *formatter) top() *frame {
+	return f.stack[len(f.stack)-1]
+}
+
+func (f *formatter) run() {
+	f.stack = []*frame{{kind: queryFrame}}
+	for i := 0; i < len(f.toks); i++ {
+		t := f.toks[i]
+		f.pos = i
+		if t.Kind == Comment {
+			f.comment(i)
+			continue
+		}
+		if f.start {
+			f.blankLine()
+			f.start = false
+		}
+		if t.IsPunct(";") {
+			if f.mustBreak {
+				// after a line comment the semicolon gets a line of its own
+				f.newline(0)
+			}
+			f.write(t)
+			f.stack = []*frame{{kind: queryFrame}}
+			f.start = true
+			continue
+		}
+		if t.IsPunct("(") {
+			f.open(i)
+			continue
+		}
+		if t.IsPunct(")") && len(f.stack) > 1 {
+			fr := f.top()
+			f.stack = f.stack[:len(f.stack)-1]
+			if fr.kind != inlineFrame {
+				f.newline(fr.indent)
+			}
+			f.write(t)
+			continue
+		}
+
+		fr := f.top()
+		switch fr.kind {
+		case queryFrame:
+			i = f.query(fr, i)
+		case blockFrame:
+			if t.IsPunct(",") {
+				f.comma(t, fr.indent+1)
+				continue
+			}
+			f.write(t)
+		default:
+			f.write(t)
+		}
+	}
+}
+
+// query formats the token at i inside a statement or subquery and returns
+// the index of the last token it consumed.
+func (f *formatter) query(fr *frame, i int) int {
+	t := f.toks[i]
+	if name, end, ok := f.clauseAt(fr, i); ok {
+		return f.clause(fr, name, i, end)
+	}
+
+	switch {
+	case t.Is("CASE"):
+		fr.cases = append(fr.cases, f.indent)
+		f.write(t)
+		return i
+
+	case (t.Is("WHEN") || t.Is("ELSE")) && len(fr.cases) > 0:
+		f.newline(fr.cases[len(fr.cases)-1] + 1)
+
+	case t.Is("END") && len(fr.cases) > 0:
+		f.newline(fr.cases[len(fr.cases)-1])
+		fr.cases = fr.cases[:len(fr.cases)-1]
+
+	case t.Is("BETWEEN"):
+		fr.between = true
+
+	case (t.Is("AND") || t.Is("OR")) && len(fr.cases) == 0 && conditionClause[fr.clause]:
+		if t.Is("AND") && fr.between {
+			fr.between = false
+			break
+		}
+		f.newline(fr.level + 1)
+
+	case fr.clause == "from" && f.joinAt(i):
+		f.newline(fr.level)
+
+	case fr.clause == "from" && t.Is("ON"):
+		f.newline(fr.level + 1)
+
+	case t.IsPunct(",") && fr.list:
+		indent := fr.level + 1
+		if fr.clause == "with" {
+			indent = fr.level
+		}
+		f.comma(t, indent)
+		return i
+	}
+	f.write(t)
+	return i
+}
+
+// conditionClause lists the clauses whose AND and OR start new lines.
+var conditionClause = map[string]bool{"where": true, "having": true, "from": true}
+
+// joinModifiers are the words that can begin a JOIN.
+var joinModifiers = map[string]bool{
+	"join": true, "left": true, "right": true, "full": true, "inner": true,
+	"cross": true, "natural": true, "outer": true,
+}
+
+// joinAt reports whether a JOIN starts at i.
+func (f *formatter) joinAt(i int) bool {
+	t := f.toks[i]
+	if t.Kind != Keyword || !joinModifiers[strings.ToLower(t.Text)] {
+		return false
+	}
+	if p, ok := f.prev(i); ok && p.Kind == Keyword && joinModifiers[strings.ToLower(p.Text)] {
+		return false
+	}
+	// left(s, 3) and right(s, 3) are functions
+	n, ok := f.next(i)
+	return !ok || !n.IsPunct("(")
+}
+
+// clauseAt reports whether a clause of a query starts at i, returning its
+// name and the index of the last token of its leading keywords.
+func (f *formatter) clauseAt(fr *frame, i int) (string, int, bool) {
+	t := f.toks[i]
+	if t.Kind != Keyword {
+		return "", 0, false
+	}
+	word := strings.ToLower(t.Text)
+	prev, hasPrev := f.prev(i)
+	next, hasNext := f.next(i)
+	first := !hasPrev || prev.IsPunct("(") || prev.IsPunct(";")
+
+	switch word {
+	case "select":
+		end := i
+		if n, ok := f.nextIndex(end); ok && (f.toks[n].Is("ALL") || f.toks[n].Is("DISTINCT")) {
+			end = n
+			if on, ok := f.nextIndex(end); ok && f.toks[on].Is("ON") && f.toks[end].Is("DISTINCT") {
+				end = on
+				if p, ok := f.nextIndex(on); ok && f.toks[p].IsPunct("(") {
+					end = f.matching(p)
+				}
+			}
+		}
+		return word, end, true
+
+	case "from":
+		if hasPrev && (prev.Is("DELETE") || prev.Is("DISTINCT")) {
+			return "", 0, false
+		}
+		return word, i, true
+
+	case "where", "having", "limit", "offset", "fetch", "window", "returning":
+		return word, i, true
+
+	case "values":
+		if hasPrev && prev.Is("DEFAULT") {
+			return "", 0, false
+		}
+		return word, i, true
+
+	case "set":
+		if first || fr.clause == "update" || fr.clause == "conflict" {
+			return word, i, true
+		}
+
+	case "group", "order":
+		if hasNext && next.Is("BY") {
+			n, _ := f.nextIndex(i)
+			return word, n, true
+		}
+
+	case "union", "intersect", "except":
+		end := i
+		if n, ok := f.nextIndex(i); ok && (f.toks[n].Is("ALL") || f.toks[n].Is("DISTINCT")) {
+			end = n
+		}
+		return word, end, true
+
+	case "with":
+		if first {
+			return word, i, true
+		}
+
+	case "insert", "update", "delete":
+		if first || prev.IsPunct(")") {
+			end := i
+			if n, ok := f.nextIndex(i); ok && (f.toks[n].Is("INTO") || f.toks[n].Is("FROM")) {
+				end = n
+			}
+			return word, end, true
+		}
+
+	case "on":
+		if hasNext && strings.EqualFold(next.Text, "conflict") {
+			n, _ := f.nextIndex(i)
+			return "conflict", n, true
+		}
+
+	case "for":
+		if hasNext && hasPrev {
+			switch strings.ToLower(next.Text) {
+			case "update", "share", "no", "key":
+				return word, i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// clause starts a clause whose leading keywords run from i to end.
+func (f *formatter) clause(fr *frame, name string, i, end int) int {
+	fr.clause = name
+	fr.between = false
+	fr.cases = nil
+	f.newline(fr.level)
+	for j := i; j <= end; j++ {
+		f.pos = j
+		if f.toks[j].Kind == Comment {
+			f.comment(j)
+			continue
+		}
+		f.write(f.toks[j])
+	}
+
+	switch name {
+	case "union", "intersect", "except":
+		fr.list = false
+		f.newline(fr.level)
+	case "with", "insert", "update", "delete", "conflict", "limit", "offset", "fetch", "for", "window":
+		fr.list = name == "with" && f.listAhead(fr, end+1)
+	default:
+		fr.list = f.listAhead(fr, end+1)
+		if fr.list {
+			f.newline(fr.level + 1)
+		}
+	}
+	return end
+}
+
+// listAhead reports whether the clause starting at i has more than one
+// item, that is a comma outside parentheses before the next clause.
+func (f *formatter) listAhead(fr *frame, i int) bool {
+	depth := 0
+	for j := i; j < len(f.toks); j++ {
+		t := f.toks[j]
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case t.IsPunct(";"):
+			return false
+		case depth > 0:
+		case t.IsPunct(","):
+			return true
+		default:
+			if _, _, ok := f.clauseAt(fr, j); ok {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// open writes an opening parenthesis and pushes the frame it starts.
+func (f *formatter) open(i int) {
+	t := f.toks[i]
+	fr := &frame{kind: inlineFrame, indent: f.indent}
+	if n, ok := f.next(i); ok && (n.Is("SELECT") || n.Is("WITH") || n.Is("VALUES")) {
+		fr.kind = queryFrame
+		fr.level = f.indent + 1
+	} else if len(f.stack) == 1 && f.objectBefore(i) == "table" && f.createStatement(i) {
+		fr.kind = blockFrame
+	}
+	f.write(t)
+	f.stack = append(f.stack, fr)
+	if fr.kind == blockFrame {
+		f.newline(fr.indent + 1)
+	}
+}
+
+// objectBefore returns, lower-cased, the keyword before the possibly
+// qualified name that ends just before i, such as "table" in
+// "CREATE TABLE s.t (". It returns "" when there is no name there.
+func (f *formatter) objectBefore(i int) string {
+	j, ok := f.prevIndex(i)
+	if !ok || !f.toks[j].IsName() {
+		return ""
+	}
+	for {
+		k, ok := f.prevIndex(j)
+		if !ok {
+			return ""
+		}
+		if f.toks[k].IsPunct(".") {
+			if j, ok = f.prevIndex(k); !ok {
+				return ""
+			}
+			continue
+		}
+		if f.toks[k].Kind != Keyword {
+			return ""
+		}
+		return strings.ToLower(f.toks[k].Text)
+	}
+}
+
+// createStatement reports whether the statement around i is a CREATE.
+func (f *formatter) createStatement(i int) bool {
+	first := -1
+	for j := i - 1; j >= 0 && !f.toks[j].IsPunct(";"); j-- {
+		if f.toks[j].Kind != Comment {
+			first = j
+		}
+	}
+	return first >= 0 && f.toks[first].Is("CREATE")
+}
+
+// comma writes a list comma so the next item starts a line at indent.
+func (f *formatter) comma(t Token, indent int) {
+	if f.opts.LeadingCommas {
+		f.newline(indent)
+		f.write(t)
+		return
+	}
+	f.write(t)
+	f.newline(indent)
+}
+
+// comment writes the comment at i, on its own line if it had one.
+func (f *formatter) comment(i int) {
+	t := f.toks[i]
+	if f.ownLine[i] {
+		if f.start {
+			f.blankLine()
+			f.start = false
+		}
+		f.newline(f.continuation())
+	}
+	f.put(t.Text, f.line.Len() > 0)
+	f.last = t
+	f.lastUnary = false
+	if strings.HasPrefix(t.Text, "--") || f.ownLine[i] {
+		f.mustBreak = true
+	}
+}
+
+// continuation returns the indentation for a line that continues the
+// current clause or expression.
+func (f *formatter) continuation() int {
+	fr := f.top()
+	if fr.kind == queryFrame {
+		if len(fr.cases) > 0 {
+			return fr.cases[len(fr.cases)-1] + 1
+		}
+		if fr.clause == "" {
+			return fr.level
+		}
+		return fr.level + 1
+	}
+	return fr.indent + 1
+}
+
+// write appends a token to the current line.
+func (f *formatter) write(t Token) {
+	if f.mustBreak {
+		f.newline(f.continuation())
+	}
+	text := t.Text
+	if t.Kind == Keyword {
+		switch f.opts.KeywordCase {
+		case UpperKeywords:
+			text = strings.ToUpper(text)
+		case LowerKeywords:
+			text = strings.ToLower(text)
+		}
+	}
+	unary := t.Kind == Operator && (t.Text == "-" || t.Text == "+") && f.operand()
+	f.put(text, f.line.Len() > 0 && f.space(t))
+	f.last = t
+	f.lastUnary = unary
+}
+
+// operand reports whether an operand is expected next, which makes a
+// following minus or plus sign unary.
+func (f *formatter) operand() bool {
+	l := f.last
+	switch l.Kind {
+	case Operator, Comment:
+		return true
+	case Punct:
+		return !l.IsPunct(")") && !l.IsPunct("]")
+	case Keyword:
+		return !l.IsName()
+	}
+	return f.line.Len() == 0
+}
+
+// space reports whether t is separated from the previous token.
+func (f *formatter) space(t Token) bool {
+	l := f.last
+	switch {
+	case f.lastUnary, l.IsPunct("("), l.IsPunct("["), l.IsPunct("."), l.Text == "::":
+		return false
+	case t.IsPunct(")"), t.IsPunct("]"), t.IsPunct("["), t.IsPunct("."),
+		t.IsPunct(","), t.IsPunct(";"), t.Text == "::":
+		return false
+	case t.IsPunct("("):
+		return !f.call()
+	}
+	return true
+}
+
+// call reports whether an opening parenthesis written now starts the
+// arguments of a function call.
+func (f *formatter) call() bool {
+	l := f.last
+	switch l.Kind {
+	case Ident, QuotedIdent:
+		switch f.objectBefore(f.pos) {
+		case "into", "table", "view":
+			return false
+		}
+		return true
+	case Keyword:
+		return callKeywords[strings.ToLower(l.Text)]
+	}
+	return false
+}
+
+// callKeywords are keywords written like functions, with no space before
+// their parenthesis.
+var callKeywords = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+	"cast": true, "any": true, "some": true, "all": true, "array": true,
+	"left": true, "right": true, "replace": true,
+}
+
+func (f *formatter) put(text string, space bool) {
+	if space {
+		f.line.WriteByte(' ')
+	}
+	f.line.WriteString(text)
+}
+
+// newline ends the current line, if it has text, and sets the indentation
+// of the next one.
+func (f *formatter) newline(indent int) {
+	if f.line.Len() > 0 {
+		f.flush()
+	}
+	f.indent = indent
+	f.mustBreak = false
+	f.lastUnary = false
+}
+
+// blankLine ends the current line and leaves an empty one after it.
+func (f *formatter) blankLine() {
+	if f.line.Len() > 0 {
+		f.flush()
+	}
+	if f.out.Len() > 0 {
+		f.out.WriteByte('\n')
+	}
+	f.indent = 0
+	f.mustBreak = false
+}
+
+func (f *formatter) flush() {
+	f.out.WriteString(strings.Repeat(" ", f.indent*f.opts.Indent))
+	f.out.WriteString(f.line.String())
+	f.out.WriteByte('\n')
+	f.line.Reset()
+}
+
+// String returns the formatted text without a trailing newline.
+func (f *formatter) String() string {
+	if f.line.Len() > 0 {
+		f.flush()
+	}
+	return strings.TrimRight(f.out.String(), "\n")
+}
+
+// prevIndex and nextIndex find the nearest token before or after i that
+// is not a comment.
+func (f *formatter) prevIndex(i int) (int, bool) {
+	for j := i - 1; j >= 0; j-- {
+		if f.toks[j].Kind != Comment {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+func (f *formatter) nextIndex(i int) (int, bool) {
+	for j := i + 1; j < len(f.toks); j++ {
+		if f.toks[j].Kind != Comment {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+func (f *formatter) prev(i int) (Token, bool) {
+	j, ok := f.prevIndex(i)
+	if !ok {
+		return Token{}, false
+	}
+	return f.toks[j], true
+}
+
+func (f *formatter) next(i int) (Token, bool) {
+	j, ok := f.nextIndex(i)
+	if !ok {
+		return Token{}, false
+	}
+	return f.toks[j], true
+}
+
+// matching returns the index of the parenthesis closing the one at i, or
+// the last token when it is never closed.
+func (f *formatter) matching(i int) int {
+	depth := 0
+	for j := i; j < len(f.toks); j++ {
+		switch {
+		case f.toks[j].IsPunct("("):
+			depth++
+		case f.toks[j].IsPunct(")"):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(f.toks) - 1
+}
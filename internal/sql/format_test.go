@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+)
+
+// formatInputs cover the constructs Format lays out.
+var formatInputs = []string{
+	"select id, name from users where active and age > 18 order by name",
+	"SELECT a FROM t1 JOIN t2 ON t1.id = t2.id LEFT JOIN t3 ON t3.x = t2.x AND t3.y = 1",
+	"with recent as (select * from orders where created > now() - interval '1 day') select count(*) from recent",
+	"select case when a = 1 then 'one' when a = 2 then 'two' else 'many' end as n from t",
+	"select * from t where id in (select id from u where u.x = 'a;b')",
+	"-- leading comment\nselect 1; /* block */ select 2",
+	"insert into t (a, b) values (1, 'x'), (2, 'y') returning a",
+	"update t set a = 1, b = coalesce(b, 0) where id = $1",
+	"create table t (id int primary key, name text not null default 'x')",
+	"create function f() returns int as $$ select 1; $$ language sql",
+	`select "Odd Name", e'it\'s' from "T"`,
+	"select a, -- trailing\n b from t",
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	opts := []FormatOptions{
+		DefaultFormatOptions,
+		{Indent: 2, KeywordCase: LowerKeywords},
+		{Indent: 4, KeywordCase: PreserveKeywords, LeadingCommas: true},
+	}
+	for _, o := range opts {
+		for _, src := range formatInputs {
+			once := Format(src, o)
+			if twice := Format(once, o); twice != once {
+				t.Errorf("Format is not idempotent with %+v for %q:\nonce:\n%s\ntwice:\n%s", o, src, once, twice)
+			}
+		}
+	}
+}
+
+func TestFormatKeepsTokens(t *testing.T) {
+	for _, src := range formatInputs {
+		want := significantTexts(src)
+		got := significantTexts(Format(src, FormatOptions{KeywordCase: PreserveKeywords}))
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("Format changed the tokens of %q:\ngot  %q\nwant %q", src, got, want)
+		}
+	}
+}
+
+func significantTexts(src string) []string {
+	var out []string
+	for _, t := range Significant(Lex(src)) {
+		out = append(out, t.Text)
+	}
+	return out
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		src  string
+		opts FormatOptions
+		want string
+	}{
+		{
+			"select id, name from users where a = 1 and b = 2",
+			DefaultFormatOptions,
+			"SELECT\n    id,\n    name\nFROM users\nWHERE a = 1\n    AND b = 2",
+		},
+		{
+			"SELECT id, name FROM users",
+			FormatOptions{Indent: 2, KeywordCase: LowerKeywords, LeadingCommas: true},
+			"select\n  id\n  , name\nfrom users",
+		},
+		{
+			"select 1;select 2",
+			DefaultFormatOptions,
+			"SELECT 1;\n\nSELECT 2",
+		},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.src, tt.opts); got != tt.want {
+			t.Errorf("Format(%q) =\n%s\nwant\n%s", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package sql
+
+import "strings"
+
+// keywords maps the keywords the lexer recognises to whether they are
+// reserved, meaning they cannot be used as a table or alias name without
+// quoting. The list covers everyday SQL rather than every PostgreSQL
+// keyword, so that common column names such as "name" or "type" stay
+// identifiers.
+var keywords = map[string]bool{
+	// reserved
+	"all": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "between": true, "both": true, "case": true, "cast": true,
+	"check": true, "collate": true, "column": true, "constraint": true,
+	"create": true, "cross": true, "default": true, "desc": true,
+	"distinct": true, "do": true, "else": true, "end": true, "except": true,
+	"false": true, "fetch": true, "for": true, "foreign": true, "from": true,
+	"full": true, "grant": true, "group": true, "having": true, "ilike": true,
+	"in": true, "inner": true, "intersect": true, "into": true, "is": true,
+	"join": true, "lateral": true, "leading": true, "left": true, "like": true,
+	"limit": true, "natural": true, "not": true, "null": true, "offset": true,
+	"on": true, "only": true, "or": true, "order": true, "outer": true,
+	"primary": true, "references": true, "returning": true, "right": true,
+	"select": true, "similar": true, "some": true, "table": true, "then": true,
+	"to": true, "trailing": true, "true": true, "union": true, "unique": true,
+	"using": true, "when": true, "where": true, "window": true, "with": true,
+
+	// non-reserved: usable as names, still cased as keywords
+	"alter": false, "analyze": false, "avg": false, "begin": false,
+	"by": false, "cascade": false, "commit": false, "conflict": false,
+	"count": false, "delete": false, "drop": false, "exists": false,
+	"explain": false, "filter": false, "function": false, "if": false,
+	"index": false, "insert": false, "key": false, "max": false, "min": false,
+	"nothing": false, "over": false, "partition": false, "recursive": false,
+	"replace": false, "restrict": false, "returns": false, "revoke": false,
+	"rollback": false, "schema": false, "sequence": false, "set": false,
+	"sum": false, "trigger": false, "truncate": false, "update": false,
+	"values": false, "view": false,
+}
+
+// IsKeyword reports whether word is a keyword the lexer recognises.
+func IsKeyword(word string) bool {
+	_, ok := keywords[strings.ToLower(word)]
+	return ok
+}
+
+// IsReserved reports whether word is a keyword that cannot be used as a
+// name without quoting.
+func IsReserved(word string) bool {
+	return keywords[strings.ToLower(word)]
+}
@@ -0,0 +1,364 @@
+// Package sql is a PostgreSQL lexer and the small analyses built on it:
+// statement splitting, finding the tables a statement references,
+// binding its parameters and changing its sort order.
+// It understands string literals, quoted identifiers, comments and
+// dollar-quoted bodies, so code built on it never mistakes their content
+// for SQL.
+package sql
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind classifies a token.
+type Kind int
+
+const (
+	Whitespace   Kind = iota
+	Comment           // -- line or /* block */ comment
+	Keyword           // SQL keyword, in any case
+	Ident             // unquoted identifier
+	QuotedIdent       // "quoted identifier"
+	String            // 'literal', E'literal', B'0101', X'ff', U&'...'
+	DollarString      // $tag$ body $tag$
+	Number            // 42, 3.14, 1e10
+	Param             // $1, :name or ${name} parameter
+	Operator          // +, ::, <>, ...
+	Punct             // ( ) [ ] , ; .
+)
+
+// Token is a piece of SQL source. Concatenating the text of every token
+// Lex returns gives back the source unchanged.
+type Token struct {
+	Kind Kind
+	Text string
+	Pos  int // byte offset of the token in the source
+}
+
+// End returns the byte offset just past the token.
+func (t Token) End() int {
+	return t.Pos + len(t.Text)
+}
+
+// Is reports whether the token is the given keyword, ignoring case.
+func (t Token) Is(keyword string) bool {
+	return t.Kind == Keyword && strings.EqualFold(t.Text, keyword)
+}
+
+// IsPunct reports whether the token is the given punctuation mark.
+func (t Token) IsPunct(p string) bool {
+	return t.Kind == Punct && t.Text == p
+}
+
+// IsSpace reports whether the token carries no meaning: whitespace or a
+// comment.
+func (t Token) IsSpace() bool {
+	return t.Kind == Whitespace || t.Kind == Comment
+}
+
+// IsName reports whether the token can name an object: an identifier,
+// a quoted identifier or a keyword PostgreSQL accepts as a name.
+func (t Token) IsName() bool {
+	switch t.Kind {
+	case Ident, QuotedIdent:
+		return true
+	case Keyword:
+		return !IsReserved(t.Text)
+	}
+	return false
+}
+
+// Name returns the identifier the token names, folded the way PostgreSQL
+// folds it: quoted identifiers keep their case, others are lower-cased.
+func (t Token) Name() string {
+	if t.Kind != QuotedIdent {
+		return strings.ToLower(t.Text)
+	}
+	s := t.Text
+	if strings.HasPrefix(s, "U&") || strings.HasPrefix(s, "u&") {
+		s = s[2:]
+	}
+	s = strings.TrimPrefix(s, `"`)
+	s = strings.TrimSuffix(s, `"`)
+	return strings.ReplaceAll(s, `""`, `"`)
+}
+
+// Open reports whether a token at the end of the input was cut short, so
+// text typed after it would still be part of it: an unterminated string,
+// quoted identifier, dollar-quoted body or block comment, or a line
+// comment.
+func (t Token) Open() bool {
+	switch t.Kind {
+	case Comment, String, QuotedIdent, DollarString:
+		_, n := scan(t.Text + "x")
+		return n > len(t.Text)
+	}
+	return false
+}
+
+// Lex splits src into tokens. It never fails: unterminated strings and
+// comments run to the end of the input.
+func Lex(src string) []Token {
+	toks := make([]Token, 0, len(src)/4+1)
+	for i := 0; i < len(src); {
+		kind, n := scan(src[i:])
+		toks = append(toks, Token{Kind: kind, Text: src[i : i+n], Pos: i})
+		i += n
+	}
+	return toks
+}
+
+// Significant returns the tokens that are neither whitespace nor comments.
+func Significant(toks []Token) []Token {
+	out := make([]Token, 0, len(toks))
+	for _, t := range toks {
+		if !t.IsSpace() {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// scan returns the kind and byte length of the token at the start of s.
+func scan(s string) (Kind, int) {
+	c := s[0]
+	switch {
+	case isSpace(c):
+		n := 1
+		for n < len(s) && isSpace(s[n]) {
+			n++
+		}
+		return Whitespace, n
+
+	case strings.HasPrefix(s, "--"):
+		n := strings.IndexByte(s, '\n')
+		if n < 0 {
+			n = len(s)
+		}
+		return Comment, n
+
+	case strings.HasPrefix(s, "/*"):
+		return Comment, blockComment(s)
+
+	case c == '\'':
+		return String, quoted(s, 0, '\'', false)
+
+	case c == '"':
+		return QuotedIdent, quoted(s, 0, '"', false)
+
+	case c == '$':
+		if n := dollarQuote(s); n > 0 {
+			return DollarString, n
+		}
+		if n := braceParam(s); n > 0 {
+			return Param, n
+		}
+		n := 1
+		for n < len(s) && isDigit(s[n]) {
+			n++
+		}
+		if n > 1 {
+			return Param, n
+		}
+		return Operator, 1
+
+	case c == ':' && colonParam(s):
+		n := 1
+		for n < len(s) && isIdentPart(s[n:]) {
+			_, size := utf8.DecodeRuneInString(s[n:])
+			n += size
+		}
+		return Param, n
+
+	case isDigit(c) || (c == '.' && len(s) > 1 && isDigit(s[1])):
+		return Number, number(s)
+
+	case isIdentStart(s):
+		if kind, n, ok := prefixedString(s); ok {
+			return kind, n
+		}
+		n := 0
+		for n < len(s) && isIdentPart(s[n:]) {
+			_, size := utf8.DecodeRuneInString(s[n:])
+			n += size
+		}
+		if IsKeyword(s[:n]) {
+			return Keyword, n
+		}
+		return Ident, n
+
+	case strings.IndexByte("()[],;.", c) >= 0:
+		return Punct, 1
+
+	case strings.IndexByte(operatorChars, c) >= 0:
+		n := 1
+		for n < len(s) && strings.IndexByte(operatorChars, s[n]) >= 0 {
+			// a comment start ends the operator
+			if strings.HasPrefix(s[n:], "--") || strings.HasPrefix(s[n:], "/*") {
+				break
+			}
+			// and so does a :name parameter, as in =:id
+			if s[n-1] != ':' && colonParam(s[n:]) {
+				break
+			}
+			n++
+		}
+		return Operator, n
+	}
+
+	_, size := utf8.DecodeRuneInString(s)
+	return Operator, size
+}
+
+const operatorChars = "+-*/<>=~!@#%^&|`?:"
+
+// prefixedString recognises E'..', B'..', X'..', N'..' and U&'..'
+// literals and U&".." identifiers.
+func prefixedString(s string) (Kind, int, bool) {
+	if len(s) >= 3 && (s[0] == 'U' || s[0] == 'u') && s[1] == '&' {
+		switch s[2] {
+		case '\'':
+			return String, 2 + quoted(s, 2, '\'', false), true
+		case '"':
+			return QuotedIdent, 2 + quoted(s, 2, '"', false), true
+		}
+	}
+	if len(s) >= 2 && s[1] == '\'' {
+		switch s[0] {
+		case 'E', 'e':
+			return String, 1 + quoted(s, 1, '\'', true), true
+		case 'B', 'b', 'X', 'x', 'N', 'n':
+			return String, 1 + quoted(s, 1, '\'', false), true
+		}
+	}
+	return 0, 0, false
+}
+
+// quoted returns the length of the quoted text starting at s[from], which
+// is the quote. Doubled quotes are escapes, and so are backslashes when
+// backslash is set.
+func quoted(s string, from int, quote byte, backslash bool) int {
+	s = s[from:]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// blockComment returns the length of a possibly nested /* */ comment.
+func blockComment(s string) int {
+	depth := 0
+	for i := 0; i < len(s)-1; i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*':
+			depth++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// colonParam reports whether s starts with a :name parameter: a colon
+// followed by an identifier, which is not the :: cast operator.
+func colonParam(s string) bool {
+	return len(s) > 1 && s[0] == ':' && isIdentStart(s[1:])
+}
+
+// braceParam returns the length of a ${name} parameter, or 0 when s does
+// not start with one.
+func braceParam(s string) int {
+	if len(s) < 4 || s[1] != '{' || !isIdentStart(s[2:]) {
+		return 0
+	}
+	n := 2
+	for n < len(s) && isIdentPart(s[n:]) {
+		_, size := utf8.DecodeRuneInString(s[n:])
+		n += size
+	}
+	if n < len(s) && s[n] == '}' {
+		return n + 1
+	}
+	return 0
+}
+
+// dollarQuote returns the length of a $tag$...$tag$ string, or 0 when s
+// does not start with a dollar-quote opening.
+func dollarQuote(s string) int {
+	end := 1
+	for end < len(s) && s[end] != '$' {
+		if !isIdentPart(s[end:]) || (end == 1 && isDigit(s[end])) {
+			return 0
+		}
+		end++
+	}
+	if end >= len(s) {
+		return 0
+	}
+	tag := s[:end+1]
+	if close := strings.Index(s[len(tag):], tag); close >= 0 {
+		return len(tag) + close + len(tag)
+	}
+	return len(s)
+}
+
+func number(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	if n < len(s) && s[n] == '.' && !(n+1 < len(s) && s[n+1] == '.') {
+		n++
+		for n < len(s) && isDigit(s[n]) {
+			n++
+		}
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		m := n + 1
+		if m < len(s) && (s[m] == '+' || s[m] == '-') {
+			m++
+		}
+		if m < len(s) && isDigit(s[m]) {
+			n = m
+			for n < len(s) && isDigit(s[n]) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(s string) bool {
+	c := s[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= utf8.RuneSelf
+}
+
+func isIdentPart(s string) bool {
+	c := s[0]
+	return isIdentStart(s) || isDigit(c) || c == '$'
+}
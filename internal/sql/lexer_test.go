@@ -0,0 +1,91 @@
+package sql
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []Kind
+	}{
+		{"SELECT 1", []Kind{Keyword, Whitespace, Number}},
+		{"select a.b", []Kind{Keyword, Whitespace, Ident, Punct, Ident}},
+		{`"Mixed Case"`, []Kind{QuotedIdent}},
+		{`U&"d\0061t"`, []Kind{QuotedIdent}},
+		{"'it''s'", []Kind{String}},
+		{`E'a\'b'`, []Kind{String}},
+		{"X'ff' B'01' N'x' U&'y'", []Kind{String, Whitespace, String, Whitespace, String, Whitespace, String}},
+		{"$$a;b$$", []Kind{DollarString}},
+		{"$fn$ $$ $fn$", []Kind{DollarString}},
+		{"$1 :name ${var}", []Kind{Param, Whitespace, Param, Whitespace, Param}},
+		{"a::int", []Kind{Ident, Operator, Ident}},
+		{"x=:id", []Kind{Ident, Operator, Param}},
+		{"1.5e-3 .5 a[1:2]", []Kind{Number, Whitespace, Number, Whitespace, Ident, Punct, Number, Operator, Number, Punct}},
+		{"-- note\nx", []Kind{Comment, Whitespace, Ident}},
+		{"/* a /* nested */ b */x", []Kind{Comment, Ident}},
+		{"a<>b", []Kind{Ident, Operator, Ident}},
+		{"a--b", []Kind{Ident, Comment}},
+		{"'open", []Kind{String}},
+		{"(a, b);", []Kind{Punct, Ident, Punct, Whitespace, Ident, Punct, Punct}},
+	}
+	for _, tt := range tests {
+		toks := Lex(tt.src)
+		var got []Kind
+		var b strings.Builder
+		for _, tok := range toks {
+			got = append(got, tok.Kind)
+			if tok.Pos != b.Len() {
+				t.Errorf("Lex(%q): token %q at %d, want %d", tt.src, tok.Text, tok.Pos, b.Len())
+			}
+			b.WriteString(tok.Text)
+		}
+		if b.String() != tt.src {
+			t.Errorf("Lex(%q) does not give back the source: %q", tt.src, b.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Lex(%q) kinds = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestTokenName(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"Users", "users"},
+		{`"Users"`, "Users"},
+		{`"a""b"`, `a"b`},
+		{`U&"x"`, "x"},
+	}
+	for _, tt := range tests {
+		if got := Lex(tt.src)[0].Name(); got != tt.want {
+			t.Errorf("Name(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestTokenOpen(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"'done'", false},
+		{"'not done", true},
+		{`"ident`, true},
+		{"$$body", true},
+		{"/* comment", true},
+		{"/* comment */", false},
+		{"-- line", true},
+		{"name", false},
+	}
+	for _, tt := range tests {
+		toks := Lex(tt.src)
+		if got := toks[len(toks)-1].Open(); got != tt.want {
+			t.Errorf("Open(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Limit returns the row count a query's top-level LIMIT or FETCH FIRST
+// clause caps its result at, when it is a literal number.
+func Limit(src string) (int, bool) {
+	toks, ok := query(src)
+	if !ok {
+		return 0, false
+	}
+	depth := 0
+	for i, t := range toks {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth > 0:
+		case t.Is("LIMIT") && i+1 < len(toks) && toks[i+1].Kind == Number:
+			n, err := strconv.Atoi(toks[i+1].Text)
+			return n, err == nil
+		case t.Is("FETCH") && i+2 < len(toks) && toks[i+2].Kind == Number &&
+			(isWord(toks[i+1], "FIRST") || isWord(toks[i+1], "NEXT")):
+			n, err := strconv.Atoi(toks[i+2].Text)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// OrderBy rewrites a query to sort its result by the given ORDER BY list,
+// replacing a top-level ORDER BY it has or adding one before its LIMIT,
+// OFFSET, FETCH or locking clause. ok is false when src is not a single
+// SELECT, VALUES or WITH query.
+func OrderBy(src, list string) (string, bool) {
+	toks, ok := query(src)
+	if !ok {
+		return "", false
+	}
+	// the clause goes from start to end; at is where one is added
+	start, end, at := -1, -1, -1
+	depth := 0
+	for i, t := range toks {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth > 0:
+		case t.Is("ORDER") && i+1 < len(toks) && toks[i+1].Is("BY"):
+			start = t.Pos
+		case t.Is("LIMIT") || t.Is("OFFSET") || t.Is("FETCH") || t.Is("FOR"):
+			if at < 0 {
+				at = t.Pos
+			}
+			if start >= 0 && end < 0 {
+				end = t.Pos
+			}
+		}
+	}
+	last := toks[len(toks)-1].End()
+	switch {
+	case start >= 0 && end < 0:
+		end = last
+	case start < 0 && at >= 0:
+		start, end = at, at
+	case start < 0:
+		start, end = last, last
+	}
+
+	before := strings.TrimRight(src[:start], " \t\r\n")
+	after := strings.TrimLeft(src[end:], " \t\r\n")
+	out := before + " ORDER BY " + list
+	if end < last {
+		out += " "
+	}
+	return out + after, true
+}
+
+// ReturnsRows reports whether src is a single SELECT, VALUES or WITH
+// query that returns rows without changing any.
+func ReturnsRows(src string) bool {
+	_, ok := query(src)
+	return ok
+}
+
+// query returns the significant tokens of src, with positions in src,
+// when it is a single statement returning rows a client can sort.
+func query(src string) ([]Token, bool) {
+	stmts := Split(src)
+	if len(stmts) != 1 {
+		return nil, false
+	}
+	toks := Significant(stmts[0].Tokens)
+	if len(toks) == 0 {
+		return nil, false
+	}
+	if first := toks[0]; !first.Is("SELECT") && !first.Is("VALUES") && !first.Is("WITH") && !first.IsPunct("(") {
+		return nil, false
+	}
+	// a common table expression may change rows too
+	for i, t := range toks[1:] {
+		if toks[i].IsPunct("(") && (t.Is("INSERT") || t.Is("UPDATE") || t.Is("DELETE") || t.Is("MERGE")) {
+			return nil, false
+		}
+	}
+	if !toks[0].Is("WITH") {
+		return toks, true
+	}
+	// the statement after the common table expressions may change rows
+	// rather than return them
+	depth := 0
+	for _, t := range toks {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth > 0:
+		case t.Is("SELECT") || t.Is("VALUES"):
+			return toks, true
+		case t.Is("INSERT") || t.Is("UPDATE") || t.Is("DELETE") || t.Is("MERGE"):
+			return nil, false
+		}
+	}
+	return toks, true
+}
+
+// isWord reports whether t is the given word, which PostgreSQL may lex
+// as a keyword or an identifier.
+func isWord(t Token, word string) bool {
+	return (t.Kind == Keyword || t.Kind == Ident) && strings.EqualFold(t.Text, word)
+}
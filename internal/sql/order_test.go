@@ -0,0 +1,84 @@
+package sql
+
+import "testing"
+
+func TestReturnsRows(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"SELECT 1", true},
+		{"  select * from t;  ", true},
+		{"VALUES (1), (2)", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"WITH x AS (DELETE FROM t RETURNING *) SELECT * FROM x", false},
+		{"WITH x AS MATERIALIZED (UPDATE t SET a = 1 RETURNING *) SELECT * FROM x", false},
+		{"SELECT * FROM t FOR UPDATE", true},
+		{"WITH x AS (SELECT 1) DELETE FROM t", false},
+		{"WITH x AS (SELECT 1) UPDATE t SET a = 1 RETURNING *", false},
+		{"UPDATE t SET a = 1 RETURNING *", false},
+		{"DELETE FROM t", false},
+		{"INSERT INTO t VALUES (1)", false},
+		{"SELECT 1; SELECT 2", false},
+		{"", false},
+		{"-- only a comment", false},
+	}
+	for _, tt := range tests {
+		if got := ReturnsRows(tt.src); got != tt.want {
+			t.Errorf("ReturnsRows(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		src  string
+		want int
+		ok   bool
+	}{
+		{"SELECT * FROM t LIMIT 100", 100, true},
+		{"select * from t limit 5 offset 10;", 5, true},
+		{"SELECT * FROM t FETCH FIRST 20 ROWS ONLY", 20, true},
+		{"SELECT * FROM t OFFSET 5 FETCH NEXT 3 ROWS ONLY", 3, true},
+		{"SELECT * FROM (SELECT * FROM t LIMIT 5) s", 0, false},
+		{"SELECT * FROM t LIMIT $1", 0, false},
+		{"SELECT * FROM t LIMIT ALL", 0, false},
+		{"SELECT * FROM t", 0, false},
+		{"DELETE FROM t", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Limit(tt.src)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Limit(%q) = %d, %v, want %d, %v", tt.src, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		src  string
+		list string
+		want string
+		ok   bool
+	}{
+		{"SELECT * FROM t", "a", "SELECT * FROM t ORDER BY a", true},
+		{"SELECT * FROM t;", "a", "SELECT * FROM t ORDER BY a;", true},
+		{"SELECT * FROM t ORDER BY b", "a DESC", "SELECT * FROM t ORDER BY a DESC", true},
+		{"SELECT * FROM t ORDER BY b LIMIT 10", "a", "SELECT * FROM t ORDER BY a LIMIT 10", true},
+		{"SELECT * FROM t LIMIT 10", "a", "SELECT * FROM t ORDER BY a LIMIT 10", true},
+		{"SELECT * FROM t\nWHERE x = 1\nOFFSET 5", "a", "SELECT * FROM t\nWHERE x = 1 ORDER BY a OFFSET 5", true},
+		{"SELECT * FROM t FOR UPDATE", "a", "SELECT * FROM t ORDER BY a FOR UPDATE", true},
+		{"SELECT * FROM (SELECT * FROM u ORDER BY x LIMIT 3) s", "a",
+			"SELECT * FROM (SELECT * FROM u ORDER BY x LIMIT 3) s ORDER BY a", true},
+		{"WITH x AS (SELECT 1 AS a) SELECT * FROM x", "a", "WITH x AS (SELECT 1 AS a) SELECT * FROM x ORDER BY a", true},
+		{"UPDATE t SET a = 1", "a", "", false},
+		{"SELECT 1; SELECT 2", "a", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := OrderBy(tt.src, tt.list)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("OrderBy(%q, %q) = %q, %v, want %q, %v", tt.src, tt.list, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Parameter is a placeholder for a value bound when a statement runs.
+type Parameter struct {
+	Name     string // the name of :name and ${name}; "" for $1
+	Position int    // the number of $1; 0 for a named parameter
+}
+
+// String returns the parameter as it is labelled: $1 or :name.
+func (p Parameter) String() string {
+	if p.Name == "" {
+		return "$" + strconv.Itoa(p.Position)
+	}
+	return ":" + p.Name
+}
+
+// ParameterOf returns the parameter a Param token stands for. :name and
+// ${name} are the same parameter.
+func ParameterOf(t Token) Parameter {
+	text := t.Text
+	switch {
+	case strings.HasPrefix(text, "${"):
+		return Parameter{Name: text[2 : len(text)-1]}
+	case strings.HasPrefix(text, ":"):
+		return Parameter{Name: text[1:]}
+	}
+	n, _ := strconv.Atoi(text[1:])
+	return Parameter{Position: n}
+}
+
+// Parameters returns the parameters src takes a value for, in the order they
+// are bound: $1 up to the highest positional parameter, including any it
+// skips, then the named ones as they first appear.
+func Parameters(src string) []Parameter {
+	var named []Parameter
+	last := 0
+	for _, t := range Lex(src) {
+		if t.Kind != Param {
+			continue
+		}
+		p := ParameterOf(t)
+		switch {
+		case p.Name == "":
+			last = max(last, p.Position)
+		case !slices.Contains(named, p):
+			named = append(named, p)
+		}
+	}
+	out := make([]Parameter, 0, last+len(named))
+	for i := 1; i <= last; i++ {
+		out = append(out, Parameter{Position: i})
+	}
+	return append(out, named...)
+}
+
+// Bind rewrites src for the driver, which only knows positional
+// parameters: named ones become $n, numbered after the highest
+// positional one in the order Parameters returns them. A parameter with a
+// type in casts, keyed by its label, is cast to it wherever it appears.
+func Bind(src string, casts map[string]string) string {
+	params := Parameters(src)
+	numbers := make(map[Parameter]int, len(params))
+	for i, p := range params {
+		numbers[p] = i + 1
+	}
+	var b strings.Builder
+	for _, t := range Lex(src) {
+		if t.Kind != Param {
+			b.WriteString(t.Text)
+			continue
+		}
+		p := ParameterOf(t)
+		b.WriteString("$" + strconv.Itoa(numbers[p]))
+		if typ := casts[p.String()]; typ != "" {
+			b.WriteString("::" + typ)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package sql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParameters(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"SELECT 1", nil},
+		{"SELECT $1, $2", []string{"$1", "$2"}},
+		{"SELECT $3", []string{"$1", "$2", "$3"}},
+		{"SELECT :a, ${b}, :a", []string{":a", ":b"}},
+		{"SELECT :a, $2", []string{"$1", "$2", ":a"}},
+		{"SELECT ${a}, :a", []string{":a"}},
+		{"SELECT x::int, ':no', \"$1\", $$ :no $$", nil},
+		{"SELECT * FROM t WHERE id=:id -- :no", []string{":id"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range Parameters(tt.src) {
+			got = append(got, p.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Parameters(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		src   string
+		casts map[string]string
+		want  string
+	}{
+		{"SELECT $1", nil, "SELECT $1"},
+		{"SELECT :a, :b, :a", nil, "SELECT $1, $2, $1"},
+		{"SELECT :a, $2", nil, "SELECT $3, $2"},
+		{"SELECT ${a} + :a", nil, "SELECT $1 + $1"},
+		{"SELECT :a, $1", map[string]string{":a": "int", "$1": "text"}, "SELECT $2::int, $1::text"},
+		{"SELECT ':a', x::date", nil, "SELECT ':a', x::date"},
+	}
+	for _, tt := range tests {
+		if got := Bind(tt.src, tt.casts); got != tt.want {
+			t.Errorf("Bind(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package sql
+
+import "strings"
+
+// Statement is one statement of a script, without its terminating
+// semicolon or surrounding whitespace and comments.
+type Statement struct {
+	Text   string
+	Start  int // byte offset of Text in the script
+	End    int // byte offset just past Text
+	Tokens []Token
+}
+
+// Split breaks a script into statements at top-level semicolons, skipping
+// empty ones. Semicolons inside strings, quoted identifiers, comments and
+// dollar-quoted bodies do not split.
+func Split(src string) []Statement {
+	var stmts []Statement
+	for _, seg := range segments(src) {
+		if st, ok := trim(src, seg.tokens); ok {
+			stmts = append(stmts, st)
+		}
+	}
+	return stmts
+}
+
+// StatementAt returns the statement around a byte offset of the script.
+// Blank space following a semicolon on the same line belongs to the
+// statement before it, so a cursor at the end of a line picks that line's
+// statement; elsewhere blank space belongs to the next statement, or to
+// the previous one at the end of the script.
+func StatementAt(src string, offset int) (Statement, bool) {
+	var prev Statement
+	found := false
+	prevSemi := -1
+	for _, seg := range segments(src) {
+		st, ok := trim(src, seg.tokens)
+		if offset <= seg.end {
+			sameLine := prevSemi >= 0 && offset > prevSemi && !strings.Contains(src[prevSemi:offset], "\n")
+			if ok && (offset >= st.Start || !found || !sameLine) {
+				return st, true
+			}
+			return prev, found
+		}
+		if ok {
+			prev, found = st, true
+		}
+		prevSemi = seg.end
+	}
+	return prev, found
+}
+
+type segment struct {
+	tokens []Token // excluding the semicolon
+	end    int     // byte offset of the semicolon, or of the end of src
+}
+
+func segments(src string) []segment {
+	var segs []segment
+	var cur []Token
+	for _, t := range Lex(src) {
+		if t.IsPunct(";") {
+			segs = append(segs, segment{tokens: cur, end: t.Pos})
+			cur = nil
+			continue
+		}
+		cur = append(cur, t)
+	}
+	return append(segs, segment{tokens: cur, end: len(src)})
+}
+
+// trim drops leading and trailing whitespace and comments.
+func trim(src string, toks []Token) (Statement, bool) {
+	first, last := -1, -1
+	for i, t := range toks {
+		if !t.IsSpace() {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return Statement{}, false
+	}
+	start, end := toks[first].Pos, toks[last].End()
+	return Statement{Text: src[start:end], Start: start, End: end, Tokens: toks[first : last+1]}, true
+}
@@ -0,0 +1,64 @@
+package sql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"", nil},
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{";;  ; SELECT 1;;", []string{"SELECT 1"}},
+		{"SELECT ';'; SELECT \";\"", []string{"SELECT ';'", `SELECT ";"`}},
+		{"SELECT 1 -- a; b\n; SELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"/* x; */ SELECT 1 /* y */", []string{"SELECT 1"}},
+		{"DO $$ BEGIN PERFORM 1; END $$; SELECT 2", []string{"DO $$ BEGIN PERFORM 1; END $$", "SELECT 2"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, st := range Split(tt.src) {
+			if tt.src[st.Start:st.End] != st.Text {
+				t.Errorf("Split(%q): %q is not at %d:%d", tt.src, st.Text, st.Start, st.End)
+			}
+			got = append(got, st.Text)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Split(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	const script = "SELECT 1;  \n\nSELECT 2;\nSELECT 3"
+	tests := []struct {
+		offset int
+		want   string
+		ok     bool
+	}{
+		{0, "SELECT 1", true},
+		{8, "SELECT 1", true},  // on the semicolon
+		{10, "SELECT 1", true}, // blank space after it on the same line
+		{12, "SELECT 2", true}, // the blank line before the next one
+		{13, "SELECT 2", true},
+		{22, "SELECT 2", true}, // end of the line
+		{len(script), "SELECT 3", true},
+	}
+	for _, tt := range tests {
+		st, ok := StatementAt(script, tt.offset)
+		if ok != tt.ok || st.Text != tt.want {
+			t.Errorf("StatementAt(%d) = %q, %v, want %q, %v", tt.offset, st.Text, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, ok := StatementAt("  ;  ", 1); ok {
+		t.Error("StatementAt found a statement in an empty script")
+	}
+	if st, ok := StatementAt("SELECT 1;\n\n", 11); !ok || st.Text != "SELECT 1" {
+		t.Errorf("StatementAt at the end of the script = %q, %v, want the last statement", st.Text, ok)
+	}
+}
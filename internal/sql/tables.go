@@ -0,0 +1,125 @@
+package sql
+
+import "strings"
+
+// TableRef is a table named in a FROM list, a JOIN, UPDATE or INSERT INTO.
+type TableRef struct {
+	Schema string // "" when unqualified
+	Name   string
+	Alias  string // "" when the table has no alias
+	Pos    int    // byte offset of the reference in the source
+}
+
+// Tables returns the tables a statement's tokens reference, with their
+// aliases, in order of appearance. Subqueries are searched too; a
+// subquery in a FROM list is not itself a table. FROM inside other
+// parentheses, as in extract(year FROM ts), names no table, and neither
+// do IS DISTINCT FROM, DO UPDATE and FOR UPDATE.
+func Tables(toks []Token) []TableRef {
+	toks = Significant(toks)
+	// a parenthesis left open, as while a statement is being typed,
+	// opens no level
+	closed := make(map[int]bool)
+	var open []int
+	for i, t := range toks {
+		switch {
+		case t.IsPunct("("):
+			open = append(open, i)
+		case t.IsPunct(")") && len(open) > 0:
+			closed[open[len(open)-1]] = true
+			open = open[:len(open)-1]
+		}
+	}
+
+	var refs []TableRef
+	// one level per open parenthesis, and one for the statement
+	type level struct {
+		query  bool // the parenthesis holds a query
+		inFrom bool
+	}
+	levels := []level{{query: true}}
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		cur := &levels[len(levels)-1]
+		switch {
+		case t.IsPunct("("):
+			if !closed[i] {
+				continue
+			}
+			sub := i+1 < len(toks) && (toks[i+1].Is("SELECT") || toks[i+1].Is("WITH") || toks[i+1].Is("VALUES"))
+			levels = append(levels, level{query: sub})
+			continue
+		case t.IsPunct(")"):
+			if len(levels) > 1 {
+				levels = levels[:len(levels)-1]
+			}
+			continue
+		case !cur.query:
+			continue
+		case t.Is("FROM"), t.Is("JOIN"), t.Is("UPDATE"), t.Is("INTO"):
+			if i > 0 && (toks[i-1].Is("DISTINCT") || toks[i-1].Is("DO") || toks[i-1].Is("FOR")) {
+				continue
+			}
+			if t.Is("FROM") {
+				cur.inFrom = true
+			}
+		case t.IsPunct(",") && cur.inFrom:
+		default:
+			if t.Kind == Keyword && clauseEnds[strings.ToLower(t.Text)] {
+				cur.inFrom = false
+			}
+			continue
+		}
+		if ref, next, ok := tableRef(toks, i+1); ok {
+			refs = append(refs, ref)
+			i = next - 1
+		}
+	}
+	return refs
+}
+
+// clauseEnds are keywords that end a FROM list.
+var clauseEnds = map[string]bool{
+	"where": true, "group": true, "having": true, "order": true, "limit": true,
+	"offset": true, "window": true, "fetch": true, "for": true, "union": true,
+	"intersect": true, "except": true, "returning": true, "on": true,
+	"using": true, "set": true, "values": true, "select": true,
+}
+
+// tableRef reads "name" or "schema.name" and an optional alias at i,
+// returning the index of the first token after it.
+func tableRef(toks []Token, i int) (TableRef, int, bool) {
+	insert := toks[i-1].Is("INTO")
+	if i < len(toks) && toks[i].Is("ONLY") {
+		i++
+	}
+	if i >= len(toks) || !toks[i].IsName() {
+		return TableRef{}, i, false
+	}
+	ref := TableRef{Name: toks[i].Name(), Pos: toks[i].Pos}
+	i++
+	if i+1 < len(toks) && toks[i].IsPunct(".") && toks[i+1].IsName() {
+		ref.Schema, ref.Name = ref.Name, toks[i+1].Name()
+		i += 2
+	}
+	if i < len(toks) && toks[i].IsPunct("(") {
+		if insert {
+			// INSERT INTO t (columns)
+			return ref, i, true
+		}
+		// a function call in FROM, not a table
+		return TableRef{}, i, false
+	}
+
+	switch {
+	case i+1 < len(toks) && toks[i].Is("AS") && toks[i+1].IsName():
+		ref.Alias = toks[i+1].Name()
+		i += 2
+	case i < len(toks) && (toks[i].Kind == Ident || toks[i].Kind == QuotedIdent):
+		// without AS only plain identifiers are aliases; a keyword here
+		// starts the next clause
+		ref.Alias = toks[i].Name()
+		i++
+	}
+	return ref, i, true
+}
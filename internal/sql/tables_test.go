@@ -0,0 +1,42 @@
+package sql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string // schema.name alias
+	}{
+		{"SELECT * FROM users", []string{"users"}},
+		{"SELECT * FROM public.users u", []string{"public.users u"}},
+		{`SELECT * FROM "Odd Name" AS o`, []string{"Odd Name o"}},
+		{"SELECT * FROM a, b JOIN c ON true", []string{"a", "b", "c"}},
+		{"SELECT * FROM (SELECT * FROM inner_t) s", []string{"inner_t"}},
+		{"SELECT * FROM t WHERE id IN (SELECT id FROM u)", []string{"t", "u"}},
+		{"SELECT extract(year FROM ts) FROM t", []string{"t"}},
+		{"SELECT a IS DISTINCT FROM b FROM t", []string{"t"}},
+		{"UPDATE t SET x = 1", []string{"t"}},
+		{"INSERT INTO t VALUES (1) ON CONFLICT DO UPDATE SET x = 1", []string{"t"}},
+		{"SELECT * FROM t FOR UPDATE", []string{"t"}},
+		{"SELECT * FROM t WHERE f(x", []string{"t"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range Tables(Lex(tt.src)) {
+			s := r.Name
+			if r.Schema != "" {
+				s = r.Schema + "." + s
+			}
+			if r.Alias != "" {
+				s += " " + r.Alias
+			}
+			got = append(got, s)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Tables(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -12,8 +14,19 @@ import (
 	"github.com/joacominatel/minadb/internal/app"
 	"github.com/joacominatel/minadb/internal/config"
 	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/queries"
+	"github.com/joacominatel/minadb/internal/schemadiff"
+	"github.com/joacominatel/minadb/internal/sql"
+	"github.com/joacominatel/minadb/internal/tui/compare"
+	"github.com/joacominatel/minadb/internal/tui/diagram"
 	"github.com/joacominatel/minadb/internal/tui/editor"
 	"github.com/joacominatel/minadb/internal/tui/explorer"
+	"github.com/joacominatel/minadb/internal/tui/filebrowser"
+	"github.com/joacominatel/minadb/internal/tui/historyview"
+	"github.com/joacominatel/minadb/internal/tui/library"
+	"github.com/joacominatel/minadb/internal/tui/paramform"
+	"github.com/joacominatel/minadb/internal/tui/pinned"
 	"github.com/joacominatel/minadb/internal/tui/results"
 	"github.com/joacominatel/minadb/internal/tui/statusbar"
 	"github.com/joacominatel/minadb/internal/tui/theme"
@@ -48,6 +61,23 @@ const (
 	ModeSelectConnection AppMode = iota // show saved connections list
 	ModeConnect                         // manual DSN input
 	ModeMain                            // main TUI
+	ModeCompare                         // schema comparison screen
+	ModeDiagram                         // ER diagram screen
+	ModeHistory                         // query history screen
+	ModeUnlock                          // passphrase prompt for local data
+	ModeLibrary                         // saved query picker
+	ModeParams                          // query parameter form
+	ModeFiles                           // open or save file browser
+	ModePinned                          // pinned results screen
+)
+
+// confirmation is a yes/no question pending in the status bar.
+type confirmation int
+
+const (
+	confirmNone  confirmation = iota
+	confirmQuit               // quit with unsaved files
+	confirmClose              // close a tab with an unsaved file
 )
 
 // Custom messages for async operations.
@@ -57,12 +87,15 @@ type (
 		err error
 	}
 	schemaLoadedMsg struct {
-		tree *app.SchemaTree
-		err  error
+		refresh *app.SchemaRefresh
+		manual  bool
+		err     error
 	}
 	queryExecutedMsg struct {
+		tab    int // id of the tab the query ran in
 		result *database.QueryResult
 		query  string
+		params []history.Param
 		err    error
 	}
 	columnsLoadedMsg struct {
@@ -71,9 +104,55 @@ type (
 		columns []database.Column
 		err     error
 	}
+	// completionColumnsMsg answers an editor.LoadColumnsMsg.
+	completionColumnsMsg struct {
+		schema  string
+		table   string
+		columns []database.Column
+	}
+	columnIndexLoadedMsg struct {
+		columns []database.ColumnRef
+		err     error
+	}
+	// columnsBatchMsg carries one batch of prefetched columns; next
+	// delivers the following batch, and the last message has done set.
+	columnsBatchMsg struct {
+		batch app.ColumnsBatch
+		done  bool
+		err   error
+		next  <-chan columnsBatchMsg
+	}
 	connectionSavedMsg struct {
 		err error
 	}
+	tableInfoLoadedMsg struct {
+		tab  int
+		info *database.TableInfo
+		err  error
+	}
+	schemasComparedMsg struct {
+		diff *schemadiff.Diff
+		err  error
+	}
+	catalogLoadedMsg struct {
+		catalog *database.Catalog
+		err     error
+	}
+	schemaDumpedMsg struct {
+		path string
+		err  error
+	}
+	diffQueryExecutedMsg struct {
+		tab    int
+		result *database.QueryResult
+		label  string
+		err    error
+	}
+	querySavedMsg struct {
+		path string
+		list []queries.Query
+		err  error
+	}
 )
 
 // Model is the top-level bubbletea model orchestrating all components.
@@ -84,6 +163,13 @@ type Model struct {
 	editor     editor.Model
 	results    results.Model
 	statusbar  statusbar.Model
+	compare    compare.Model
+	diagram    diagram.Model
+	history    historyview.Model
+	library    library.Model
+	paramForm  paramform.Model
+	files      filebrowser.Model
+	pinned     pinned.Model
 	connInput  textinput.Model
 	activePane Pane
 	mode       AppMode
@@ -93,6 +179,31 @@ type Model struct {
 	showHelp   bool
 	initialDSN string
 
+	// Local data storage
+	unlockInput textinput.Model
+	unlocking   bool
+	newVault    bool    // the passphrase creates the vault, so it is typed twice
+	firstEntry  string  // the new passphrase as typed the first time
+	nextMode    AppMode // the mode to show once the unlock prompt is done
+	storageErr  error   // why history and cache are not saved, if so
+
+	// Editor tabs; the active one's editor and results are the fields above
+	tabs        []tab
+	activeTab   int
+	nextTab     int // id of the newest tab
+	tabInput    textinput.Model
+	renamingTab bool
+	confirm     confirmation
+
+	// Query parameters given this session, by label
+	paramValues map[string]history.Param
+
+	// Past and pinned results of every tab
+	store *results.Store
+
+	// Column layouts of the results grid, by table or query
+	layouts *results.Layouts
+
 	// Connection selection
 	connCursor int
 	connDSN    string // the DSN used for current connection (for saving)
@@ -112,30 +223,48 @@ func NewModel(service *app.Service, cfg *config.Config, dsn string) Model {
 		mode = ModeSelectConnection
 	}
 
+	ed := editor.New()
+	ed.SetFormatOptions(cfg.Preferences.Format.Options())
+
 	m := Model{
 		service:    service,
 		cfg:        cfg,
 		explorer:   explorer.New(),
-		editor:     editor.New(),
-		results:    results.New(),
+		editor:     ed,
 		statusbar:  statusbar.New(),
 		connInput:  ti,
 		activePane: PaneExplorer,
 		mode:       mode,
 		initialDSN: dsn,
+		tabs:       []tab{{id: 1, name: "Query 1", target: -1}},
+		nextTab:    1,
 	}
+	m.store = results.NewStore(cfg.Preferences.ResultsMemory())
+	m.layouts = results.NewLayouts()
+	m.results = m.newResults()
+	m.openStorage()
 
 	return m
 }
 
+// Close saves what the session leaves behind, for when the program
+// exits.
+func (m Model) Close() error {
+	if data, ok := m.layouts.Save(); ok {
+		return m.service.SaveLayouts(data)
+	}
+	return nil
+}
+
 // Init returns the initial command.
 func (m Model) Init() tea.Cmd {
 	cmds := []tea.Cmd{
 		textinput.Blink,
 	}
 
-	// If a DSN was provided via flag, connect immediately
-	if m.initialDSN != "" {
+	// If a DSN was provided via flag, connect immediately, or once local
+	// data is unlocked
+	if m.initialDSN != "" && m.mode != ModeUnlock {
 		cmds = append(cmds, m.connectCmd(m.initialDSN))
 	}
 
@@ -149,12 +278,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.loadColumnsCmd(schema, table)
 	}
 
+	var cmd tea.Cmd
+
 	// Handle quick query from explorer
 	if qm, ok := msg.(explorer.QuickQueryMsg); ok {
 		m.editor.SetQuery(qm.Query)
-		m.results.SetLoading(true)
-		m.statusbar.SetMessage("Executing query...")
-		return m, m.executeQueryCmd(qm.Query)
+		return m.runQuery(qm.Query, nil)
 	}
 
 	switch msg := msg.(type) {
@@ -166,13 +295,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		// Global keys
+		if m.confirm != confirmNone {
+			return m.updateConfirm(msg)
+		}
 		switch msg.String() {
 		case "ctrl+c":
-			return m, tea.Quit
+			return m.quit()
 		}
 
 		// Help toggle
-		if msg.String() == "?" && m.mode == ModeMain && m.activePane != PaneEditor {
+		if msg.String() == "?" && m.mode == ModeMain && m.activePane != PaneEditor && !m.explorer.Searching() && !m.results.Prompting() && !m.renamingTab {
 			m.showHelp = !m.showHelp
 			return m, nil
 		}
@@ -184,14 +316,49 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Mode-specific key handling
 		switch m.mode {
+		case ModeUnlock:
+			return m.updateUnlock(msg)
 		case ModeSelectConnection:
 			return m.updateSelectConnection(msg)
 		case ModeConnect:
 			return m.updateConnect(msg)
 		case ModeMain:
 			return m.updateMain(msg)
+		case ModeCompare:
+			m.compare, cmd = m.compare.Update(msg)
+			return m, cmd
+		case ModeDiagram:
+			m.diagram, cmd = m.diagram.Update(msg)
+			return m, cmd
+		case ModeHistory:
+			m.history, cmd = m.history.Update(msg)
+			return m, cmd
+		case ModeLibrary:
+			m.library, cmd = m.library.Update(msg)
+			return m, cmd
+		case ModeParams:
+			m.paramForm, cmd = m.paramForm.Update(msg)
+			return m, cmd
+		case ModeFiles:
+			m.files, cmd = m.files.Update(msg)
+			return m, cmd
+		case ModePinned:
+			m.pinned, cmd = m.pinned.Update(msg)
+			return m, cmd
 		}
 
+	case vaultUnlockedMsg:
+		m.unlocking = false
+		if msg.err != nil {
+			m.err = msg.err
+			m.unlockInput.Reset()
+			m.firstEntry = ""
+			return m, nil
+		}
+		m.err = nil
+		m.useVault(msg.vault, nil)
+		return m.leaveUnlock()
+
 	case connectedMsg:
 		if msg.err != nil {
 			m.err = msg.err
@@ -201,13 +368,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.connDSN = msg.dsn
 		m.mode = ModeMain
 		m.err = nil
-		m.explorer.SetLoading(true)
+		// Show the tree from the last session right away and check it
+		// for DDL changes in the background
+		if tree := m.service.CachedSchemaTree(); tree != nil {
+			m.explorer.SetTree(tree)
+			m.explorer.SetRefreshing(true)
+			names := m.service.AllTableNames(tree)
+			m.eachEditor(func(e *editor.Model) { e.SetTableNames(names) })
+		} else {
+			m.explorer.SetLoading(true)
+		}
 		m.statusbar.SetConnected(true, m.service.DatabaseName())
+		m.eachResults(func(r *results.Model) { r.SetConnections(m.connectionNames()) })
 		m.setFocus(PaneExplorer)
 		m.layout()
 
 		// Save connection in background
-		cmds := []tea.Cmd{m.loadSchemaCmd()}
+		cmds := []tea.Cmd{m.refreshSchemaCmd(false)}
 		if msg.dsn != "" {
 			cmds = append(cmds, m.saveConnectionCmd(msg.dsn))
 		}
@@ -217,32 +394,116 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.statusbar.SetMessage("Warning: could not save connection")
 		}
+		m.eachResults(func(r *results.Model) { r.SetConnections(m.connectionNames()) })
 		return m, nil
 
 	case schemaLoadedMsg:
+		m.explorer.SetRefreshing(false)
 		if msg.err != nil {
 			m.err = msg.err
 			m.explorer.SetLoading(false)
 			m.statusbar.SetMessage("Failed to load schema: " + msg.err.Error())
 			return m, nil
 		}
-		m.explorer.SetTree(msg.tree)
-		m.statusbar.SetMessage("")
-		// Cache table names for editor autocompletion
-		tableNames := m.service.AllTableNames(msg.tree)
-		m.editor.SetTableNames(tableNames)
+		refresh := msg.refresh
+		if refresh.Changed {
+			m.explorer.SetTree(refresh.Tree)
+			m.explorer.InvalidateTables(refresh.Stale)
+			// Cache table names for editor autocompletion
+			tableNames := m.service.AllTableNames(refresh.Tree)
+			m.eachEditor(func(e *editor.Model) { e.SetTableNames(tableNames) })
+		}
+		switch {
+		case msg.manual:
+			m.statusbar.SetMessage("Schema reloaded")
+		case refresh.Changed && len(refresh.Stale) > 0:
+			m.statusbar.SetMessage(fmt.Sprintf("Schema changed: %d table(s) updated", len(refresh.Stale)))
+		default:
+			m.statusbar.SetMessage("")
+		}
+		return m, nil
+
+	case editor.LoadColumnsMsg:
+		return m, m.completionColumnsCmd(msg.Schema, msg.Table)
+
+	case completionColumnsMsg:
+		m.eachEditor(func(e *editor.Model) { e.SetColumns(msg.schema, msg.table, msg.columns) })
 		return m, nil
 
+	case explorer.LoadColumnIndexMsg:
+		return m, m.loadColumnIndexCmd()
+
+	case columnIndexLoadedMsg:
+		m.explorer.SetColumnIndex(msg.columns, msg.err)
+		return m, nil
+
+	case explorer.PrefetchColumnsMsg:
+		return m, m.prefetchColumnsCmd(msg.Schema, msg.Tables)
+
+	case columnsBatchMsg:
+		batch := msg.batch
+		for table, columns := range batch.Columns {
+			m.explorer.SetColumns(batch.Schema, table, columns)
+		}
+		if msg.done {
+			m.explorer.SetColumnsProgress(batch.Schema, batch.Total, batch.Total)
+			if msg.err != nil {
+				m.statusbar.SetMessage("Failed to load columns: " + msg.err.Error())
+			}
+			return m, nil
+		}
+		m.explorer.SetColumnsProgress(batch.Schema, batch.Done, batch.Total)
+		return m, waitColumnsBatchCmd(msg.next)
+
+	case explorer.RefreshSchemaMsg:
+		m.statusbar.SetMessage("Reloading schema...")
+		return m, m.refreshSchemaCmd(true)
+
 	case queryExecutedMsg:
-		m.results.SetLoading(false)
+		m.editor.SetHistory(m.service.History())
+		i := m.tabIndex(msg.tab)
+		if i < 0 {
+			return m, nil // the tab was closed while the query ran
+		}
+		t := &m.tabs[i]
+		t.running = false
+		res := m.tabResults(i)
+		res.SetLoading(false)
+		status := ""
+		if i != m.activeTab {
+			status = t.name + ": query finished"
+		}
 		if msg.err != nil {
-			m.results.SetError(msg.err)
-			m.statusbar.SetMessage("")
+			res.SetError(msg.err)
+			if i != m.activeTab {
+				status = t.name + ": query failed"
+			}
+			m.statusbar.SetMessage(status)
 			return m, nil
 		}
-		m.results.SetResult(msg.result)
-		m.results.SetLastQuery(msg.query)
-		m.statusbar.SetMessage("")
+		res.SetResult(msg.result)
+		res.SetLastQuery(msg.query)
+		t.lastParams = msg.params
+		m.statusbar.SetMessage(status)
+		// Row actions (delete, filter) need the source table's keys and
+		// types, which the explorer's connection knows
+		if schema, table, ok := res.SourceTable(); ok && (m.tabDSN(i) == "" || m.tabDSN(i) == m.connDSN) {
+			return m, m.loadTableInfoCmd(msg.tab, schema, table)
+		}
+		return m, nil
+
+	case tableInfoLoadedMsg:
+		// Without metadata row actions are simply unavailable; no need to nag
+		if i := m.tabIndex(msg.tab); i >= 0 && msg.err == nil {
+			m.tabResults(i).SetTableInfo(msg.info)
+		}
+		return m, nil
+
+	case results.ShowPinnedMsg:
+		return m.showPinned()
+
+	case pinned.CloseMsg:
+		m.mode = ModeMain
 		return m, nil
 
 	case results.SetEditorQueryMsg:
@@ -250,6 +511,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.setFocus(PaneEditor)
 		return m, nil
 
+	case results.RunQueryMsg:
+		m.editor.SetQuery(msg.Query)
+		return m.runQuery(msg.Query, m.tabs[m.activeTab].lastParams)
+
+	case results.RerunQueryMsg:
+		if m.tabs[m.activeTab].running {
+			m.statusbar.SetMessage("A query is already running in this tab: open another with Alt+T")
+			return m, nil
+		}
+		return m.startQuery(msg.Query, m.tabs[m.activeTab].lastParams)
+
+	case results.DiffWithConnectionMsg:
+		if msg.Connection < 0 || msg.Connection >= len(m.cfg.Connections) || !sql.ReturnsRows(msg.Query) {
+			return m, nil
+		}
+		return m, m.diffQueryCmd(m.cfg.Connections[msg.Connection], msg.Query, m.tabs[m.activeTab].lastParams)
+
+	case diffQueryExecutedMsg:
+		if i := m.tabIndex(msg.tab); i >= 0 {
+			m.tabResults(i).SetDiffAgainst(msg.result, msg.label, msg.err)
+		}
+		return m, nil
+
 	case results.StatusNotifyMsg:
 		m.statusbar.SetMessage(msg.Message)
 		return m, nil
@@ -263,14 +547,192 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case editor.ExecuteQueryMsg:
-		m.results.SetLoading(true)
-		m.statusbar.SetMessage("Executing query...")
-		return m, m.executeQueryCmd(msg.Query)
+		return m.runQuery(msg.Query, nil)
+
+	case filebrowser.CloseMsg:
+		m.mode = ModeMain
+		return m, nil
+
+	case filebrowser.OpenMsg:
+		return m, openFileCmd(msg.Path)
+
+	case filebrowser.SaveMsg:
+		t := m.tabs[m.activeTab]
+		return m, saveFileCmd(t.id, msg.Path, m.editor.Value())
+
+	case fileOpenedMsg:
+		if msg.err != nil {
+			m.files.SetMessage("Could not open: "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.mode = ModeMain
+		m.loadFile(msg.path, msg.content)
+		m.setFocus(PaneEditor)
+		return m, nil
+
+	case fileSavedMsg:
+		if msg.err != nil {
+			if m.mode == ModeFiles {
+				m.files.SetMessage("Could not save: "+msg.err.Error(), true)
+			} else {
+				m.statusbar.SetMessage("Could not save: " + msg.err.Error())
+			}
+			return m, nil
+		}
+		if i := m.tabIndex(msg.tab); i >= 0 {
+			t := &m.tabs[i]
+			t.path = msg.path
+			t.name = filepath.Base(msg.path)
+			t.saved = msg.content
+		}
+		m.mode = ModeMain
+		m.statusbar.SetMessage("Saved " + msg.path)
+		return m, nil
+
+	case externalEditedMsg:
+		if msg.err != nil {
+			m.statusbar.SetMessage("External editor: " + msg.err.Error())
+			return m, nil
+		}
+		if i := m.tabIndex(msg.tab); i == m.activeTab {
+			m.editor.SetQuery(msg.content)
+		} else if i >= 0 {
+			m.tabs[i].editor.SetQuery(msg.content)
+		}
+		return m, nil
+
+	case paramform.CloseMsg:
+		m.mode = ModeMain
+		return m, nil
+
+	case paramform.RunMsg:
+		m.mode = ModeMain
+		if m.paramValues == nil {
+			m.paramValues = make(map[string]history.Param)
+		}
+		for _, p := range msg.Params {
+			m.paramValues[p.Name] = p
+		}
+		return m.startQuery(msg.Query, msg.Params)
+
+	case explorer.CompareSchemasMsg:
+		if len(m.cfg.Connections) < 2 {
+			m.statusbar.SetMessage("Schema compare needs at least two saved connections")
+			return m, nil
+		}
+		m.compare = compare.New(m.cfg.Connections)
+		m.compare.SetSize(m.width, m.height)
+		m.mode = ModeCompare
+		return m, nil
+
+	case explorer.DiagramMsg:
+		m.diagram = diagram.New(msg.Schema, msg.Table)
+		m.diagram.SetSize(m.width, m.height)
+		m.mode = ModeDiagram
+		// neighbours may live in other schemas, so load them all
+		return m, m.loadCatalogCmd()
+
+	case catalogLoadedMsg:
+		m.diagram.SetCatalog(msg.catalog, msg.err)
+		return m, nil
+
+	case diagram.CloseMsg:
+		m.mode = ModeMain
+		return m, nil
+
+	case explorer.DumpSchemaMsg:
+		m.statusbar.SetMessage("Dumping schema...")
+		return m, m.dumpSchemaCmd(msg.Schema)
+
+	case schemaDumpedMsg:
+		if msg.err != nil {
+			m.statusbar.SetMessage("Schema dump failed: " + msg.err.Error())
+		} else {
+			m.statusbar.SetMessage("Schema written to " + msg.path)
+		}
+		return m, nil
+
+	case compare.RequestMsg:
+		return m, m.compareSchemasCmd(msg.Source, msg.Target)
+
+	case schemasComparedMsg:
+		m.compare.SetDiff(msg.diff, msg.err)
+		return m, nil
+
+	case compare.OpenScriptMsg:
+		m.mode = ModeMain
+		m.editor.SetQuery(msg.Script)
+		m.setFocus(PaneEditor)
+		return m, nil
+
+	case compare.CloseMsg:
+		m.mode = ModeMain
+		return m, nil
+
+	case historyview.CloseMsg:
+		m.mode = ModeMain
+		return m, nil
+
+	case historyview.EditMsg:
+		m.mode = ModeMain
+		m.editor.SetQuery(msg.Query)
+		m.setFocus(PaneEditor)
+		return m, nil
+
+	case historyview.RunMsg:
+		m.mode = ModeMain
+		m.editor.SetQuery(msg.Query)
+		return m.runQuery(msg.Query, msg.Params)
+
+	case library.CloseMsg:
+		m.mode = ModeMain
+		return m, nil
+
+	case library.InsertMsg:
+		m.mode = ModeMain
+		m.setFocus(PaneEditor)
+		m.editor.InsertSnippet(msg.Query.Body)
+		return m, nil
+
+	case library.SaveMsg:
+		return m, m.saveQueryCmd(msg.Query)
+
+	case querySavedMsg:
+		if msg.err != nil {
+			m.library.SetMessage("Could not save: "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.library.CloseForm()
+		m.library.SetQueries(msg.list)
+		m.library.SetMessage("Saved "+msg.path, false)
+		return m, nil
 	}
 
 	// Pass through to active component
-	if m.mode == ModeMain {
+	switch m.mode {
+	case ModeMain:
 		return m.updateComponents(msg)
+	case ModeCompare:
+		m.compare, cmd = m.compare.Update(msg)
+		return m, cmd
+	case ModeDiagram:
+		m.diagram, cmd = m.diagram.Update(msg)
+		return m, cmd
+	case ModeHistory:
+		m.history, cmd = m.history.Update(msg)
+		return m, cmd
+	case ModeLibrary:
+		m.library, cmd = m.library.Update(msg)
+		return m, cmd
+	case ModeParams:
+		m.paramForm, cmd = m.paramForm.Update(msg)
+		return m, cmd
+	case ModeFiles:
+		m.files, cmd = m.files.Update(msg)
+		return m, cmd
+	case ModePinned:
+		m.pinned, cmd = m.pinned.Update(msg)
+		return m, cmd
 	}
 
 	return m, nil
@@ -336,25 +798,84 @@ func (m Model) updateConnect(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.renamingTab {
+		return m.updateRename(msg)
+	}
+	if tm, ok := m.updateTabs(msg); ok {
+		return tm, nil
+	}
+
 	switch msg.String() {
 	case "q":
-		if m.activePane != PaneEditor {
-			return m, tea.Quit
+		if m.activePane != PaneEditor && !m.explorer.Searching() && !m.results.Prompting() {
+			return m.quit()
 		}
+	case "ctrl+o":
+		return m.browseFiles(filebrowser.Open)
+	case "ctrl+s":
+		if m.activePane == PaneEditor && m.editor.CompletionActive() {
+			return m.updateComponents(msg)
+		}
+		return m.saveTab()
+	case "alt+s":
+		return m.browseFiles(filebrowser.Save)
+	case "f4":
+		return m, externalEditCmd(m.tabs[m.activeTab].id, m.editor.Value())
 	case "tab":
 		if m.activePane == PaneEditor && m.editor.CompletionActive() {
 			return m.updateComponents(msg)
 		}
+		if m.activePane == PaneExplorer && m.explorer.Searching() {
+			return m, nil
+		}
+		if m.activePane == PaneResults && m.results.Prompting() {
+			return m.updateComponents(msg)
+		}
 		m.cyclePane()
 		return m, nil
 	case "shift+tab":
+		if m.activePane == PaneEditor && m.editor.CompletionActive() {
+			return m.updateComponents(msg)
+		}
+		if m.activePane == PaneResults && m.results.Prompting() {
+			return m.updateComponents(msg)
+		}
 		m.cyclePaneBack()
 		return m, nil
+	case "f2":
+		m.history = historyview.New(m.service.History())
+		m.history.SetSize(m.width, m.height)
+		m.mode = ModeHistory
+		return m, nil
+	case "f7":
+		return m.showPinned()
+	case "f3":
+		dir, err := config.QueriesDir(m.cfg.Preferences)
+		if err != nil {
+			m.statusbar.SetMessage("Saved queries: " + err.Error())
+			return m, nil
+		}
+		list, err := queries.Load(dir)
+		m.library = library.New(list, dir, m.editor.Value())
+		m.library.SetSize(m.width, m.height)
+		if err != nil {
+			m.library.SetMessage("Could not read saved queries: "+err.Error(), true)
+		}
+		m.mode = ModeLibrary
+		return m, nil
 	}
 
 	return m.updateComponents(msg)
 }
 
+// showPinned opens the pinned results screen.
+func (m Model) showPinned() (tea.Model, tea.Cmd) {
+	m.pinned = pinned.New(m.store)
+	m.pinned.SetSize(m.width, m.height)
+	m.mode = ModePinned
+	return m, nil
+}
+
 func (m Model) updateComponents(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
@@ -424,8 +945,12 @@ func (m *Model) layout() {
 	}
 	resultsHeight := availHeight - editorHeight - 1
 
+	m.compare.SetSize(m.width, m.height)
+	m.diagram.SetSize(m.width, m.height)
+	m.pinned.SetSize(m.width, m.height)
 	m.explorer.SetSize(explorerWidth, availHeight)
-	m.editor.SetSize(rightWidth, editorHeight)
+	// the tab strip takes a line from the editor
+	m.editor.SetSize(rightWidth, editorHeight-1)
 	m.results.SetSize(rightWidth, resultsHeight)
 	m.statusbar.SetWidth(m.width)
 }
@@ -454,23 +979,60 @@ func (m Model) saveConnectionCmd(dsn string) tea.Cmd {
 	}
 }
 
-func (m Model) loadSchemaCmd() tea.Cmd {
+// refreshSchemaCmd brings the schema tree up to date. Without force only
+// the schemas whose DDL changed since the cached snapshot are reloaded.
+func (m Model) refreshSchemaCmd(force bool) tea.Cmd {
 	service := m.service
 	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 		defer cancel()
-		tree, err := service.LoadSchemaTree(ctx)
-		return schemaLoadedMsg{tree: tree, err: err}
+		refresh, err := service.RefreshSchemaTree(ctx, force)
+		return schemaLoadedMsg{refresh: refresh, manual: force, err: err}
 	}
 }
 
-func (m Model) executeQueryCmd(query string) tea.Cmd {
+// runQuery runs query, first asking for the values of its parameters
+// when it has any. The form offers the values in given, then those last
+// used for each parameter this session or in the history.
+func (m Model) runQuery(query string, given []history.Param) (tea.Model, tea.Cmd) {
+	if m.tabs[m.activeTab].running {
+		m.statusbar.SetMessage("A query is already running in this tab: open another with Alt+T")
+		return m, nil
+	}
+	params := sql.Parameters(query)
+	if len(params) == 0 {
+		return m.startQuery(query, nil)
+	}
+
+	last := history.LastParams(m.service.History())
+	for name, p := range m.paramValues {
+		last[name] = p
+	}
+	for _, p := range given {
+		last[p.Name] = p
+	}
+	m.paramForm = paramform.New(query, params, last)
+	m.paramForm.SetSize(m.width, m.height)
+	m.mode = ModeParams
+	return m, nil
+}
+
+// startQuery runs query in the active tab, on the tab's connection.
+// Tabs run their queries independently of each other.
+func (m Model) startQuery(query string, params []history.Param) (tea.Model, tea.Cmd) {
+	m.tabs[m.activeTab].running = true
+	m.results.SetLoading(true)
+	m.statusbar.SetMessage("Executing query...")
+	return m, m.executeQueryCmd(m.tabs[m.activeTab].id, m.tabDSN(m.activeTab), query, params)
+}
+
+func (m Model) executeQueryCmd(tab int, dsn, query string, params []history.Param) tea.Cmd {
 	service := m.service
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		result, err := service.ExecuteQuery(ctx, query)
-		return queryExecutedMsg{result: result, query: query, err: err}
+		result, err := service.ExecuteQueryAt(ctx, dsn, query, params...)
+		return queryExecutedMsg{tab: tab, result: result, query: query, params: params, err: err}
 	}
 }
 
@@ -484,6 +1046,143 @@ func (m Model) loadColumnsCmd(schema, table string) tea.Cmd {
 	}
 }
 
+// completionColumnsCmd fetches columns for editor completion. Failures
+// are not reported: completion just has nothing to offer for the table.
+func (m Model) completionColumnsCmd(schema, table string) tea.Cmd {
+	service := m.service
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		columns, _ := service.ResolveColumns(ctx, schema, table)
+		return completionColumnsMsg{schema: schema, table: table, columns: columns}
+	}
+}
+
+func (m Model) loadColumnIndexCmd() tea.Cmd {
+	service := m.service
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		columns, err := service.ColumnIndex(ctx)
+		return columnIndexLoadedMsg{columns: columns, err: err}
+	}
+}
+
+// prefetchColumnsCmd loads the columns of a schema's tables in concurrent
+// batches, delivering each batch to the explorer as it arrives.
+func (m Model) prefetchColumnsCmd(schema string, tables []string) tea.Cmd {
+	service := m.service
+	return func() tea.Msg {
+		ch := make(chan columnsBatchMsg, 1)
+		go func() {
+			defer close(ch)
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+			err := service.PrefetchColumns(ctx, schema, tables, func(b app.ColumnsBatch) {
+				ch <- columnsBatchMsg{batch: b, next: ch}
+			})
+			ch <- columnsBatchMsg{batch: app.ColumnsBatch{Schema: schema, Total: len(tables)}, done: true, err: err}
+		}()
+		return <-ch
+	}
+}
+
+func waitColumnsBatchCmd(ch <-chan columnsBatchMsg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
+
+func (m Model) compareSchemasCmd(source, target config.Connection) tea.Cmd {
+	service := m.service
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		diff, err := service.CompareSchemas(ctx, source.DSN(), target.DSN(), nil)
+		return schemasComparedMsg{diff: diff, err: err}
+	}
+}
+
+func (m Model) loadCatalogCmd() tea.Cmd {
+	service := m.service
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		cat, err := service.LoadCatalog(ctx, nil)
+		return catalogLoadedMsg{catalog: cat, err: err}
+	}
+}
+
+// dumpSchemaCmd writes the DDL to a file in the working directory. The name
+// carries no timestamp so repeated dumps overwrite it, ready to commit.
+func (m Model) dumpSchemaCmd(schema string) tea.Cmd {
+	service := m.service
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		var schemas []string
+		path := service.DatabaseName() + "_schema.sql"
+		if schema != "" {
+			schemas = []string{schema}
+			path = service.DatabaseName() + "_" + schema + "_schema.sql"
+		}
+		script, err := service.DumpSchema(ctx, schemas)
+		if err != nil {
+			return schemaDumpedMsg{err: err}
+		}
+		if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+			return schemaDumpedMsg{err: err}
+		}
+		return schemaDumpedMsg{path: path}
+	}
+}
+
+func (m Model) saveQueryCmd(q queries.Query) tea.Cmd {
+	prefs := m.cfg.Preferences
+	return func() tea.Msg {
+		dir, err := config.QueriesDir(prefs)
+		if err != nil {
+			return querySavedMsg{err: err}
+		}
+		path, err := queries.Save(dir, q)
+		if err != nil {
+			return querySavedMsg{err: err}
+		}
+		list, err := queries.Load(dir)
+		return querySavedMsg{path: path, list: list, err: err}
+	}
+}
+
+func (m Model) diffQueryCmd(conn config.Connection, query string, params []history.Param) tea.Cmd {
+	service := m.service
+	tab := m.tabs[m.activeTab].id
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		result, err := service.ExecuteQueryAt(ctx, conn.DSN(), query, params...)
+		return diffQueryExecutedMsg{tab: tab, result: result, label: conn.Name, err: err}
+	}
+}
+
+func (m Model) connectionNames() []string {
+	names := make([]string, len(m.cfg.Connections))
+	for i, c := range m.cfg.Connections {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func (m Model) loadTableInfoCmd(tab int, schema, table string) tea.Cmd {
+	service := m.service
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		info, err := service.LoadTableInfo(ctx, schema, table)
+		return tableInfoLoadedMsg{tab: tab, info: info, err: err}
+	}
+}
+
 // View renders the entire application.
 func (m Model) View() string {
 	if m.showHelp {
@@ -491,10 +1190,26 @@ func (m Model) View() string {
 	}
 
 	switch m.mode {
+	case ModeUnlock:
+		return m.viewUnlock()
 	case ModeSelectConnection:
 		return m.viewSelectConnection()
 	case ModeConnect:
 		return m.viewConnect()
+	case ModeCompare:
+		return m.compare.View()
+	case ModeDiagram:
+		return m.diagram.View()
+	case ModeHistory:
+		return m.history.View()
+	case ModeLibrary:
+		return m.library.View()
+	case ModeParams:
+		return m.paramForm.View()
+	case ModeFiles:
+		return m.files.View()
+	case ModePinned:
+		return m.pinned.View()
 	default:
 		return m.viewMain()
 	}
@@ -556,6 +1271,9 @@ func (m Model) viewSelectConnection() string {
 	if errMsg != "" {
 		parts = append(parts, errMsg)
 	}
+	if warning := m.storageWarning(); warning != "" {
+		parts = append(parts, "", warning)
+	}
 	parts = append(parts, "", hints)
 
 	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
@@ -601,6 +1319,7 @@ func (m Model) viewConnect() string {
 		prompt,
 		"  "+m.connInput.View(),
 		errMsg,
+		m.storageWarning(),
 		"",
 		hint,
 	)
@@ -648,7 +1367,10 @@ func (m Model) viewMain() string {
 	editorView := editorBorder.
 		Width(rightWidth - 2).
 		Height(editorHeight).
-		Render(m.editor.View())
+		Render(lipgloss.JoinVertical(lipgloss.Left,
+			m.viewTabs(rightWidth-2),
+			m.editor.View(),
+		))
 
 	resultsBorder := theme.StyleBorder
 	if m.activePane == PaneResults {
@@ -699,23 +1421,47 @@ func (m Model) viewHelp() string {
 		keyStyle.Render("  q / Ctrl+C")+"    "+descStyle.Render("Quit application"),
 		keyStyle.Render("  Tab")+"           "+descStyle.Render("Switch between panes"),
 		keyStyle.Render("  Shift+Tab")+"     "+descStyle.Render("Switch panes (reverse)"),
+		keyStyle.Render("  F2")+"            "+descStyle.Render("Query history"),
+		keyStyle.Render("  F3")+"            "+descStyle.Render("Saved queries and snippets"),
+		keyStyle.Render("  F7")+"            "+descStyle.Render("Pinned results"),
 		keyStyle.Render("  ?")+"             "+descStyle.Render("Toggle this help"),
 		"",
 		sectionStyle.Render("Explorer"),
 		keyStyle.Render("  ↑/k  ↓/j")+"     "+descStyle.Render("Navigate up/down"),
 		keyStyle.Render("  Enter/→/l")+"     "+descStyle.Render("Expand item"),
 		keyStyle.Render("  ←/h")+"           "+descStyle.Render("Collapse item"),
+		keyStyle.Render("  /")+"             "+descStyle.Render("Find table, view, column or function"),
 		keyStyle.Render("  s")+"             "+descStyle.Render("Quick SELECT * LIMIT 100"),
 		keyStyle.Render("  d")+"             "+descStyle.Render("Count rows"),
+		keyStyle.Render("  C")+"             "+descStyle.Render("Compare schemas of two connections"),
+		keyStyle.Render("  E")+"             "+descStyle.Render("ER diagram of table or schema"),
+		keyStyle.Render("  X")+"             "+descStyle.Render("Dump schema DDL to a .sql file"),
+		keyStyle.Render("  r")+"             "+descStyle.Render("Reload schema tree"),
 		"",
 		sectionStyle.Render("Editor"),
-		keyStyle.Render("  Ctrl+E / F5")+"   "+descStyle.Render("Execute query"),
+		keyStyle.Render("  Ctrl+E / F5")+"   "+descStyle.Render("Execute selection, or the whole query"),
+		keyStyle.Render("  Alt+Enter/F6")+"  "+descStyle.Render("Execute statement under cursor"),
+		keyStyle.Render("  Shift+Arrows")+"  "+descStyle.Render("Select text (Ctrl+Shift+←/→ by word)"),
+		keyStyle.Render("  $1 :name")+"      "+descStyle.Render("Parameters: asks for values, Ctrl+T cycles type"),
+		"",
+		sectionStyle.Render("Tabs"),
+		keyStyle.Render("  Alt+T / Alt+W")+" "+descStyle.Render("New/close tab"),
+		keyStyle.Render("  Alt+[/]")+"       "+descStyle.Render("Previous/next tab (Alt+1-9 jumps)"),
+		keyStyle.Render("  Alt+R")+"         "+descStyle.Render("Rename tab"),
+		keyStyle.Render("  Alt+C")+"         "+descStyle.Render("Cycle the connection the tab runs on"),
+		"",
+		sectionStyle.Render("Files"),
+		keyStyle.Render("  Ctrl+O")+"        "+descStyle.Render("Open a .sql file"),
+		keyStyle.Render("  Ctrl+S/Alt+S")+"  "+descStyle.Render("Save / save as"),
+		keyStyle.Render("  F4")+"            "+descStyle.Render("Edit in $EDITOR"),
 		keyStyle.Render("  Ctrl+K")+"        "+descStyle.Render("Clear editor"),
-		keyStyle.Render("  Ctrl+L")+"        "+descStyle.Render("Format query (uppercase keywords)"),
+		keyStyle.Render("  Ctrl+L")+"        "+descStyle.Render("Format query"),
+		keyStyle.Render("  Ctrl+R")+"        "+descStyle.Render("Search query history"),
 		keyStyle.Render("  Auto")+"          "+descStyle.Render("Keywords uppercase on space/newline/;"),
 		keyStyle.Render("  Ctrl+Space")+"    "+descStyle.Render("Open autocomplete"),
 		keyStyle.Render("  ↑/↓ Enter Tab")+" "+descStyle.Render("Navigate/accept completion"),
 		keyStyle.Render("  Esc")+"           "+descStyle.Render("Cancel completion"),
+		keyStyle.Render("  Tab/Shift+Tab")+" "+descStyle.Render("Next/previous snippet placeholder"),
 		"",
 		sectionStyle.Render("Results"),
 		keyStyle.Render("  ↑/k  ↓/j")+"     "+descStyle.Render("Move row cursor"),
@@ -724,11 +1470,30 @@ func (m Model) viewHelp() string {
 		keyStyle.Render("  Home/End")+"      "+descStyle.Render("First/last column"),
 		keyStyle.Render("  g / G")+"         "+descStyle.Render("First/last row"),
 		keyStyle.Render("  Enter")+"         "+descStyle.Render("Record detail view"),
+		keyStyle.Render("  /")+"             "+descStyle.Render("Search cells (Tab: this column, ^R regex, ^T case)"),
+		keyStyle.Render("  n / N")+"         "+descStyle.Render("Next/previous match"),
+		keyStyle.Render("  s / S")+"         "+descStyle.Render("Sort by column / add to a multi-column sort"),
+		keyStyle.Render("  C")+"             "+descStyle.Render("Show, hide, reorder and freeze columns"),
+		keyStyle.Render("  H")+"             "+descStyle.Render("Hide column"),
+		keyStyle.Render("  { / }")+"         "+descStyle.Render("Move column left/right"),
+		keyStyle.Render("  z")+"             "+descStyle.Render("Freeze columns up to this one / unfreeze"),
+		keyStyle.Render("  + / -")+"         "+descStyle.Render("Widen/narrow column"),
+		keyStyle.Render("  a / A")+"         "+descStyle.Render("Fit column to its values / reset widths"),
 		keyStyle.Render("  c")+"             "+descStyle.Render("Copy cell value"),
 		keyStyle.Render("  y")+"             "+descStyle.Render("Copy row (JSON/CSV/Text)"),
 		keyStyle.Render("  f")+"             "+descStyle.Render("Filter by current value"),
+		keyStyle.Render("  |")+"             "+descStyle.Render("Filter loaded rows: amount > 100 and status != 'done', email ~ /gmail/, is null"),
 		keyStyle.Render("  e")+"             "+descStyle.Render("Export results (JSON/CSV)"),
-		keyStyle.Render("  D")+"             "+descStyle.Render("Delete record"),
+		keyStyle.Render("  Space")+"         "+descStyle.Render("Mark/unmark row"),
+		keyStyle.Render("  Esc")+"           "+descStyle.Render("Clear marks and search"),
+		keyStyle.Render("  D")+"             "+descStyle.Render("Delete record(s) by primary key"),
+		keyStyle.Render("  F")+"             "+descStyle.Render("Open row referenced by foreign key"),
+		keyStyle.Render("  R")+"             "+descStyle.Render("List rows referencing this row"),
+		keyStyle.Render("  B")+"             "+descStyle.Render("Save result as diff baseline"),
+		keyStyle.Render("  =")+"             "+descStyle.Render("Diff against baseline or another connection"),
+		keyStyle.Render("  [ / ]")+"         "+descStyle.Render("Back/forward through visited results"),
+		keyStyle.Render("  < / >")+"         "+descStyle.Render("Older/newer result of this tab"),
+		keyStyle.Render("  p / P")+"         "+descStyle.Render("Pin result / show pinned results"),
 		"",
 		theme.StyleMuted.Render("Press any key to close"),
 	)
@@ -0,0 +1,476 @@
+// Package compare is the schema comparison screen: pick two saved
+// connections, browse the differences and export a migration script.
+package compare
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/config"
+	"github.com/joacominatel/minadb/internal/schemadiff"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// RequestMsg asks the app to compare two connections.
+type RequestMsg struct {
+	Source config.Connection
+	Target config.Connection
+}
+
+// CloseMsg asks the app to leave the comparison screen.
+type CloseMsg struct{}
+
+// OpenScriptMsg asks the app to load the migration script into the editor.
+type OpenScriptMsg struct {
+	Script string
+}
+
+type scriptWrittenMsg struct {
+	path string
+	err  error
+}
+
+type step int
+
+const (
+	stepSource step = iota
+	stepTarget
+	stepLoading
+	stepDiff
+	stepScript
+)
+
+// Model is the schema comparison component.
+type Model struct {
+	conns  []config.Connection
+	step   step
+	cursor int
+	source int
+	target int
+
+	diff   *schemadiff.Diff
+	script string
+	err    error
+
+	selected     int // change under the cursor
+	listScroll   int
+	detailScroll int
+	scriptScroll int
+
+	width         int
+	height        int
+	statusMessage string
+}
+
+// New creates a comparison screen over the saved connections.
+func New(conns []config.Connection) Model {
+	return Model{conns: conns}
+}
+
+// SetSize updates the component dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+// SetDiff shows the result of a comparison.
+func (m *Model) SetDiff(diff *schemadiff.Diff, err error) {
+	m.err = err
+	m.diff = diff
+	m.script = ""
+	if diff != nil {
+		m.script = diff.MigrationScript()
+	}
+	m.selected = 0
+	m.listScroll = 0
+	m.detailScroll = 0
+	m.scriptScroll = 0
+	m.step = stepDiff
+}
+
+// Init returns the initial command (none).
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the comparison screen.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case scriptWrittenMsg:
+		if msg.err != nil {
+			m.statusMessage = "Write failed: " + msg.err.Error()
+		} else {
+			m.statusMessage = "Migration script written to " + msg.path
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		m.statusMessage = ""
+		switch m.step {
+		case stepSource, stepTarget:
+			return m.updatePick(msg)
+		case stepLoading:
+			if msg.String() == "esc" {
+				return m, closeCmd
+			}
+		case stepDiff:
+			return m.updateDiff(msg)
+		case stepScript:
+			return m.updateScript(msg)
+		}
+	}
+	return m, nil
+}
+
+func closeCmd() tea.Msg {
+	return CloseMsg{}
+}
+
+func (m Model) updatePick(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		if m.step == stepTarget {
+			m.step = stepSource
+			m.cursor = m.source
+			return m, nil
+		}
+		return m, closeCmd
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.conns)-1 {
+			m.cursor++
+		}
+	case "enter":
+		if len(m.conns) == 0 {
+			return m, nil
+		}
+		if m.step == stepSource {
+			m.source = m.cursor
+			m.step = stepTarget
+			return m, nil
+		}
+		m.target = m.cursor
+		return m, m.compare()
+	}
+	return m, nil
+}
+
+func (m *Model) compare() tea.Cmd {
+	m.step = stepLoading
+	m.err = nil
+	req := RequestMsg{Source: m.conns[m.source], Target: m.conns[m.target]}
+	return func() tea.Msg { return req }
+}
+
+func (m Model) updateDiff(msg tea.KeyMsg) (Model, tea.Cmd) {
+	count := 0
+	if m.diff != nil {
+		count = len(m.diff.Changes)
+	}
+
+	switch msg.String() {
+	case "esc", "q":
+		return m, closeCmd
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+			m.detailScroll = 0
+		}
+	case "down", "j":
+		if m.selected < count-1 {
+			m.selected++
+			m.detailScroll = 0
+		}
+	case "g":
+		m.selected = 0
+		m.detailScroll = 0
+	case "G":
+		m.selected = max(0, count-1)
+		m.detailScroll = 0
+	case "J", "pgdown":
+		m.detailScroll++
+	case "K", "pgup":
+		if m.detailScroll > 0 {
+			m.detailScroll--
+		}
+	case "r":
+		return m, m.compare()
+	case "s", "enter":
+		if m.diff != nil {
+			m.step = stepScript
+			m.scriptScroll = 0
+		}
+	default:
+		return m.scriptAction(msg)
+	}
+	m.ensureListWindow()
+	return m, nil
+}
+
+func (m Model) updateScript(msg tea.KeyMsg) (Model, tea.Cmd) {
+	lines := strings.Count(m.script, "\n")
+	switch msg.String() {
+	case "esc", "q":
+		m.step = stepDiff
+	case "up", "k":
+		if m.scriptScroll > 0 {
+			m.scriptScroll--
+		}
+	case "down", "j":
+		if m.scriptScroll < lines-1 {
+			m.scriptScroll++
+		}
+	case "pgup":
+		m.scriptScroll = max(0, m.scriptScroll-m.bodyHeight())
+	case "pgdown":
+		m.scriptScroll = max(0, min(lines-1, m.scriptScroll+m.bodyHeight()))
+	case "g":
+		m.scriptScroll = 0
+	case "G":
+		m.scriptScroll = max(0, lines-m.bodyHeight())
+	default:
+		return m.scriptAction(msg)
+	}
+	return m, nil
+}
+
+// scriptAction handles the export keys shared by the diff and script views.
+func (m Model) scriptAction(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if m.diff == nil {
+		return m, nil
+	}
+	switch msg.String() {
+	case "w":
+		m.statusMessage = "Writing script..."
+		return m, writeScriptCmd(m.script)
+	case "y":
+		if err := clipboard.WriteAll(m.script); err != nil {
+			m.statusMessage = "Copy failed: " + err.Error()
+		} else {
+			m.statusMessage = "Migration script copied"
+		}
+	case "e":
+		script := m.script
+		return m, func() tea.Msg { return OpenScriptMsg{Script: script} }
+	}
+	return m, nil
+}
+
+func writeScriptCmd(script string) tea.Cmd {
+	return func() tea.Msg {
+		path := fmt.Sprintf("minadb_migration_%s.sql", time.Now().Format("20060102_150405"))
+		err := os.WriteFile(path, []byte(script), 0o644)
+		return scriptWrittenMsg{path: path, err: err}
+	}
+}
+
+func (m Model) bodyHeight() int {
+	return max(1, m.height-5)
+}
+
+func (m *Model) ensureListWindow() {
+	visible := m.bodyHeight()
+	if m.selected < m.listScroll {
+		m.listScroll = m.selected
+	}
+	if m.selected >= m.listScroll+visible {
+		m.listScroll = m.selected - visible + 1
+	}
+}
+
+// ── View ────────────────────────────────────────────────────────────────
+
+// View renders the comparison screen.
+func (m Model) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(0, 1)
+
+	var body string
+	switch m.step {
+	case stepSource, stepTarget:
+		body = m.viewPick()
+	case stepLoading:
+		body = theme.StyleMuted.Render(fmt.Sprintf("  Comparing %s → %s...",
+			m.conns[m.source].Name, m.conns[m.target].Name))
+	case stepDiff:
+		body = m.viewDiff()
+	case stepScript:
+		body = m.viewScript()
+	}
+
+	footer := m.footer()
+	if m.statusMessage != "" {
+		footer = theme.StyleSuccess.Render("  "+m.statusMessage) + "  " + footer
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Schema Compare")+"  "+theme.StyleMuted.Render(m.heading()),
+		"",
+		body,
+		"",
+		footer,
+	)
+}
+
+func (m Model) heading() string {
+	switch m.step {
+	case stepSource:
+		return "choose the source (desired schema)"
+	case stepTarget:
+		return "choose the target (schema to migrate)"
+	}
+	s := m.conns[m.source].Name + " → " + m.conns[m.target].Name
+	if m.diff != nil {
+		s += fmt.Sprintf("  %d difference(s)", len(m.diff.Changes))
+	}
+	return s
+}
+
+func (m Model) footer() string {
+	var hints string
+	switch m.step {
+	case stepSource:
+		hints = "↑/↓ navigate | Enter select | Esc close"
+	case stepTarget:
+		hints = "↑/↓ navigate | Enter compare | Esc back"
+	case stepLoading:
+		hints = "Esc close"
+	case stepDiff:
+		hints = "↑/↓ change | J/K scroll detail | s script | w write | y copy | e editor | r rerun | Esc close"
+	case stepScript:
+		hints = "↑/↓ scroll | w write | y copy | e editor | Esc back"
+	}
+	return theme.StyleMuted.Render("  " + hints)
+}
+
+func (m Model) viewPick() string {
+	if len(m.conns) == 0 {
+		return theme.StyleMuted.Render("  No saved connections to compare")
+	}
+	var items []string
+	for i, conn := range m.conns {
+		label := conn.Name + " (" + conn.DisplayString() + ")"
+		switch {
+		case i == m.cursor:
+			items = append(items, lipgloss.NewStyle().
+				Foreground(theme.ColorHighlight).
+				Bold(true).
+				Render("> "+label))
+		case m.step == stepTarget && i == m.source:
+			items = append(items, theme.StyleMuted.Render("  "+label+"  [source]"))
+		default:
+			items = append(items, "  "+label)
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, items...)
+}
+
+func (m Model) viewDiff() string {
+	if m.err != nil {
+		return theme.StyleError.Render("  Error: " + m.err.Error())
+	}
+	if m.diff == nil || m.diff.Empty() {
+		return theme.StyleSuccess.Render("  Schemas are identical")
+	}
+
+	listWidth := max(30, m.width*2/5)
+	detailWidth := max(20, m.width-listWidth-3)
+	height := m.bodyHeight()
+
+	var list []string
+	for i := m.listScroll; i < len(m.diff.Changes) && i < m.listScroll+height; i++ {
+		c := m.diff.Changes[i]
+		line := widget.Truncate(c.Summary(), listWidth-2)
+		style := changeStyle(c.Kind)
+		if i == m.selected {
+			style = style.Background(lipgloss.Color("236")).Bold(true)
+			line = "> " + line
+		} else {
+			line = "  " + line
+		}
+		list = append(list, style.Render(line))
+	}
+
+	listView := lipgloss.NewStyle().Width(listWidth).Height(height).Render(strings.Join(list, "\n"))
+	detailView := lipgloss.NewStyle().
+		Width(detailWidth).
+		Height(height).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true).
+		BorderForeground(theme.ColorBorder).
+		PaddingLeft(1).
+		Render(m.viewDetail(detailWidth-2, height))
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, listView, detailView)
+}
+
+func (m Model) viewDetail(width, height int) string {
+	c := m.diff.Changes[m.selected]
+
+	var lines []string
+	lines = append(lines, lipgloss.NewStyle().Bold(true).Render(c.Object.String()+" "+c.Path()))
+	switch c.Kind {
+	case schemadiff.Added:
+		lines = append(lines, theme.StyleMuted.Render("only in "+m.conns[m.source].Name))
+	case schemadiff.Removed:
+		lines = append(lines, theme.StyleMuted.Render("only in "+m.conns[m.target].Name))
+	default:
+		lines = append(lines, theme.StyleMuted.Render("- "+m.conns[m.target].Name+"  + "+m.conns[m.source].Name))
+	}
+	lines = append(lines, "")
+
+	for _, l := range schemadiff.DiffLines(c.Target, c.Source) {
+		text := widget.Truncate(l.Text, width-2)
+		switch l.Op {
+		case schemadiff.LineAdded:
+			lines = append(lines, theme.StyleSuccess.Render("+ "+text))
+		case schemadiff.LineRemoved:
+			lines = append(lines, theme.StyleError.Render("- "+text))
+		default:
+			lines = append(lines, "  "+text)
+		}
+	}
+
+	start := min(m.detailScroll, max(0, len(lines)-1))
+	end := min(len(lines), start+height)
+	return strings.Join(lines[start:end], "\n")
+}
+
+func (m Model) viewScript() string {
+	lines := strings.Split(m.script, "\n")
+	height := m.bodyHeight()
+	start := min(m.scriptScroll, max(0, len(lines)-1))
+	end := min(len(lines), start+height)
+
+	var out []string
+	for _, l := range lines[start:end] {
+		l = widget.Truncate(l, m.width-2)
+		if strings.HasPrefix(l, "--") {
+			l = theme.StyleMuted.Render(l)
+		}
+		out = append(out, " "+l)
+	}
+	return strings.Join(out, "\n")
+}
+
+func changeStyle(kind schemadiff.ChangeKind) lipgloss.Style {
+	switch kind {
+	case schemadiff.Added:
+		return lipgloss.NewStyle().Foreground(theme.ColorSuccess)
+	case schemadiff.Removed:
+		return lipgloss.NewStyle().Foreground(theme.ColorError)
+	default:
+		return lipgloss.NewStyle().Foreground(theme.ColorWarning)
+	}
+}
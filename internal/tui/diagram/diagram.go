@@ -0,0 +1,306 @@
+// Package diagram is the ER diagram screen: a text drawing of one table
+// and its neighbours, with export to Mermaid, DOT and PlantUML files.
+package diagram
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/erd"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+)
+
+// CloseMsg asks the app to leave the diagram screen.
+type CloseMsg struct{}
+
+type exportedMsg struct {
+	path string
+	err  error
+}
+
+// Model is the ER diagram component.
+type Model struct {
+	schema string
+	focus  string // schema.name of the table in the middle
+	whole  bool   // export the whole schema instead of the neighbourhood
+
+	catalog *database.Catalog
+	err     error
+	loading bool
+
+	lines     []string
+	neighbors []string // tables around the focus, for moving to them
+	selected  int      // neighbour under the cursor, -1 for none
+	history   []string // previous focus tables
+	scrollY   int
+	scrollX   int
+
+	width         int
+	height        int
+	statusMessage string
+}
+
+// New creates a diagram screen centred on a table. An empty table starts
+// on the first table of the schema and exports the whole schema.
+func New(schema, table string) Model {
+	m := Model{schema: schema, loading: true, selected: -1}
+	if table == "" {
+		m.whole = true
+	} else {
+		m.focus = schema + "." + table
+	}
+	return m
+}
+
+// SetSize updates the component dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.redraw()
+}
+
+// SetCatalog provides the metadata the diagram is drawn from.
+func (m *Model) SetCatalog(cat *database.Catalog, err error) {
+	m.loading = false
+	m.catalog = cat
+	m.err = err
+	if cat != nil && m.focus == "" {
+		for _, t := range cat.Tables {
+			if t.Schema == m.schema {
+				m.focus = t.Schema + "." + t.Name
+				break
+			}
+		}
+	}
+	m.redraw()
+}
+
+// redraw renders the neighbourhood of the focus table.
+func (m *Model) redraw() {
+	m.lines = nil
+	m.neighbors = nil
+	if m.catalog == nil || m.focus == "" {
+		return
+	}
+	schema, table, _ := strings.Cut(m.focus, ".")
+	d := erd.Neighbourhood(m.catalog, schema, table)
+	m.lines = strings.Split(strings.TrimRight(d.ASCII(m.focus, max(40, m.width-4)), "\n"), "\n")
+	for _, t := range d.Tables {
+		if t.Key() != m.focus {
+			m.neighbors = append(m.neighbors, t.Key())
+		}
+	}
+	if m.selected >= len(m.neighbors) {
+		m.selected = -1
+	}
+}
+
+// exportDiagram returns the diagram for the current export scope.
+func (m Model) exportDiagram() *erd.Diagram {
+	if m.whole {
+		return erd.Build(m.catalog, func(schema, _ string) bool { return schema == m.schema })
+	}
+	schema, table, _ := strings.Cut(m.focus, ".")
+	return erd.Neighbourhood(m.catalog, schema, table)
+}
+
+func (m Model) exportName() string {
+	if m.whole {
+		return m.schema + "_erd"
+	}
+	return strings.ReplaceAll(m.focus, ".", "_") + "_erd"
+}
+
+// Init returns the initial command (none).
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the diagram screen.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case exportedMsg:
+		if msg.err != nil {
+			m.statusMessage = "Export failed: " + msg.err.Error()
+		} else {
+			m.statusMessage = "Diagram written to " + msg.path
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		m.statusMessage = ""
+		return m.updateKeys(msg)
+	}
+	return m, nil
+}
+
+func (m Model) updateKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		return m, func() tea.Msg { return CloseMsg{} }
+	}
+	if m.catalog == nil {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		m.scrollY = max(0, m.scrollY-1)
+	case "down", "j":
+		m.scrollY = max(0, min(len(m.lines)-m.bodyHeight(), m.scrollY+1))
+	case "left", "h":
+		m.scrollX = max(0, m.scrollX-4)
+	case "right", "l":
+		m.scrollX += 4
+	case "tab":
+		if len(m.neighbors) > 0 {
+			m.selected = (m.selected + 1) % len(m.neighbors)
+		}
+	case "shift+tab":
+		if len(m.neighbors) > 0 {
+			m.selected = (m.selected - 1 + len(m.neighbors)) % len(m.neighbors)
+		}
+	case "enter":
+		if m.selected >= 0 && m.selected < len(m.neighbors) {
+			m.history = append(m.history, m.focus)
+			m.focus = m.neighbors[m.selected]
+			m.selected = -1
+			m.scrollY, m.scrollX = 0, 0
+			m.redraw()
+		}
+	case "backspace":
+		if n := len(m.history); n > 0 {
+			m.focus = m.history[n-1]
+			m.history = m.history[:n-1]
+			m.selected = -1
+			m.scrollY, m.scrollX = 0, 0
+			m.redraw()
+		}
+	case "a":
+		m.whole = !m.whole
+	case "m":
+		return m, m.exportCmd(erd.FormatMermaid)
+	case "d":
+		return m, m.exportCmd(erd.FormatDOT)
+	case "p":
+		return m, m.exportCmd(erd.FormatPlantUML)
+	case "y":
+		if err := clipboard.WriteAll(m.exportDiagram().Mermaid()); err != nil {
+			m.statusMessage = "Copy failed: " + err.Error()
+		} else {
+			m.statusMessage = "Mermaid diagram copied"
+		}
+	}
+	return m, nil
+}
+
+func (m Model) exportCmd(f erd.Format) tea.Cmd {
+	text := m.exportDiagram().Render(f)
+	path := m.exportName() + f.Extension()
+	return func() tea.Msg {
+		err := os.WriteFile(path, []byte(text), 0o644)
+		return exportedMsg{path: path, err: err}
+	}
+}
+
+func (m Model) bodyHeight() int {
+	return max(1, m.height-6)
+}
+
+// ── View ────────────────────────────────────────────────────────────────
+
+// View renders the diagram screen.
+func (m Model) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(0, 1)
+
+	heading := m.focus
+	if heading == "" {
+		heading = m.schema
+	}
+
+	var body string
+	switch {
+	case m.loading:
+		body = theme.StyleMuted.Render("  Loading schema...")
+	case m.err != nil:
+		body = theme.StyleError.Render("  Error: " + m.err.Error())
+	case m.focus == "":
+		body = theme.StyleMuted.Render("  Schema " + m.schema + " has no tables")
+	default:
+		body = m.viewDiagram()
+	}
+
+	footer := theme.StyleMuted.Render("  " + m.footer())
+	if m.statusMessage != "" {
+		footer = theme.StyleSuccess.Render("  "+m.statusMessage) + "  " + footer
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("ER Diagram")+"  "+theme.StyleMuted.Render(heading),
+		"",
+		body,
+		"",
+		m.viewNeighbors(),
+		footer,
+	)
+}
+
+func (m Model) viewDiagram() string {
+	height := m.bodyHeight()
+	start := min(m.scrollY, max(0, len(m.lines)-1))
+	end := min(len(m.lines), start+height)
+
+	var out []string
+	for _, l := range m.lines[start:end] {
+		runes := []rune(l)
+		if m.scrollX < len(runes) {
+			l = string(runes[m.scrollX:])
+		} else {
+			l = ""
+		}
+		if lipgloss.Width(l) > m.width-2 {
+			l = string([]rune(l)[:max(0, m.width-3)]) + "…"
+		}
+		out = append(out, " "+l)
+	}
+	return strings.Join(out, "\n")
+}
+
+func (m Model) viewNeighbors() string {
+	if len(m.neighbors) == 0 {
+		return ""
+	}
+	parts := make([]string, len(m.neighbors))
+	for i, n := range m.neighbors {
+		if i == m.selected {
+			parts[i] = lipgloss.NewStyle().
+				Foreground(theme.ColorHighlight).
+				Bold(true).
+				Render("[" + n + "]")
+		} else {
+			parts[i] = n
+		}
+	}
+	return theme.StyleMuted.Render("  Go to: ") + strings.Join(parts, "  ")
+}
+
+func (m Model) footer() string {
+	scope := "this table and neighbours"
+	if m.whole {
+		scope = "schema " + m.schema
+	}
+	hints := fmt.Sprintf("Export %s: m mermaid | d dot | p plantuml | y copy | a scope", scope)
+	hints += " | ↑↓←→ scroll | Tab/Enter go to"
+	if len(m.history) > 0 {
+		hints += " | Backspace back"
+	}
+	return hints + " | Esc close"
+}
@@ -1,13 +1,17 @@
 package editor
 
 import (
+	"maps"
 	"sort"
 	"strings"
-	"unicode"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/sql"
 	"github.com/joacominatel/minadb/internal/tui/theme"
 )
 
@@ -16,26 +20,21 @@ type ExecuteQueryMsg struct {
 	Query string
 }
 
-// SQL keywords for formatting and completion.
-var sqlKeywords = map[string]bool{
-	"select": true, "from": true, "where": true, "and": true, "or": true,
-	"insert": true, "into": true, "update": true, "delete": true,
-	"create": true, "drop": true, "alter": true, "table": true,
-	"index": true, "join": true, "inner": true, "outer": true,
-	"left": true, "right": true, "cross": true, "on": true,
-	"not": true, "in": true, "is": true, "null": true, "like": true,
-	"order": true, "by": true, "group": true, "having": true,
-	"limit": true, "offset": true, "as": true, "distinct": true,
-	"count": true, "sum": true, "avg": true, "min": true, "max": true,
-	"between": true, "exists": true, "case": true, "when": true,
-	"then": true, "else": true, "end": true, "values": true,
-	"set": true, "begin": true, "commit": true, "rollback": true,
-	"union": true, "all": true, "asc": true, "desc": true,
-	"primary": true, "key": true, "foreign": true, "references": true,
-	"cascade": true, "restrict": true, "default": true,
-	"true": true, "false": true, "ilike": true, "returning": true,
+// LoadColumnsMsg asks for the columns of a table referenced in the query,
+// for completion. Schema is empty when the query does not qualify the
+// table, which then resolves through the search path.
+type LoadColumnsMsg struct {
+	Schema string
+	Table  string
 }
 
+// completion is one entry of the completion popup.
+type completion struct {
+	text   string
+	detail string // e.g. the column type
+}
+
+// SQL keywords offered by completion.
 var sqlKeywordList = []string{
 	"SELECT", "FROM", "WHERE", "AND", "OR", "INSERT", "INTO", "UPDATE", "DELETE",
 	"CREATE", "DROP", "ALTER", "TABLE", "INDEX", "JOIN", "INNER", "OUTER", "LEFT",
@@ -52,11 +51,23 @@ type Model struct {
 	width    int
 	height   int
 	focused  bool
+	format   sql.FormatOptions
+	view     *textView
+	history  []history.Entry // executed queries, newest first
+	search   *historySearch  // Ctrl+R reverse search, nil when closed
+	snippet  *snippetSession // placeholders left to fill, nil when none
+
+	anchor     int  // byte offset the selection starts at, -1 for none
+	flash      span // text last sent to run, highlighted until flashUntil
+	flashUntil time.Time
 
 	// Completion state
-	tableNames          []string // cached table names from database
+	tableNames          []string                     // cached table names from database
+	columns             map[string][]database.Column // columns by table as written in queries
+	columnRequests      map[string]bool              // tables whose columns were asked for
+	awaitingColumns     bool                         // reopen completion when columns arrive
 	showingCompletions  bool
-	completions         []string
+	completions         []completion
 	completionIndex     int
 	completionStartByte int
 }
@@ -67,6 +78,7 @@ func New() Model {
 	ta.Placeholder = "Enter SQL query..."
 	ta.ShowLineNumbers = false
 	ta.CharLimit = 0 // unlimited
+	ta.MaxHeight = 0 // no line limit; the view only draws what is on screen
 	ta.Prompt = "│ "
 	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
 	ta.FocusedStyle.Base = lipgloss.NewStyle()
@@ -78,6 +90,9 @@ func New() Model {
 
 	return Model{
 		textarea: ta,
+		format:   sql.DefaultFormatOptions,
+		view:     newTextView(),
+		anchor:   -1,
 	}
 }
 
@@ -96,6 +111,7 @@ func (m *Model) SetFocused(f bool) {
 		m.textarea.Focus()
 	} else {
 		m.textarea.Blur()
+		m.search = nil
 	}
 }
 
@@ -112,22 +128,63 @@ func (m Model) Value() string {
 // SetQuery replaces the editor content.
 func (m *Model) SetQuery(query string) {
 	m.textarea.SetValue(query)
+	m.snippet = nil
+	m.anchor = -1
+}
+
+// SetFormatOptions sets the style Ctrl+L formats queries with.
+func (m *Model) SetFormatOptions(opts sql.FormatOptions) {
+	m.format = opts
 }
 
 // SetTableNames sets the available table names for autocompletion.
+// Column metadata fetched so far is dropped since the schema changed.
 func (m *Model) SetTableNames(names []string) {
 	m.tableNames = names
+	m.columns = nil
+	m.columnRequests = nil
+}
+
+// SetColumns provides the columns of a table requested with
+// LoadColumnsMsg. Nil columns mark the table as unknown so it is not
+// requested again.
+func (m *Model) SetColumns(schema, table string, columns []database.Column) {
+	if m.columns == nil {
+		m.columns = make(map[string][]database.Column)
+	}
+	m.columns[tableRef{schema: schema, table: table}.key()] = columns
+	if m.awaitingColumns && m.focused {
+		m.awaitingColumns = false
+		m.openCompletions()
+	} else if m.showingCompletions {
+		m.refreshCompletions()
+	}
+}
+
+// Blank returns an empty editor of the same size with m's format
+// options, history and completion metadata, for a new tab.
+func (m Model) Blank() Model {
+	b := New()
+	b.SetSize(m.width, m.height)
+	b.format = m.format
+	b.history = m.history
+	b.tableNames = m.tableNames
+	b.columns = maps.Clone(m.columns)
+	return b
 }
 
 // Clear empties the editor.
 func (m *Model) Clear() {
 	m.textarea.Reset()
 	m.cancelCompletion()
+	m.anchor = -1
 }
 
-// CompletionActive reports if completion UI is open.
+// CompletionActive reports if completion UI or the history search is
+// open, or snippet placeholders are being filled, so keys such as Tab
+// belong to the editor.
 func (m Model) CompletionActive() bool {
-	return m.showingCompletions
+	return m.showingCompletions || m.search != nil || m.snippet != nil
 }
 
 // Init returns the initial command.
@@ -143,36 +200,51 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.flash = span{}
+		if m.search != nil {
+			return m.updateHistorySearch(msg)
+		}
+		if m.snippet != nil {
+			return m.updateSnippet(msg)
+		}
 		if msg.Type == tea.KeyCtrlAt || msg.Type == tea.KeyNull {
-			m.openCompletions()
-			return m, nil
+			return m, m.openCompletions()
 		}
 
 		key := msg.String()
 
+		if move, ok := selectKeys[key]; ok {
+			return m.extendSelection(move)
+		}
 		switch key {
 		case "ctrl+e", "f5":
-			query := strings.TrimSpace(m.textarea.Value())
-			if query != "" {
-				m.cancelCompletion()
-				return m, func() tea.Msg {
-					return ExecuteQueryMsg{Query: query}
-				}
-			}
+			return m, m.runQuery(false)
+
+		case "alt+enter", "f6":
+			return m, m.runQuery(true)
+		}
+		var used bool
+		if m, used = m.updateSelection(msg); used {
 			return m, nil
+		}
+
+		switch key {
 
 		case "ctrl+k":
 			m.Clear()
 			return m, nil
 
+		case "ctrl+r":
+			m.openHistorySearch()
+			return m, nil
+
 		case "ctrl+l":
-			// Manual formatting is still useful for pasted queries.
-			m.formatKeywords()
+			m.cancelCompletion()
+			m.formatQuery()
 			return m, nil
 
 		case "ctrl+space", "ctrl+@":
-			m.openCompletions()
-			return m, nil
+			return m, m.openCompletions()
 
 		case "up":
 			if m.showingCompletions && len(m.completions) > 0 {
@@ -208,13 +280,13 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	m.textarea, cmd = m.textarea.Update(msg)
 
 	if keyMsg, ok := msg.(tea.KeyMsg); ok {
-		m.afterTextInput(keyMsg)
+		return m, tea.Batch(cmd, m.afterTextInput(keyMsg))
 	}
 
 	return m, cmd
 }
 
-func (m *Model) afterTextInput(msg tea.KeyMsg) {
+func (m *Model) afterTextInput(msg tea.KeyMsg) tea.Cmd {
 	key := msg.String()
 
 	if key == " " || key == "enter" || key == ";" {
@@ -222,135 +294,121 @@ func (m *Model) afterTextInput(msg tea.KeyMsg) {
 	}
 
 	if m.showingCompletions {
-		m.refreshCompletions()
+		return m.refreshCompletions()
 	}
+
+	// typing "alias." lists the columns of that table right away
+	if key == "." {
+		val := m.textarea.Value()
+		prefix, start, _ := extractTrailingIdentifier(m.beforeCursor())
+		if qual, _, ok := splitQualifier(prefix); ok {
+			if _, found := analyzeStatement(val, start).resolve(qual); found {
+				return m.openCompletions()
+			}
+		}
+	}
+	return nil
 }
 
+// autoUppercaseLastWord uppercases the keyword just finished by a space,
+// newline or semicolon before the cursor. Words inside strings, quoted
+// identifiers and comments are lexed as such and left alone.
 func (m *Model) autoUppercaseLastWord() {
 	val := m.textarea.Value()
-	runes := []rune(val)
-	if len(runes) < 2 {
+	cursor := m.cursorOffset()
+	toks := sql.Lex(val[:cursor])
+	n := len(toks)
+	if n < 2 {
 		return
 	}
-
-	i := len(runes) - 1
-	if !isWordBoundary(runes[i]) {
-		return
-	}
-	i--
-	for i >= 0 && isWordBoundary(runes[i]) {
-		i--
-	}
-	if i < 0 {
+	last := toks[n-1]
+	if last.Kind != sql.Whitespace && !last.IsPunct(";") {
 		return
 	}
 
-	end := i
-	for i >= 0 && (unicode.IsLetter(runes[i]) || runes[i] == '_') {
-		i--
-	}
-	start := i + 1
-	if start > end {
-		return
+	word := toks[n-2]
+	if last.IsPunct(";") && word.Kind == sql.Whitespace && n >= 3 {
+		word = toks[n-3]
 	}
-
-	word := string(runes[start : end+1])
-	if !sqlKeywords[strings.ToLower(word)] {
+	if word.Kind != sql.Keyword || word.Text == strings.ToUpper(word.Text) {
 		return
 	}
-
-	runes = append(runes[:start], append([]rune(strings.ToUpper(word)), runes[end+1:]...)...)
-	m.textarea.SetValue(string(runes))
+	m.textarea.SetValue(val[:word.Pos] + strings.ToUpper(word.Text) + val[word.End():])
+	m.setCursorOffset(cursor)
 }
 
-// formatKeywords uppercases all SQL keywords in the editor content.
-func (m *Model) formatKeywords() {
+// formatQuery pretty-prints the editor content.
+func (m *Model) formatQuery() {
 	val := m.textarea.Value()
-	if val == "" {
+	if strings.TrimSpace(val) == "" {
 		return
 	}
-
-	var result strings.Builder
-	word := strings.Builder{}
-	inString := false
-	quote := rune(0)
-
-	for _, ch := range val {
-		// Track string literals
-		if (ch == '\'' || ch == '"') && !inString {
-			inString = true
-			quote = ch
-			m.flushWord(&word, &result)
-			result.WriteRune(ch)
-			continue
-		}
-		if inString && ch == quote {
-			inString = false
-			result.WriteRune(ch)
-			continue
-		}
-		if inString {
-			result.WriteRune(ch)
-			continue
-		}
-
-		// Word boundary
-		if !unicode.IsLetter(ch) && ch != '_' {
-			m.flushWord(&word, &result)
-			result.WriteRune(ch)
-		} else {
-			word.WriteRune(ch)
-		}
-	}
-	m.flushWord(&word, &result)
-
-	m.textarea.SetValue(result.String())
+	m.textarea.SetValue(sql.Format(val, m.format))
 }
 
-func (m *Model) flushWord(word *strings.Builder, result *strings.Builder) {
-	if word.Len() == 0 {
-		return
-	}
-	w := word.String()
-	if sqlKeywords[strings.ToLower(w)] {
-		result.WriteString(strings.ToUpper(w))
-	} else {
-		result.WriteString(w)
+// openCompletions shows the completion popup for the identifier before
+// the cursor. It returns a command requesting the columns of referenced
+// tables that are not known yet.
+func (m *Model) openCompletions() tea.Cmd {
+	prefix, start, ok := extractTrailingIdentifier(m.beforeCursor())
+	if !ok {
+		m.cancelCompletion()
+		return nil
 	}
-	word.Reset()
-}
-
-// tryCompletion attempts table name completion at the current cursor position.
-// Returns true if a completion was applied.
-func (m *Model) openCompletions() {
-	prefix, start := extractTrailingIdentifier(m.textarea.Value())
-	suggestions := m.getSuggestions(prefix, start)
+	suggestions, missing := m.getSuggestions(prefix, start)
+	cmd := m.requestColumns(missing)
+	m.awaitingColumns = cmd != nil
 	if len(suggestions) == 0 {
 		m.cancelCompletion()
-		return
+		return cmd
 	}
 
 	m.showingCompletions = true
 	m.completions = suggestions
 	m.completionIndex = 0
 	m.completionStartByte = start
+	return cmd
 }
 
-func (m *Model) refreshCompletions() {
+func (m *Model) refreshCompletions() tea.Cmd {
 	if !m.showingCompletions {
-		return
+		return nil
+	}
+	prefix, start, ok := extractTrailingIdentifier(m.beforeCursor())
+	if !ok {
+		m.cancelCompletion()
+		return nil
 	}
-	prefix, start := extractTrailingIdentifier(m.textarea.Value())
-	suggestions := m.getSuggestions(prefix, start)
+	suggestions, missing := m.getSuggestions(prefix, start)
+	cmd := m.requestColumns(missing)
 	if len(suggestions) == 0 {
 		m.cancelCompletion()
-		return
+		m.awaitingColumns = cmd != nil
+		return cmd
 	}
 	m.completions = suggestions
 	m.completionStartByte = start
 	if m.completionIndex >= len(m.completions) {
 		m.completionIndex = len(m.completions) - 1
 	}
+	return cmd
+}
+
+// requestColumns asks once for the columns of each table.
+func (m *Model) requestColumns(refs []tableRef) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, ref := range refs {
+		if m.columnRequests[ref.key()] {
+			continue
+		}
+		if m.columnRequests == nil {
+			m.columnRequests = make(map[string]bool)
+		}
+		m.columnRequests[ref.key()] = true
+		msg := LoadColumnsMsg{Schema: ref.schema, Table: ref.table}
+		cmds = append(cmds, func() tea.Msg { return msg })
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m *Model) acceptCompletion() {
@@ -359,94 +417,174 @@ func (m *Model) acceptCompletion() {
 	}
 
 	val := m.textarea.Value()
-	if m.completionStartByte < 0 || m.completionStartByte > len(val) {
+	cursor := m.cursorOffset()
+	if m.completionStartByte < 0 || m.completionStartByte > cursor {
 		m.cancelCompletion()
 		return
 	}
 
-	newVal := val[:m.completionStartByte] + m.completions[m.completionIndex]
-	m.textarea.SetValue(newVal)
+	text := m.completions[m.completionIndex].text
+	m.textarea.SetValue(val[:m.completionStartByte] + text + val[cursor:])
+	m.setCursorOffset(m.completionStartByte + len(text))
 	m.cancelCompletion()
 }
 
 func (m *Model) cancelCompletion() {
 	m.showingCompletions = false
+	m.awaitingColumns = false
 	m.completions = nil
 	m.completionIndex = 0
 	m.completionStartByte = 0
 }
 
-func isIdentChar(c rune) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
-		(c >= '0' && c <= '9') || c == '_' || c == '.'
-}
-
-func isWordBoundary(c rune) bool {
-	return unicode.IsSpace(c) || c == ';' || c == ',' || c == ')' || c == '('
-}
-
-func extractTrailingIdentifier(s string) (string, int) {
-	if s == "" {
-		return "", 0
-	}
-
-	i := len(s)
-	for i > 0 {
-		r := rune(s[i-1])
-		if isIdentChar(r) {
-			i--
-			continue
+// extractTrailingIdentifier returns the possibly qualified identifier
+// ending at the end of s and its byte offset. ok is false when s ends
+// inside a string literal, quoted identifier or comment.
+func extractTrailingIdentifier(s string) (prefix string, start int, ok bool) {
+	toks := sql.Lex(s)
+	start = len(s)
+	for i := len(toks) - 1; i >= 0; i-- {
+		t := toks[i]
+		if i == len(toks)-1 && t.Open() {
+			return "", len(s), false
+		}
+		if t.Kind != sql.Ident && t.Kind != sql.Keyword && !t.IsPunct(".") {
+			break
 		}
-		break
+		start = t.Pos
 	}
-
-	return s[i:], i
+	return s[start:], start, true
 }
 
-func (m Model) getSuggestions(prefix string, start int) []string {
-	prefixLower := strings.ToLower(prefix)
-	prefixUpper := strings.ToUpper(prefix)
+// maxCompletions caps the number of entries in the popup.
+const maxCompletions = 10
 
-	context := strings.TrimSpace(strings.ToUpper(m.textarea.Value()[:start]))
-	last := lastSQLToken(context)
+// columnClauses are the clauses where column names are expected.
+var columnClauses = map[string]bool{
+	"SELECT": true, "WHERE": true, "ON": true, "GROUP": true, "ORDER": true,
+	"HAVING": true, "SET": true, "RETURNING": true, "USING": true,
+}
 
-	candidates := make([]string, 0, len(sqlKeywordList)+len(m.tableNames)+1)
+// getSuggestions lists completions for prefix, which starts at byte start.
+// It also returns the referenced tables whose columns are not known yet.
+func (m Model) getSuggestions(prefix string, start int) ([]completion, []tableRef) {
+	val := m.textarea.Value()
+	stmt := analyzeStatement(val, start)
+
+	// alias.column or table.column
+	if qual, partial, ok := splitQualifier(prefix); ok {
+		if ref, found := stmt.resolve(qual); found {
+			cols, known := m.columns[ref.key()]
+			if !known {
+				return nil, []tableRef{ref}
+			}
+			var out []completion
+			for _, c := range cols {
+				if hasPrefixFold(c.Name, partial) {
+					out = append(out, completion{text: qual + "." + c.Name, detail: c.DataType})
+				}
+			}
+			return capCompletions(out), nil
+		}
+	}
 
-	switch last {
-	case "FROM", "JOIN", "INTO", "UPDATE", "TABLE":
-		candidates = append(candidates, m.tableNames...)
-	case "SELECT":
-		candidates = append(candidates, "*")
-		candidates = append(candidates, sqlKeywordList...)
-		candidates = append(candidates, m.tableNames...)
+	last := lastSQLToken(strings.TrimSpace(strings.ToUpper(val[:start])))
+	var columns, words []string
+	var missing []tableRef
+	detail := make(map[string]string)
+
+	switch {
+	case last == "FROM" || last == "JOIN" || last == "INTO" || last == "UPDATE" || last == "TABLE":
+		words = append(words, m.tableNames...)
+	case columnClauses[stmt.clause]:
+		for _, ref := range stmt.tables {
+			cols, known := m.columns[ref.key()]
+			if !known {
+				missing = append(missing, ref)
+				continue
+			}
+			label := ref.alias
+			if label == "" {
+				label = ref.table
+			}
+			for _, c := range cols {
+				if _, dup := detail[c.Name]; !dup {
+					columns = append(columns, c.Name)
+					detail[c.Name] = c.DataType + " · " + label
+				}
+			}
+		}
+		if stmt.clause == "SELECT" {
+			words = append(words, "*")
+		}
+		words = append(words, sqlKeywordList...)
+		words = append(words, m.tableNames...)
 	default:
-		candidates = append(candidates, sqlKeywordList...)
-		candidates = append(candidates, m.tableNames...)
+		words = append(words, sqlKeywordList...)
+		words = append(words, m.tableNames...)
 	}
 
-	seen := make(map[string]struct{}, len(candidates))
-	filtered := make([]string, 0, 10)
-	for _, c := range candidates {
-		if c == "" {
-			continue
-		}
-		k := strings.ToLower(c)
-		if _, ok := seen[k]; ok {
-			continue
+	// columns come first, then keywords and tables
+	seen := make(map[string]struct{})
+	var out []completion
+	for _, group := range [][]string{sortedCopy(columns), sortedCopy(words)} {
+		for _, c := range group {
+			if c == "" {
+				continue
+			}
+			k := strings.ToLower(c)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if hasPrefixFold(c, prefix) {
+				out = append(out, completion{text: c, detail: detail[c]})
+			}
 		}
-		seen[k] = struct{}{}
+	}
+	return capCompletions(out), missing
+}
 
-		if prefixLower == "" || strings.HasPrefix(strings.ToLower(c), prefixLower) || strings.HasPrefix(strings.ToUpper(c), prefixUpper) {
-			filtered = append(filtered, c)
+// resolve finds the table an alias or table name refers to.
+func (s stmtContext) resolve(name string) (tableRef, bool) {
+	name = strings.ToLower(strings.Trim(name, `"`))
+	for _, ref := range s.tables {
+		if ref.alias == name {
+			return ref, true
 		}
 	}
+	for _, ref := range s.tables {
+		if ref.alias == "" && (ref.table == name || ref.key() == name) {
+			return ref, true
+		}
+	}
+	return tableRef{}, false
+}
 
-	sort.Strings(filtered)
-	if len(filtered) > 10 {
-		filtered = filtered[:10]
+// splitQualifier splits "a.b" at its last dot.
+func splitQualifier(prefix string) (qual, partial string, ok bool) {
+	i := strings.LastIndexByte(prefix, '.')
+	if i <= 0 {
+		return "", "", false
 	}
+	return prefix[:i], prefix[i+1:], true
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func sortedCopy(list []string) []string {
+	out := append([]string(nil), list...)
+	sort.Strings(out)
+	return out
+}
 
-	return filtered
+func capCompletions(list []completion) []completion {
+	if len(list) > maxCompletions {
+		return list[:maxCompletions]
+	}
+	return list
 }
 
 func lastSQLToken(s string) string {
@@ -469,8 +607,15 @@ func (m Model) View() string {
 
 	title := titleStyle.Render("Query Editor")
 
-	editorView := title + "\n" + m.textarea.View()
+	body := m.textarea.View() // placeholder
+	if m.textarea.Value() != "" {
+		body = m.renderText()
+	}
+	editorView := title + "\n" + body
 
+	if m.search != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, editorView, m.renderHistorySearch())
+	}
 	if !m.showingCompletions || len(m.completions) == 0 {
 		return editorView
 	}
@@ -486,12 +631,21 @@ func (m Model) renderCompletionDropdown() string {
 		Bold(true).
 		Padding(0, 1)
 
+	textWidth := 0
+	for _, c := range m.completions {
+		textWidth = max(textWidth, lipgloss.Width(c.text))
+	}
+
 	items := make([]string, 0, len(m.completions))
 	for i, c := range m.completions {
+		label := c.text
+		if c.detail != "" {
+			label += strings.Repeat(" ", textWidth-lipgloss.Width(c.text)) + "  "
+		}
 		if i == m.completionIndex {
-			items = append(items, selectedStyle.Render(c))
+			items = append(items, selectedStyle.Render(label+c.detail))
 		} else {
-			items = append(items, itemStyle.Render(c))
+			items = append(items, itemStyle.Render(label+theme.StyleMuted.Render(c.detail)))
 		}
 	}
 
@@ -0,0 +1,411 @@
+package editor
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/sql"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// class is how a character of the query is coloured.
+type class int
+
+const (
+	classPlain class = iota
+	classKeyword
+	classIdent
+	classString
+	classNumber
+	classComment
+	classOperator
+	classParam
+	classBracket // bracket under the cursor and its match
+	classError   // unterminated literal or comment, unmatched bracket
+	classField   // placeholder of an inserted snippet
+	classFieldOn // placeholder being filled
+)
+
+var classStyles = [...]lipgloss.Style{
+	classPlain:    lipgloss.NewStyle(),
+	classKeyword:  lipgloss.NewStyle().Foreground(theme.ColorKeyword).Bold(true),
+	classIdent:    lipgloss.NewStyle().Foreground(theme.ColorIdent),
+	classString:   lipgloss.NewStyle().Foreground(theme.ColorString),
+	classNumber:   lipgloss.NewStyle().Foreground(theme.ColorNumber),
+	classComment:  lipgloss.NewStyle().Foreground(theme.ColorComment).Italic(true),
+	classOperator: lipgloss.NewStyle().Foreground(theme.ColorOperator),
+	classParam:    lipgloss.NewStyle().Foreground(theme.ColorParam),
+	classBracket:  lipgloss.NewStyle().Foreground(theme.ColorHighlight).Bold(true).Underline(true),
+	classError:    lipgloss.NewStyle().Foreground(theme.ColorError).Underline(true),
+	classField:    lipgloss.NewStyle().Foreground(theme.ColorParam).Underline(true),
+	classFieldOn:  lipgloss.NewStyle().Foreground(theme.ColorParam).Background(lipgloss.Color("237")).Underline(true),
+}
+
+// Backgrounds laid over the syntax colours.
+var (
+	selectionBackground = lipgloss.Color("24")
+	flashBackground     = lipgloss.Color("58")
+)
+
+func tokenClass(k sql.Kind) class {
+	switch k {
+	case sql.Keyword:
+		return classKeyword
+	case sql.Ident, sql.QuotedIdent:
+		return classIdent
+	case sql.String, sql.DollarString:
+		return classString
+	case sql.Number:
+		return classNumber
+	case sql.Comment:
+		return classComment
+	case sql.Operator:
+		return classOperator
+	case sql.Param:
+		return classParam
+	}
+	return classPlain
+}
+
+// textView renders the editor content with syntax highlighting in place
+// of the textarea's own view. It keeps the tokens of the last content it
+// saw, so the script is only lexed again when it changes, and draws only
+// the lines that are on screen.
+type textView struct {
+	src        string
+	toks       []sql.Token
+	lines      []string
+	lineStarts []int // byte offset of each line in src
+	errorAt    int   // start of an unterminated token, or -1
+
+	// first display row on screen: a line and a wrapped row within it
+	topLine, topRow int
+}
+
+func newTextView() *textView {
+	return &textView{errorAt: -1}
+}
+
+// update lexes src if it differs from the content seen last. Tokens of
+// the statements before the one changed are kept, so typing near the end
+// of a long script only lexes its tail again.
+func (v *textView) update(src string) {
+	if src == v.src && v.lines != nil {
+		return
+	}
+	prefix := 0
+	for prefix < len(src) && prefix < len(v.src) && src[prefix] == v.src[prefix] {
+		prefix++
+	}
+	// a token before the change may still grow into it, as "1" does into
+	// "1e5" and "-" into a comment, and an unterminated literal ends at
+	// the new quote, so lexing starts again after the last semicolon
+	keep := sort.Search(len(v.toks), func(i int) bool { return v.toks[i].End() > prefix })
+	for keep > 0 && !v.toks[keep-1].IsPunct(";") {
+		keep--
+	}
+	start := 0
+	if keep > 0 {
+		start = v.toks[keep-1].End()
+	}
+	v.toks = v.toks[:keep]
+	for _, t := range sql.Lex(src[start:]) {
+		t.Pos += start
+		v.toks = append(v.toks, t)
+	}
+	v.src = src
+	v.lines = strings.Split(src, "\n")
+	v.lineStarts = make([]int, len(v.lines))
+	offset := 0
+	for i, l := range v.lines {
+		v.lineStarts[i] = offset
+		offset += len(l) + 1
+	}
+	v.errorAt = -1
+	if n := len(v.toks); n > 0 {
+		last := v.toks[n-1]
+		unterminated := last.Kind == sql.Comment && strings.HasPrefix(last.Text, "/*") ||
+			last.Kind == sql.String || last.Kind == sql.QuotedIdent || last.Kind == sql.DollarString
+		if unterminated && last.Open() {
+			v.errorAt = last.Pos
+		}
+	}
+}
+
+// tokenAt returns the index of the token containing byte offset pos, or
+// -1.
+func (v *textView) tokenAt(pos int) int {
+	i := sort.Search(len(v.toks), func(i int) bool { return v.toks[i].End() > pos })
+	if i < len(v.toks) && v.toks[i].Pos <= pos {
+		return i
+	}
+	return -1
+}
+
+// brackets returns the offsets of the bracket at or just before the
+// cursor and of its match, which is -1 when the bracket is unmatched.
+// It returns -1, -1 when the cursor is not next to a bracket.
+func (v *textView) brackets(cursor int) (int, int) {
+	for _, pos := range []int{cursor, cursor - 1} {
+		i := v.tokenAt(pos)
+		if i < 0 || v.toks[i].Kind != sql.Punct {
+			continue
+		}
+		switch v.toks[i].Text {
+		case "(":
+			return pos, v.match(i, "(", ")", 1)
+		case "[":
+			return pos, v.match(i, "[", "]", 1)
+		case ")":
+			return pos, v.match(i, ")", "(", -1)
+		case "]":
+			return pos, v.match(i, "]", "[", -1)
+		}
+	}
+	return -1, -1
+}
+
+// match walks from token i in direction dir to the bracket that closes
+// it, returning its offset or -1.
+func (v *textView) match(i int, open, close string, dir int) int {
+	depth := 0
+	for j := i; j >= 0 && j < len(v.toks); j += dir {
+		t := v.toks[j]
+		if t.Kind != sql.Punct {
+			continue
+		}
+		switch t.Text {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return t.Pos
+			}
+		}
+	}
+	return -1
+}
+
+// wrapRows splits a line into display rows the way the textarea soft-wraps
+// it, returning the number of runes in each row. As in the textarea, the
+// last row has one extra position for the cursor past the end of the line.
+func wrapRows(runes []rune, width int) []int {
+	rows := []int{0}
+	widths := []int{0}
+	var word []rune
+	spaces := 0
+	row := 0
+	newRow := func() {
+		row++
+		rows = append(rows, 0)
+		widths = append(widths, 0)
+	}
+	for _, r := range runes {
+		if unicode.IsSpace(r) {
+			spaces++
+		} else {
+			word = append(word, r)
+		}
+
+		if spaces > 0 {
+			w := uniseg.StringWidth(string(word))
+			if widths[row]+w+spaces > width {
+				newRow()
+			}
+			rows[row] += len(word) + spaces
+			widths[row] += w + spaces
+			spaces = 0
+			word = nil
+		} else {
+			w := uniseg.StringWidth(string(word))
+			if w+runewidth.RuneWidth(word[len(word)-1]) > width {
+				if rows[row] > 0 {
+					newRow()
+				}
+				rows[row] += len(word)
+				widths[row] += w
+				word = nil
+			}
+		}
+	}
+
+	if widths[row]+uniseg.StringWidth(string(word))+spaces >= width {
+		rows = append(rows, len(word)+spaces+1)
+	} else {
+		rows[row] += len(word) + spaces + 1
+	}
+	return rows
+}
+
+// scroll moves the first row on screen so the cursor row stays visible,
+// scrolling as little as possible.
+func (v *textView) scroll(line, row, height, width int) {
+	if v.topLine >= len(v.lines) {
+		v.topLine, v.topRow = len(v.lines)-1, 0
+	}
+	if v.topRow >= len(wrapRows([]rune(v.lines[v.topLine]), width)) {
+		v.topRow = 0
+	}
+	if line < v.topLine || (line == v.topLine && row < v.topRow) {
+		v.topLine, v.topRow = line, row
+		return
+	}
+	for n := 1; n < height; n++ {
+		if line == v.topLine && row == v.topRow {
+			return
+		}
+		if row > 0 {
+			row--
+		} else {
+			line--
+			row = len(wrapRows([]rune(v.lines[line]), width)) - 1
+		}
+	}
+	v.topLine, v.topRow = line, row
+}
+
+// renderText draws the visible part of the editor content.
+func (m Model) renderText() string {
+	ta := m.textarea
+	v := m.view
+	v.update(ta.Value())
+
+	width, height := ta.Width(), ta.Height()
+	info := ta.LineInfo()
+	curLine := ta.Line()
+	v.scroll(curLine, info.RowOffset, height, width)
+
+	cursorByte := v.lineStarts[curLine] + len(string([]rune(v.lines[curLine])[:info.StartColumn+info.ColumnOffset]))
+	bracket, matched := v.brackets(cursorByte)
+
+	promptStyle := ta.BlurredStyle.Prompt
+	if m.focused {
+		promptStyle = ta.FocusedStyle.Prompt
+	}
+	prompt := promptStyle.Render(ta.Prompt)
+
+	selected, hasSelection := m.selection()
+	flash, hasFlash := m.flashing()
+	// background returns the colour behind the character at pos, "" for
+	// none
+	background := func(pos int) lipgloss.Color {
+		switch {
+		case hasSelection && selected.contains(pos):
+			return selectionBackground
+		case hasFlash && flash.contains(pos):
+			return flashBackground
+		}
+		return ""
+	}
+
+	classAt := func(pos, tok int) class {
+		if in, active := m.snippetFieldAt(pos); in {
+			if active {
+				return classFieldOn
+			}
+			return classField
+		}
+		switch {
+		case v.errorAt >= 0 && pos >= v.errorAt:
+			return classError
+		case pos == bracket && matched < 0:
+			return classError
+		case pos == bracket || pos == matched:
+			return classBracket
+		case tok >= 0:
+			return tokenClass(v.toks[tok].Kind)
+		}
+		return classPlain
+	}
+
+	out := make([]string, 0, height)
+	line, row := v.topLine, v.topRow
+	for len(out) < height && line < len(v.lines) {
+		runes := []rune(v.lines[line])
+		rows := wrapRows(runes, width)
+
+		// byte offset of each rune, so rows can be matched to tokens
+		offsets := make([]int, len(runes)+1)
+		pos := v.lineStarts[line]
+		for i, r := range runes {
+			offsets[i] = pos
+			pos += utf8.RuneLen(r)
+		}
+		offsets[len(runes)] = pos
+
+		start := 0
+		for r := 0; r < row; r++ {
+			start += rows[r]
+		}
+		for ; row < len(rows) && len(out) < height; row++ {
+			end := min(start+rows[row], len(runes))
+
+			var b strings.Builder
+			var run strings.Builder
+			runClass := classPlain
+			var runBackground lipgloss.Color
+			flush := func() {
+				if run.Len() > 0 {
+					style := classStyles[runClass]
+					if runBackground != "" {
+						style = style.Background(runBackground)
+					}
+					b.WriteString(style.Render(run.String()))
+					run.Reset()
+				}
+			}
+			tok := v.tokenAt(offsets[start])
+			drawn := 0
+			for i := start; i < end; i++ {
+				for tok >= 0 && tok < len(v.toks) && v.toks[tok].End() <= offsets[i] {
+					tok++
+				}
+				if tok >= len(v.toks) {
+					tok = -1
+				}
+				c := classAt(offsets[i], tok)
+				bg := background(offsets[i])
+				ch := string(runes[i])
+				if runes[i] == '\t' {
+					ch = " "
+				}
+				drawn += uniseg.StringWidth(ch)
+				if line == curLine && row == info.RowOffset && i-start == info.ColumnOffset {
+					flush()
+					cur := ta.Cursor
+					cur.TextStyle = classStyles[c]
+					cur.SetChar(ch)
+					b.WriteString(cur.View())
+					continue
+				}
+				if c != runClass || bg != runBackground {
+					flush()
+					runClass, runBackground = c, bg
+				}
+				run.WriteString(ch)
+			}
+			flush()
+			if line == curLine && row == info.RowOffset && info.ColumnOffset >= end-start {
+				cur := ta.Cursor
+				cur.SetChar(" ")
+				b.WriteString(cur.View())
+				drawn++
+			}
+			b.WriteString(strings.Repeat(" ", max(0, width-drawn)))
+			out = append(out, prompt+b.String())
+			start += rows[row]
+		}
+		line++
+		row = 0
+	}
+	for len(out) < height {
+		out = append(out, prompt+strings.Repeat(" ", width))
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,145 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// historyRows is how many matches the reverse search shows at once.
+const historyRows = 8
+
+// historySearch is the Ctrl+R reverse search over executed queries.
+type historySearch struct {
+	input   textinput.Model
+	matches []history.Entry
+	index   int // match shown, 0 is the newest
+}
+
+// SetHistory provides the executed queries, newest first, for Ctrl+R.
+func (m *Model) SetHistory(entries []history.Entry) {
+	m.history = entries
+	if m.search != nil {
+		m.search.filter(m.history)
+	}
+}
+
+func (m *Model) openHistorySearch() {
+	m.cancelCompletion()
+	m.snippet = nil
+	ti := widget.NewInput()
+	ti.Prompt = "reverse-i-search: "
+	ti.PromptStyle = lipgloss.NewStyle().Foreground(theme.ColorPrimary)
+	ti.Focus()
+	m.search = &historySearch{input: ti}
+	m.search.filter(m.history)
+}
+
+func (s *historySearch) filter(entries []history.Entry) {
+	s.matches = history.Filter(entries, s.input.Value(), "", true)
+	s.index = 0
+}
+
+func (s *historySearch) selected() (history.Entry, bool) {
+	if s.index < len(s.matches) {
+		return s.matches[s.index], true
+	}
+	return history.Entry{}, false
+}
+
+// updateHistorySearch handles keys while the reverse search is open.
+func (m Model) updateHistorySearch(msg tea.KeyMsg) (Model, tea.Cmd) {
+	s := m.search
+	switch msg.String() {
+	case "esc", "ctrl+g", "ctrl+c":
+		m.search = nil
+		return m, nil
+
+	case "ctrl+r", "up":
+		if s.index < len(s.matches)-1 {
+			s.index++
+		}
+		return m, nil
+
+	case "ctrl+s", "down":
+		if s.index > 0 {
+			s.index--
+		}
+		return m, nil
+
+	case "enter", "tab":
+		if e, ok := s.selected(); ok {
+			m.textarea.SetValue(e.Query)
+		}
+		m.search = nil
+		return m, nil
+
+	case "ctrl+e", "f5":
+		e, ok := s.selected()
+		m.search = nil
+		if !ok {
+			return m, nil
+		}
+		m.textarea.SetValue(e.Query)
+		return m, func() tea.Msg { return ExecuteQueryMsg{Query: e.Query} }
+	}
+
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	s.filter(m.history)
+	return m, cmd
+}
+
+// renderHistorySearch draws the reverse search popup: the pattern and the
+// matches around the selected one, newest at the top.
+func (m Model) renderHistorySearch() string {
+	s := m.search
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("0")).
+		Background(theme.ColorHighlight).
+		Bold(true)
+
+	width := max(20, m.width-6)
+	lines := []string{s.input.View()}
+	if len(s.matches) == 0 {
+		lines = append(lines, theme.StyleMuted.Render("no matching query"))
+	}
+
+	start := max(0, s.index-historyRows+1)
+	end := min(len(s.matches), start+historyRows)
+	for i := start; i < end; i++ {
+		e := s.matches[i]
+		meta := e.At.Format("Jan 02 15:04")
+		if !e.OK() {
+			meta += " ✗"
+		}
+		query := oneLine(e.Query)
+		room := max(4, width-lipgloss.Width(meta)-2)
+		if lipgloss.Width(query) > room {
+			query = string([]rune(query)[:room-1]) + "…"
+		}
+		if i == s.index {
+			lines = append(lines, selectedStyle.Render(query)+"  "+theme.StyleMuted.Render(meta))
+		} else {
+			lines = append(lines, query+"  "+theme.StyleMuted.Render(meta))
+		}
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.ColorPrimary).
+		MaxWidth(max(20, m.width-2)).
+		Render(strings.Join(lines, "\n"))
+	hint := theme.StyleMuted.Render("  Ctrl+R/↑ older | ↓ newer | Enter edit | Ctrl+E run | Esc cancel")
+	return lipgloss.JoinVertical(lipgloss.Left, box, hint)
+}
+
+// oneLine collapses a query's whitespace so it fits on one line.
+func oneLine(q string) string {
+	return strings.Join(strings.Fields(q), " ")
+}
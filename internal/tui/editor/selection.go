@@ -0,0 +1,158 @@
+package editor
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joacominatel/minadb/internal/sql"
+)
+
+// flashDuration is how long the text sent to the database stays
+// highlighted.
+const flashDuration = 600 * time.Millisecond
+
+// span is a byte range of the editor content.
+type span struct {
+	start, end int
+}
+
+func (s span) contains(pos int) bool {
+	return pos >= s.start && pos < s.end
+}
+
+// flashDoneMsg redraws the editor once the highlight of a query run has
+// expired.
+type flashDoneMsg struct{}
+
+// selectKeys maps the keys that extend the selection to the cursor
+// movement they make.
+var selectKeys = map[string]tea.KeyMsg{
+	"shift+left":       {Type: tea.KeyLeft},
+	"shift+right":      {Type: tea.KeyRight},
+	"shift+up":         {Type: tea.KeyUp},
+	"shift+down":       {Type: tea.KeyDown},
+	"shift+home":       {Type: tea.KeyHome},
+	"shift+end":        {Type: tea.KeyEnd},
+	"ctrl+shift+left":  {Type: tea.KeyLeft, Alt: true},
+	"ctrl+shift+right": {Type: tea.KeyRight, Alt: true},
+	"ctrl+shift+home":  {Type: tea.KeyCtrlHome},
+	"ctrl+shift+end":   {Type: tea.KeyCtrlEnd},
+}
+
+// Selection returns the selected text, or "" when nothing is selected.
+func (m Model) Selection() string {
+	s, ok := m.selection()
+	if !ok {
+		return ""
+	}
+	return m.textarea.Value()[s.start:s.end]
+}
+
+// selection returns the selected range, which runs between the anchor
+// and the cursor.
+func (m Model) selection() (span, bool) {
+	if m.anchor < 0 {
+		return span{}, false
+	}
+	cur := m.cursorOffset()
+	anchor := min(m.anchor, len(m.textarea.Value()))
+	if anchor == cur {
+		return span{}, false
+	}
+	return span{start: min(anchor, cur), end: max(anchor, cur)}, true
+}
+
+// extendSelection moves the cursor with move, keeping the selection
+// anchored where it started.
+func (m Model) extendSelection(move tea.KeyMsg) (Model, tea.Cmd) {
+	m.cancelCompletion()
+	if m.anchor < 0 {
+		m.anchor = m.cursorOffset()
+	}
+	var cmd tea.Cmd
+	m.textarea, cmd = m.textarea.Update(move)
+	return m, cmd
+}
+
+// deleteSelection removes the selected text, leaving the cursor where it
+// was. It reports whether there was any.
+func (m *Model) deleteSelection() bool {
+	s, ok := m.selection()
+	m.anchor = -1
+	if !ok {
+		return false
+	}
+	val := m.textarea.Value()
+	m.textarea.SetValue(val[:s.start] + val[s.end:])
+	m.setCursorOffset(s.start)
+	return true
+}
+
+// updateSelection handles a key while text is selected: typing replaces
+// the selection, Backspace and Delete remove it and any other key drops
+// it. It reports whether the key was used up.
+func (m Model) updateSelection(msg tea.KeyMsg) (Model, bool) {
+	if _, ok := m.selection(); !ok {
+		m.anchor = -1
+		return m, false
+	}
+	switch {
+	case msg.String() == "backspace" || msg.String() == "delete":
+		m.deleteSelection()
+		return m, true
+	case msg.String() == "esc" && !m.showingCompletions:
+		m.anchor = -1
+		return m, true
+	case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace || msg.Type == tea.KeyEnter ||
+		msg.Type == tea.KeyTab || msg.String() == "ctrl+v":
+		m.deleteSelection()
+	default:
+		m.anchor = -1
+	}
+	return m, false
+}
+
+// runQuery asks to run the selection or, without one, the whole
+// content, or the statement around the cursor when statement is set.
+// What runs is highlighted for a moment.
+func (m *Model) runQuery(statement bool) tea.Cmd {
+	val := m.textarea.Value()
+	s, ok := m.selection()
+	switch {
+	case ok:
+	case statement:
+		st, found := sql.StatementAt(val, m.cursorOffset())
+		if !found {
+			return nil
+		}
+		s = span{start: st.Start, end: st.End}
+	default:
+		trimmed := strings.TrimSpace(val)
+		if trimmed == "" {
+			return nil
+		}
+		start := strings.Index(val, trimmed)
+		s = span{start: start, end: start + len(trimmed)}
+	}
+	query := strings.TrimSpace(val[s.start:s.end])
+	if query == "" {
+		return nil
+	}
+	m.cancelCompletion()
+	m.flash = s
+	m.flashUntil = time.Now().Add(flashDuration)
+	return tea.Batch(
+		func() tea.Msg { return ExecuteQueryMsg{Query: query} },
+		tea.Tick(flashDuration, func(time.Time) tea.Msg { return flashDoneMsg{} }),
+	)
+}
+
+// flashing returns the range highlighted after a query run, if it has
+// not expired.
+func (m Model) flashing() (span, bool) {
+	if m.flash.end <= m.flash.start || time.Now().After(m.flashUntil) {
+		return span{}, false
+	}
+	return m.flash, true
+}
@@ -0,0 +1,273 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joacominatel/minadb/internal/queries"
+)
+
+// snippetSession tracks the placeholders of an inserted saved query while
+// Tab moves through them.
+type snippetSession struct {
+	fields []queries.Field // byte ranges in the editor content, in tab order
+	keys   []string        // distinct keys in tab order, without "0"
+	active int             // index into keys
+	fresh  bool            // the active field still holds its default text
+}
+
+// InsertSnippet inserts a saved query body at the cursor. When it has
+// placeholders, the cursor goes to the first one and Tab and Shift+Tab
+// move between them; typing over a placeholder replaces its default.
+func (m *Model) InsertSnippet(body string) {
+	m.cancelCompletion()
+	m.search = nil
+	m.snippet = nil
+
+	// the textarea expands tabs and drops carriage returns; do it first
+	// so the field offsets match what it stores
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\t", "    ")
+	text, fields := queries.Expand(body)
+
+	at := m.cursorOffset()
+	m.textarea.InsertString(text)
+	if len(fields) == 0 {
+		return
+	}
+
+	s := &snippetSession{}
+	final := false
+	for _, f := range fields {
+		f.Start += at
+		f.End += at
+		s.fields = append(s.fields, f)
+		switch {
+		case f.Key == "0":
+			final = true
+		case !slices.Contains(s.keys, f.Key):
+			s.keys = append(s.keys, f.Key)
+		}
+	}
+	if !final {
+		end := at + len(text)
+		s.fields = append(s.fields, queries.Field{Start: end, End: end, Key: "0"})
+	}
+	m.snippet = s
+	if len(s.keys) == 0 {
+		m.finishSnippet()
+		return
+	}
+	m.enterField(0)
+}
+
+// primary is the field the cursor edits for the active key; the others
+// with that key are copied from it when the cursor moves on.
+func (s *snippetSession) primary() int {
+	for i, f := range s.fields {
+		if f.Key == s.keys[s.active] {
+			return i
+		}
+	}
+	return -1
+}
+
+// enterField puts the cursor at the end of the placeholder for keys[i].
+func (m *Model) enterField(i int) {
+	s := m.snippet
+	s.active = i
+	f := s.fields[s.primary()]
+	s.fresh = f.End > f.Start
+	m.setCursorOffset(f.End)
+}
+
+// finishSnippet leaves the snippet at ${0}, or the end of the inserted
+// text.
+func (m *Model) finishSnippet() {
+	s := m.snippet
+	m.snippet = nil
+	for _, f := range s.fields {
+		if f.Key == "0" {
+			m.setCursorOffset(f.Start)
+			return
+		}
+	}
+}
+
+// updateSnippet handles keys while an inserted snippet has placeholders
+// left to fill.
+func (m Model) updateSnippet(msg tea.KeyMsg) (Model, tea.Cmd) {
+	s := m.snippet
+	switch msg.String() {
+	case "tab", "shift+tab":
+		if m.showingCompletions {
+			break
+		}
+		m.mirrorField()
+		next := s.active + 1
+		if msg.String() == "shift+tab" {
+			next = max(0, s.active-1)
+		}
+		if next >= len(s.keys) {
+			m.finishSnippet()
+		} else {
+			m.enterField(next)
+		}
+		return m, nil
+
+	case "esc":
+		if m.showingCompletions {
+			break
+		}
+		m.mirrorField()
+		m.snippet = nil
+		return m, nil
+
+	case "ctrl+k", "ctrl+l", "ctrl+r", "ctrl+e", "f5", "alt+enter", "f6":
+		// these replace or run the whole content
+		m.mirrorField()
+		m.snippet = nil
+		return m.Update(msg)
+
+	case "backspace", "delete":
+		if s.fresh {
+			m.replaceField("")
+			return m, nil
+		}
+
+	default:
+		if s.fresh && (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) {
+			m.replaceField("")
+		}
+	}
+
+	// edit as usual, then follow what changed in the active field
+	before := len(m.textarea.Value())
+	m.snippet = nil
+	m, cmd := m.Update(msg)
+	m.snippet = s
+	m.followField(before)
+	return m, cmd
+}
+
+// replaceField sets the text of the active placeholder, leaving the
+// cursor at its end.
+func (m *Model) replaceField(text string) {
+	s := m.snippet
+	i := s.primary()
+	f := s.fields[i]
+	val := m.textarea.Value()
+	m.textarea.SetValue(val[:f.Start] + text + val[f.End:])
+	s.shift(i, f.End, len(text)-(f.End-f.Start))
+	s.fresh = false
+	m.setCursorOffset(s.fields[i].End)
+}
+
+// shift moves the end of field i and every field after offset by delta.
+func (s *snippetSession) shift(i, offset, delta int) {
+	for j := range s.fields {
+		switch {
+		case j == i:
+			s.fields[j].End += delta
+		case s.fields[j].Start >= offset:
+			s.fields[j].Start += delta
+			s.fields[j].End += delta
+		}
+	}
+}
+
+// followField grows or shrinks the active placeholder by what an edit
+// changed, and ends the session when the cursor has left it.
+func (m *Model) followField(before int) {
+	s := m.snippet
+	i := s.primary()
+	f := s.fields[i]
+	delta := len(m.textarea.Value()) - before
+	if delta != 0 {
+		s.fresh = false
+		s.shift(i, f.End, delta)
+	}
+	f = s.fields[i]
+	if cur := m.cursorOffset(); f.End < f.Start || cur < f.Start || cur > f.End {
+		m.snippet = nil
+	}
+}
+
+// mirrorField copies the active placeholder's text to the others with
+// the same key.
+func (m *Model) mirrorField() {
+	s := m.snippet
+	i := s.primary()
+	if i < 0 {
+		return
+	}
+	val := m.textarea.Value()
+	text := val[s.fields[i].Start:s.fields[i].End]
+	changed := false
+	for j := range s.fields {
+		f := s.fields[j]
+		if j == i || f.Key != s.fields[i].Key || val[f.Start:f.End] == text {
+			continue
+		}
+		val = val[:f.Start] + text + val[f.End:]
+		s.shift(j, f.End, len(text)-(f.End-f.Start))
+		changed = true
+	}
+	if changed {
+		cursor := s.fields[i].End
+		m.textarea.SetValue(val)
+		m.setCursorOffset(cursor)
+	}
+}
+
+// snippetFieldAt reports whether byte pos is inside a placeholder of the
+// snippet being filled, and whether that placeholder is the active one.
+func (m Model) snippetFieldAt(pos int) (inField, active bool) {
+	s := m.snippet
+	if s == nil {
+		return false, false
+	}
+	for _, f := range s.fields {
+		if f.Key != "0" && pos >= f.Start && pos < f.End {
+			return true, f.Key == s.keys[s.active]
+		}
+	}
+	return false, false
+}
+
+// beforeCursor returns the content up to the cursor.
+func (m Model) beforeCursor() string {
+	return m.textarea.Value()[:m.cursorOffset()]
+}
+
+// cursorOffset returns the byte offset of the cursor in the content.
+func (m Model) cursorOffset() int {
+	lines := strings.Split(m.textarea.Value(), "\n")
+	row := min(m.textarea.Line(), len(lines)-1)
+	info := m.textarea.LineInfo()
+	off := 0
+	for _, l := range lines[:row] {
+		off += len(l) + 1
+	}
+	runes := []rune(lines[row])
+	col := min(info.StartColumn+info.ColumnOffset, len(runes))
+	return off + len(string(runes[:col]))
+}
+
+// setCursorOffset moves the cursor to a byte offset in the content.
+func (m *Model) setCursorOffset(off int) {
+	val := m.textarea.Value()
+	off = max(0, min(off, len(val)))
+	before := val[:off]
+	row := strings.Count(before, "\n")
+	col := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:])
+	for m.textarea.Line() > row {
+		m.textarea.CursorUp()
+	}
+	for m.textarea.Line() < row {
+		m.textarea.CursorDown()
+	}
+	m.textarea.SetCursor(col)
+}
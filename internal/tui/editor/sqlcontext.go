@@ -0,0 +1,62 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/sql"
+)
+
+// tableRef is a table named in a FROM, JOIN, UPDATE or INTO clause.
+type tableRef struct {
+	schema string // "" when unqualified
+	table  string
+	alias  string // "" when the table has no alias
+}
+
+// key identifies the table's columns independently of its alias.
+func (r tableRef) key() string {
+	if r.schema == "" {
+		return r.table
+	}
+	return r.schema + "." + r.table
+}
+
+// stmtContext is what completion needs to know about the statement
+// around the cursor.
+type stmtContext struct {
+	tables []tableRef
+	clause string // last clause keyword before the cursor, upper case
+}
+
+// clauseKeywords are the keywords that change what may follow.
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "JOIN": true, "ON": true, "WHERE": true,
+	"GROUP": true, "ORDER": true, "HAVING": true, "SET": true, "VALUES": true,
+	"RETURNING": true, "USING": true, "LIMIT": true, "OFFSET": true,
+	"INTO": true, "UPDATE": true, "TABLE": true,
+}
+
+// analyzeStatement looks at the statement containing cursor (a byte
+// offset in text), collecting its tables and the clause at the cursor.
+func analyzeStatement(text string, cursor int) stmtContext {
+	stmt, ok := sql.StatementAt(text, cursor)
+	if !ok {
+		return stmtContext{}
+	}
+
+	var ctx stmtContext
+	for _, ref := range sql.Tables(stmt.Tokens) {
+		ctx.tables = append(ctx.tables, tableRef{schema: ref.Schema, table: ref.Name, alias: ref.Alias})
+	}
+	for i := len(stmt.Tokens) - 1; i >= 0; i-- {
+		t := stmt.Tokens[i]
+		if t.Pos >= cursor || t.Kind != sql.Keyword {
+			continue
+		}
+		if up := strings.ToUpper(t.Text); clauseKeywords[up] {
+			ctx.clause = up
+			break
+		}
+	}
+	return ctx
+}
@@ -19,6 +19,8 @@ const (
 	NodeSchema
 	NodeTable
 	NodeColumn
+	NodeView
+	NodeFunction
 )
 
 // TreeNode represents a single node in the schema tree.
@@ -47,15 +49,48 @@ type QuickQueryMsg struct {
 	Query string
 }
 
+// CompareSchemasMsg is sent when the user opens the schema comparison screen.
+type CompareSchemasMsg struct{}
+
+// DiagramMsg is sent when the user opens the ER diagram for a table, or
+// for a whole schema when Table is empty.
+type DiagramMsg struct {
+	Schema string
+	Table  string
+}
+
+// DumpSchemaMsg is sent when the user asks for a schema-only SQL dump.
+// An empty Schema means every schema in the database.
+type DumpSchemaMsg struct {
+	Schema string
+}
+
+// PrefetchColumnsMsg is sent when a schema is expanded, asking for the
+// columns of its tables that have not been loaded yet.
+type PrefetchColumnsMsg struct {
+	Schema string
+	Tables []string
+}
+
+// RefreshSchemaMsg is sent when the user asks to reload the schema tree.
+type RefreshSchemaMsg struct{}
+
 // Model is the explorer (schema tree) component.
 type Model struct {
-	tree    *TreeNode
-	items   []flatItem
-	cursor  int
-	width   int
-	height  int
-	focused bool
-	loading bool
+	tree       *TreeNode
+	items      []flatItem
+	cursor     int
+	width      int
+	height     int
+	focused    bool
+	loading    bool
+	refreshing bool
+	prefetch   map[string][2]int // schema -> tables done, total while loading columns
+
+	// fuzzy finder
+	finder      *finder
+	columnIndex []database.ColumnRef // every column, nil until loaded
+	pendingJump string               // node to select once its table's columns arrive
 }
 
 // New creates a new explorer model.
@@ -84,6 +119,12 @@ func (m *Model) SetLoading(l bool) {
 	m.loading = l
 }
 
+// SetRefreshing marks the tree as being checked for changes in the
+// background while it stays usable.
+func (m *Model) SetRefreshing(r bool) {
+	m.refreshing = r
+}
+
 // ColumnsLoadedMsg signals that columns have been loaded for a table.
 type ColumnsLoadedMsg struct {
 	Schema  string
@@ -92,8 +133,38 @@ type ColumnsLoadedMsg struct {
 	Err     error
 }
 
-// SetTree populates the explorer from a schema tree.
+// SetColumnsProgress records how many of a schema's tables have their
+// columns loaded. The schema leaves the progress display once done
+// reaches total.
+func (m *Model) SetColumnsProgress(schema string, done, total int) {
+	if m.prefetch == nil {
+		m.prefetch = make(map[string][2]int)
+	}
+	if done >= total {
+		delete(m.prefetch, schema)
+		return
+	}
+	m.prefetch[schema] = [2]int{done, total}
+}
+
+// SetTree populates the explorer from a schema tree. Schemas and tables
+// that were already in the tree keep their expansion and loaded columns,
+// and the cursor stays on the same node when it still exists.
 func (m *Model) SetTree(schema *app.SchemaTree) {
+	prev := make(map[string]*TreeNode)
+	if m.tree != nil {
+		for _, s := range m.tree.Children {
+			prev[nodeKey(s)] = s
+			for _, t := range s.Children {
+				prev[nodeKey(t)] = t
+			}
+		}
+	}
+	var cursorKey string
+	if m.cursor >= 0 && m.cursor < len(m.items) {
+		cursorKey = nodeKey(m.items[m.cursor].node)
+	}
+
 	root := &TreeNode{
 		Kind:     NodeDatabase,
 		Name:     schema.Database,
@@ -108,21 +179,78 @@ func (m *Model) SetTree(schema *app.SchemaTree) {
 			Expanded: false,
 			Loaded:   true,
 		}
-		for _, t := range s.Tables {
-			tableNode := &TreeNode{
-				Kind:   NodeTable,
-				Name:   t,
-				Schema: s.Name,
-				Loaded: false,
+		if old, ok := prev[nodeKey(schemaNode)]; ok {
+			schemaNode.Expanded = old.Expanded
+		}
+		add := func(kind NodeKind, names []string) {
+			for _, name := range names {
+				node := &TreeNode{
+					Kind:   kind,
+					Name:   name,
+					Schema: s.Name,
+					Loaded: kind == NodeFunction, // functions have no children
+				}
+				if old, ok := prev[nodeKey(node)]; ok {
+					node.Expanded = old.Expanded
+					node.Loaded = old.Loaded
+					node.Children = old.Children
+					node.RowCount = old.RowCount
+				}
+				schemaNode.Children = append(schemaNode.Children, node)
 			}
-			schemaNode.Children = append(schemaNode.Children, tableNode)
 		}
+		add(NodeTable, s.Tables)
+		add(NodeView, s.Views)
+		add(NodeFunction, s.Functions)
 		root.Children = append(root.Children, schemaNode)
 	}
 
 	m.tree = root
 	m.flatten()
 	m.loading = false
+	m.refreshing = false
+	m.moveCursorTo(cursorKey)
+	// the column index may no longer match the tree
+	m.columnIndex = nil
+}
+
+// InvalidateTables forgets the columns of the given schema.table tables
+// or views so they are fetched again the next time they are expanded.
+func (m *Model) InvalidateTables(keys []string) {
+	cursorKey := ""
+	if m.cursor >= 0 && m.cursor < len(m.items) {
+		cursorKey = nodeKey(m.items[m.cursor].node)
+	}
+	for _, key := range keys {
+		schema, table, _ := strings.Cut(key, ".")
+		m.visitTable(schema, table, func(node *TreeNode) {
+			node.Children = nil
+			node.Loaded = false
+			node.Expanded = false
+		})
+	}
+	m.flatten()
+	m.moveCursorTo(cursorKey)
+}
+
+// nodeKey identifies a node across tree rebuilds.
+func nodeKey(n *TreeNode) string {
+	return fmt.Sprintf("%d/%s/%s/%s", n.Kind, n.Schema, n.Table, n.Name)
+}
+
+// moveCursorTo puts the cursor on the node with the given key and
+// reports whether that node is visible.
+func (m *Model) moveCursorTo(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, item := range m.items {
+		if nodeKey(item.node) == key {
+			m.cursor = i
+			return true
+		}
+	}
+	return false
 }
 
 // SetColumns adds column nodes to a table node.
@@ -144,6 +272,9 @@ func (m *Model) SetColumns(schema, table string, columns []database.Column) {
 		node.Loaded = true
 	})
 	m.flatten()
+	if m.pendingJump != "" && m.moveCursorTo(m.pendingJump) {
+		m.pendingJump = ""
+	}
 }
 
 func (m *Model) visitTable(schema, table string, fn func(*TreeNode)) {
@@ -155,7 +286,7 @@ func (m *Model) visitTable(schema, table string, fn func(*TreeNode)) {
 			continue
 		}
 		for _, t := range s.Children {
-			if t.Name == table {
+			if t.Name == table && (t.Kind == NodeTable || t.Kind == NodeView) {
 				fn(t)
 				return
 			}
@@ -164,13 +295,14 @@ func (m *Model) visitTable(schema, table string, fn func(*TreeNode)) {
 }
 
 // SelectedTable returns the schema and table name of the currently selected table node, if any.
+// Views count as tables here since they can be queried the same way.
 func (m Model) SelectedTable() (schema, table string, ok bool) {
 	if m.cursor < 0 || m.cursor >= len(m.items) {
 		return "", "", false
 	}
 	node := m.items[m.cursor].node
 	switch node.Kind {
-	case NodeTable:
+	case NodeTable, NodeView:
 		return node.Schema, node.Name, true
 	case NodeColumn:
 		return node.Schema, node.Table, true
@@ -178,6 +310,33 @@ func (m Model) SelectedTable() (schema, table string, ok bool) {
 	return "", "", false
 }
 
+// openDiagram opens the ER diagram of the table under the cursor, or of
+// the schema when the cursor is on one.
+func (m *Model) openDiagram() tea.Cmd {
+	msg := DiagramMsg{}
+	if schema, table, ok := m.SelectedTable(); ok && m.items[m.cursor].node.Kind != NodeView {
+		msg.Schema, msg.Table = schema, table
+	} else if schema := m.SelectedSchema(); schema != "" {
+		msg.Schema = schema
+	} else {
+		return nil
+	}
+	return func() tea.Msg { return msg }
+}
+
+// SelectedSchema returns the schema the cursor is in, or "" on the
+// database node.
+func (m Model) SelectedSchema() string {
+	if m.cursor < 0 || m.cursor >= len(m.items) {
+		return ""
+	}
+	node := m.items[m.cursor].node
+	if node.Kind == NodeSchema {
+		return node.Name
+	}
+	return node.Schema
+}
+
 // flatten rebuilds the flat item list from the tree.
 func (m *Model) flatten() {
 	m.items = nil
@@ -211,7 +370,12 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.finder != nil {
+			return m.updateFinder(msg)
+		}
 		switch msg.String() {
+		case "/":
+			return m, m.openFinder()
 		case "up", "k":
 			if m.cursor > 0 {
 				m.cursor--
@@ -230,6 +394,19 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case "d":
 			// Describe table (count query)
 			return m, m.describeTable()
+		case "C":
+			return m, func() tea.Msg { return CompareSchemasMsg{} }
+		case "E":
+			return m, m.openDiagram()
+		case "X":
+			schema := m.SelectedSchema()
+			return m, func() tea.Msg { return DumpSchemaMsg{Schema: schema} }
+		case "r":
+			if m.tree == nil || m.refreshing {
+				return m, nil
+			}
+			m.refreshing = true
+			return m, func() tea.Msg { return RefreshSchemaMsg{} }
 		}
 	}
 
@@ -243,12 +420,7 @@ func (m *Model) quickSelect() tea.Cmd {
 		return nil
 	}
 
-	var query string
-	if schema == "public" {
-		query = fmt.Sprintf("SELECT * FROM %s LIMIT 100;", quoteIdent(table))
-	} else {
-		query = fmt.Sprintf("SELECT * FROM %s.%s LIMIT 100;", quoteIdent(schema), quoteIdent(table))
-	}
+	query := fmt.Sprintf("SELECT * FROM %s LIMIT 100;", database.QualifiedName(schema, table))
 
 	return func() tea.Msg {
 		return QuickQueryMsg{Query: query}
@@ -262,36 +434,21 @@ func (m *Model) describeTable() tea.Cmd {
 		return nil
 	}
 
-	var query string
-	if schema == "public" {
-		query = fmt.Sprintf("SELECT count(*) FROM %s;", quoteIdent(table))
-	} else {
-		query = fmt.Sprintf("SELECT count(*) FROM %s.%s;", quoteIdent(schema), quoteIdent(table))
-	}
+	query := fmt.Sprintf("SELECT count(*) FROM %s;", database.QualifiedName(schema, table))
 
 	return func() tea.Msg {
 		return QuickQueryMsg{Query: query}
 	}
 }
 
-// quoteIdent quotes an identifier if it contains special characters.
-func quoteIdent(name string) string {
-	for _, c := range name {
-		if !((c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_') {
-			return "\"" + strings.ReplaceAll(name, "\"", "\"\"") + "\""
-		}
-	}
-	return name
-}
-
 func (m *Model) toggleExpand() tea.Cmd {
 	if m.cursor < 0 || m.cursor >= len(m.items) {
 		return nil
 	}
 	node := m.items[m.cursor].node
 
-	// Columns have no children
-	if node.Kind == NodeColumn {
+	// Columns and functions have no children
+	if node.Kind == NodeColumn || node.Kind == NodeFunction {
 		return nil
 	}
 
@@ -304,8 +461,13 @@ func (m *Model) toggleExpand() tea.Cmd {
 	node.Expanded = true
 	m.flatten()
 
-	// If this is a table and columns aren't loaded yet, request them
-	if node.Kind == NodeTable && !node.Loaded {
+	// Expanding a schema starts loading its tables' columns in the background
+	if node.Kind == NodeSchema {
+		return m.prefetchColumns(node)
+	}
+
+	// If this is a table or view and columns aren't loaded yet, request them
+	if (node.Kind == NodeTable || node.Kind == NodeView) && !node.Loaded {
 		schema := node.Schema
 		table := node.Name
 		return func() tea.Msg {
@@ -316,6 +478,24 @@ func (m *Model) toggleExpand() tea.Cmd {
 	return nil
 }
 
+func (m *Model) prefetchColumns(schema *TreeNode) tea.Cmd {
+	if _, busy := m.prefetch[schema.Name]; busy {
+		return nil
+	}
+	var tables []string
+	for _, t := range schema.Children {
+		if t.Kind != NodeFunction && !t.Loaded {
+			tables = append(tables, t.Name)
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	m.SetColumnsProgress(schema.Name, 0, len(tables))
+	msg := PrefetchColumnsMsg{Schema: schema.Name, Tables: tables}
+	return func() tea.Msg { return msg }
+}
+
 func (m *Model) collapse() tea.Cmd {
 	if m.cursor < 0 || m.cursor >= len(m.items) {
 		return nil
@@ -351,6 +531,16 @@ func (m Model) View() string {
 		Padding(0, 1)
 
 	title := titleStyle.Render("Schema Explorer")
+	if m.refreshing {
+		title += theme.StyleMuted.Render("↻ refreshing")
+	} else if len(m.prefetch) > 0 {
+		done, total := 0, 0
+		for _, p := range m.prefetch {
+			done += p[0]
+			total += p[1]
+		}
+		title += theme.StyleMuted.Render(fmt.Sprintf("columns %d/%d", done, total))
+	}
 
 	if m.loading {
 		return title + "\n" + theme.StyleMuted.Render("  Loading...")
@@ -360,6 +550,10 @@ func (m Model) View() string {
 		return title + "\n" + theme.StyleMuted.Render("  No connection")
 	}
 
+	if m.finder != nil {
+		return title + "\n" + m.viewFinder()
+	}
+
 	var b strings.Builder
 	b.WriteString(title)
 	b.WriteString("\n")
@@ -406,7 +600,7 @@ func (m Model) renderNode(item flatItem, selected bool) string {
 		} else {
 			icon = "▶ "
 		}
-	case NodeTable:
+	case NodeTable, NodeView:
 		if node.Expanded {
 			icon = "▼ "
 		} else {
@@ -414,12 +608,17 @@ func (m Model) renderNode(item flatItem, selected bool) string {
 		}
 	case NodeColumn:
 		icon = "  "
+	case NodeFunction:
+		icon = "ƒ "
 	}
 
 	name := node.Name
 	if node.Kind == NodeColumn && node.DataType != "" {
 		name = fmt.Sprintf("%s %s", node.Name, lipgloss.NewStyle().Foreground(theme.ColorMuted).Render(node.DataType))
 	}
+	if node.Kind == NodeView {
+		name = fmt.Sprintf("%s %s", node.Name, lipgloss.NewStyle().Foreground(theme.ColorMuted).Render("view"))
+	}
 
 	line := indent + icon + name
 
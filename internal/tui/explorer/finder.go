@@ -0,0 +1,300 @@
+package explorer
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/fuzzy"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// LoadColumnIndexMsg is sent when the finder opens before the column
+// index has been loaded.
+type LoadColumnIndexMsg struct{}
+
+// maxFinderResults caps how many matches are ranked for display.
+const maxFinderResults = 200
+
+// finderItem is one searchable object.
+type finderItem struct {
+	kind     NodeKind
+	schema   string
+	table    string // parent table or view, for columns
+	name     string
+	dataType string
+	text     string // qualified name, what the pattern is matched against
+}
+
+// finder is the fuzzy search overlay of the explorer.
+type finder struct {
+	input   textinput.Model
+	items   []finderItem
+	matches []fuzzy.Match
+	cursor  int
+	loading bool // waiting for the column index
+	err     error
+}
+
+// Searching reports whether the finder is open and capturing keys.
+func (m Model) Searching() bool {
+	return m.finder != nil
+}
+
+// SetColumnIndex provides the columns of every table and view so the
+// finder can search columns that have not been loaded into the tree.
+func (m *Model) SetColumnIndex(cols []database.ColumnRef, err error) {
+	if err == nil {
+		m.columnIndex = cols
+	}
+	if m.finder != nil {
+		m.finder.loading = false
+		m.finder.err = err
+		m.finder.items = m.finderItems()
+		m.finder.search()
+	}
+}
+
+func (m *Model) openFinder() tea.Cmd {
+	if m.tree == nil {
+		return nil
+	}
+	ti := widget.NewInput()
+	ti.Prompt = "/ "
+	ti.Placeholder = "table, column, view, function..."
+	ti.Focus()
+	m.finder = &finder{input: ti}
+	m.finder.items = m.finderItems()
+	m.finder.search()
+	if m.columnIndex != nil {
+		return nil
+	}
+	m.finder.loading = true
+	return func() tea.Msg { return LoadColumnIndexMsg{} }
+}
+
+// finderItems lists every object in the tree plus every indexed column.
+// Until the index arrives, the columns already loaded in the tree stand in.
+func (m Model) finderItems() []finderItem {
+	var items []finderItem
+	for _, s := range m.tree.Children {
+		items = append(items, finderItem{kind: NodeSchema, schema: s.Name, name: s.Name, text: s.Name})
+		for _, t := range s.Children {
+			items = append(items, finderItem{
+				kind:   t.Kind,
+				schema: s.Name,
+				name:   t.Name,
+				text:   s.Name + "." + t.Name,
+			})
+			if m.columnIndex != nil {
+				continue
+			}
+			for _, c := range t.Children {
+				items = append(items, finderItem{
+					kind:     NodeColumn,
+					schema:   s.Name,
+					table:    t.Name,
+					name:     c.Name,
+					dataType: c.DataType,
+					text:     s.Name + "." + t.Name + "." + c.Name,
+				})
+			}
+		}
+	}
+	for _, c := range m.columnIndex {
+		items = append(items, finderItem{
+			kind:     NodeColumn,
+			schema:   c.Schema,
+			table:    c.Table,
+			name:     c.Name,
+			dataType: c.DataType,
+			text:     c.Schema + "." + c.Table + "." + c.Name,
+		})
+	}
+	return items
+}
+
+// search ranks the items against the current input.
+func (f *finder) search() {
+	texts := make([]string, len(f.items))
+	for i, it := range f.items {
+		texts[i] = it.text
+	}
+	f.matches = fuzzy.Rank(f.input.Value(), texts)
+	if len(f.matches) > maxFinderResults {
+		f.matches = f.matches[:maxFinderResults]
+	}
+	f.cursor = 0
+}
+
+func (m Model) updateFinder(msg tea.KeyMsg) (Model, tea.Cmd) {
+	f := m.finder
+	switch msg.String() {
+	case "esc":
+		m.finder = nil
+		return m, nil
+	case "enter":
+		if f.cursor < len(f.matches) {
+			item := f.items[f.matches[f.cursor].Index]
+			m.finder = nil
+			return m, m.jumpTo(item)
+		}
+		return m, nil
+	case "up", "ctrl+p", "ctrl+k":
+		if f.cursor > 0 {
+			f.cursor--
+		}
+		return m, nil
+	case "down", "ctrl+n", "ctrl+j":
+		if f.cursor < len(f.matches)-1 {
+			f.cursor++
+		}
+		return m, nil
+	}
+
+	before := f.input.Value()
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	if f.input.Value() != before {
+		f.search()
+	}
+	return m, cmd
+}
+
+// jumpTo expands the parents of an item and puts the cursor on it. A
+// column of a table whose columns are not loaded yet is selected once
+// they arrive.
+func (m *Model) jumpTo(item finderItem) tea.Cmd {
+	var schemaNode, tableNode *TreeNode
+	for _, s := range m.tree.Children {
+		if s.Name == item.schema {
+			schemaNode = s
+		}
+	}
+	if schemaNode == nil {
+		return nil
+	}
+	m.tree.Expanded = true
+	m.pendingJump = ""
+
+	target := &TreeNode{Kind: item.kind, Schema: item.schema, Name: item.name}
+	switch item.kind {
+	case NodeSchema:
+		target = schemaNode
+	case NodeColumn:
+		target.Table = item.table
+		schemaNode.Expanded = true
+		m.visitTable(item.schema, item.table, func(n *TreeNode) { tableNode = n })
+		if tableNode == nil {
+			break
+		}
+		tableNode.Expanded = true
+		if !tableNode.Loaded {
+			m.pendingJump = nodeKey(target)
+			m.flatten()
+			m.moveCursorTo(nodeKey(tableNode))
+			schema, table := tableNode.Schema, tableNode.Name
+			return func() tea.Msg { return requestColumnsMsg{Schema: schema, Table: table} }
+		}
+	default:
+		schemaNode.Expanded = true
+	}
+
+	m.flatten()
+	if !m.moveCursorTo(nodeKey(target)) {
+		// the object is not in the tree, e.g. a materialized view's column
+		if tableNode != nil {
+			m.moveCursorTo(nodeKey(tableNode))
+		} else {
+			m.moveCursorTo(nodeKey(schemaNode))
+		}
+	}
+	return nil
+}
+
+func (m Model) viewFinder() string {
+	f := m.finder
+	var b strings.Builder
+	b.WriteString(f.input.View())
+	b.WriteString("\n")
+
+	switch {
+	case f.err != nil:
+		b.WriteString(theme.StyleError.Render("  Column index: "+f.err.Error()) + "\n")
+	case f.loading:
+		b.WriteString(theme.StyleMuted.Render("  Indexing columns...") + "\n")
+	}
+
+	if len(f.matches) == 0 {
+		b.WriteString(theme.StyleMuted.Render("  No matches"))
+		return b.String()
+	}
+
+	visible := max(1, m.height-4)
+	start := 0
+	if f.cursor >= visible {
+		start = f.cursor - visible + 1
+	}
+	end := min(len(f.matches), start+visible)
+
+	var lines []string
+	for i := start; i < end; i++ {
+		lines = append(lines, m.renderMatch(f.items[f.matches[i].Index], f.matches[i], i == f.cursor))
+	}
+	b.WriteString(strings.Join(lines, "\n"))
+	return b.String()
+}
+
+var finderKinds = map[NodeKind]string{
+	NodeSchema:   "sch ",
+	NodeTable:    "tbl ",
+	NodeView:     "view",
+	NodeFunction: "fn  ",
+	NodeColumn:   "col ",
+}
+
+// renderMatch draws one result with its matched characters highlighted.
+func (m Model) renderMatch(item finderItem, match fuzzy.Match, selected bool) string {
+	textStyle := lipgloss.NewStyle()
+	if selected {
+		textStyle = textStyle.Foreground(theme.ColorHighlight).Bold(true)
+	}
+	hitStyle := lipgloss.NewStyle().Foreground(theme.ColorPrimary).Bold(true).Underline(true)
+
+	hits := make(map[int]bool, len(match.Positions))
+	for _, p := range match.Positions {
+		hits[p] = true
+	}
+
+	// leave room for the kind tag and the selection marker
+	room := max(4, m.width-10)
+	runes := []rune(item.text)
+	truncated := len(runes) > room
+	if truncated {
+		runes = runes[:room-1]
+	}
+
+	var b strings.Builder
+	marker := "  "
+	if selected {
+		marker = "> "
+	}
+	b.WriteString(textStyle.Render(marker))
+	b.WriteString(theme.StyleMuted.Render(finderKinds[item.kind]) + " ")
+	for i, r := range runes {
+		if hits[i] {
+			b.WriteString(hitStyle.Render(string(r)))
+		} else {
+			b.WriteString(textStyle.Render(string(r)))
+		}
+	}
+	if truncated {
+		b.WriteString(theme.StyleMuted.Render("…"))
+	} else if item.dataType != "" && len(runes)+len(item.dataType)+1 <= room {
+		b.WriteString(" " + theme.StyleMuted.Render(item.dataType))
+	}
+	return b.String()
+}
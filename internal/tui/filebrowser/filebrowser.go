@@ -0,0 +1,368 @@
+// Package filebrowser picks a .sql file to open into the editor, or the
+// file to save the editor's content to, walking directories from the
+// keyboard.
+package filebrowser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// CloseMsg asks the app to leave the browser.
+type CloseMsg struct{}
+
+// OpenMsg asks the app to open a file into the editor.
+type OpenMsg struct {
+	Path string
+}
+
+// SaveMsg asks the app to save the editor's content to a file.
+type SaveMsg struct {
+	Path string
+}
+
+// Mode is what the browser picks a file for.
+type Mode int
+
+const (
+	Open Mode = iota
+	Save
+)
+
+// entry is a directory or .sql file listed in the browser.
+type entry struct {
+	name string
+	dir  bool
+}
+
+// Model is the file browser.
+type Model struct {
+	mode     Mode
+	dir      string
+	entries  []entry
+	filtered []entry
+
+	input  textinput.Model // filter when opening, file name when saving
+	cursor int
+	scroll int
+
+	overwrite string // existing file Enter was pressed on once
+	message   string
+	err       bool
+
+	width  int
+	height int
+}
+
+// New creates a browser for mode showing dir. name is the file name
+// offered when saving.
+func New(mode Mode, dir, name string) Model {
+	ti := widget.NewInput()
+	ti.Prompt = "/ "
+	ti.Placeholder = "filter, or type a path..."
+	if mode == Save {
+		ti.Prompt = "Save as: "
+		ti.Placeholder = "query.sql"
+		ti.SetValue(name)
+		ti.CursorEnd()
+	}
+	ti.Focus()
+
+	m := Model{mode: mode, input: ti}
+	m.chdir(dir)
+	return m
+}
+
+// SetSize updates the component dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.input.Width = max(10, w-12)
+}
+
+// SetMessage shows a status line under the list; isErr styles it as an
+// error.
+func (m *Model) SetMessage(msg string, isErr bool) {
+	m.message = msg
+	m.err = isErr
+}
+
+// chdir lists dir: its parent, subdirectories and .sql files. Hidden
+// entries are left out.
+func (m *Model) chdir(dir string) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		m.SetMessage(err.Error(), true)
+		return
+	}
+	m.dir = dir
+	m.entries = nil
+	if parent := filepath.Dir(dir); parent != dir {
+		m.entries = append(m.entries, entry{name: "..", dir: true})
+	}
+	var files []entry
+	for _, e := range list {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		isDir := e.IsDir()
+		if e.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		switch {
+		case isDir:
+			m.entries = append(m.entries, entry{name: name, dir: true})
+		case strings.EqualFold(filepath.Ext(name), ".sql"):
+			files = append(files, entry{name: name})
+		}
+	}
+	m.entries = append(m.entries, files...)
+	m.message = ""
+	m.overwrite = ""
+	if m.mode == Open {
+		m.input.SetValue("")
+	}
+	m.filter()
+}
+
+func (m *Model) filter() {
+	m.cursor = 0
+	m.scroll = 0
+	text := strings.ToLower(m.input.Value())
+	if m.mode == Save || text == "" || strings.ContainsRune(text, filepath.Separator) {
+		m.filtered = m.entries
+		return
+	}
+	m.filtered = nil
+	for _, e := range m.entries {
+		if strings.Contains(strings.ToLower(e.name), text) {
+			m.filtered = append(m.filtered, e)
+		}
+	}
+}
+
+func (m Model) selected() (entry, bool) {
+	if m.cursor < len(m.filtered) {
+		return m.filtered[m.cursor], true
+	}
+	return entry{}, false
+}
+
+// resolve turns a typed path into an absolute one, relative to the
+// directory shown; ~ is the home directory.
+func (m Model) resolve(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + rest
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// Update handles messages for the browser.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "esc":
+		return m, func() tea.Msg { return CloseMsg{} }
+
+	case "up", "ctrl+p":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		m.clampScroll()
+		return m, nil
+
+	case "down", "ctrl+n":
+		if m.cursor < len(m.filtered)-1 {
+			m.cursor++
+		}
+		m.clampScroll()
+		return m, nil
+
+	case "pgup":
+		m.cursor = max(0, m.cursor-m.listHeight())
+		m.clampScroll()
+		return m, nil
+
+	case "pgdown":
+		m.cursor = max(0, min(len(m.filtered)-1, m.cursor+m.listHeight()))
+		m.clampScroll()
+		return m, nil
+
+	case "tab":
+		// enter the selected directory, or take the selected file's name
+		if e, ok := m.selected(); ok {
+			if e.dir {
+				m.chdir(filepath.Join(m.dir, e.name))
+			} else if m.mode == Save {
+				m.input.SetValue(e.name)
+				m.input.CursorEnd()
+			}
+		}
+		return m, nil
+
+	case "backspace":
+		if m.input.Value() == "" {
+			m.chdir(filepath.Dir(m.dir))
+			return m, nil
+		}
+
+	case "enter":
+		if m.mode == Save {
+			return m.save()
+		}
+		return m.open()
+	}
+
+	var cmd tea.Cmd
+	before := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != before {
+		m.overwrite = ""
+		m.filter()
+	}
+	return m, cmd
+}
+
+// open enters the selected directory or opens the selected file. A typed
+// path is opened, or entered, directly.
+func (m Model) open() (Model, tea.Cmd) {
+	text := strings.TrimSpace(m.input.Value())
+	if strings.ContainsRune(text, filepath.Separator) || strings.HasPrefix(text, "~") {
+		path := m.resolve(text)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			m.chdir(path)
+			return m, nil
+		}
+		return m, func() tea.Msg { return OpenMsg{Path: path} }
+	}
+	e, ok := m.selected()
+	if !ok {
+		return m, nil
+	}
+	path := filepath.Join(m.dir, e.name)
+	if e.dir {
+		m.chdir(path)
+		return m, nil
+	}
+	return m, func() tea.Msg { return OpenMsg{Path: path} }
+}
+
+// save asks to save to the typed name, with .sql added when it has no
+// extension, or enters it when it names a directory. An existing file is
+// only overwritten on a second Enter.
+func (m Model) save() (Model, tea.Cmd) {
+	name := strings.TrimSpace(m.input.Value())
+	if name == "" {
+		if e, ok := m.selected(); ok && e.dir {
+			m.chdir(filepath.Join(m.dir, e.name))
+		}
+		return m, nil
+	}
+	path := m.resolve(name)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		m.chdir(path)
+		m.input.SetValue("")
+		return m, nil
+	}
+	if filepath.Ext(path) == "" {
+		path += ".sql"
+	}
+	if _, err := os.Stat(path); err == nil && m.overwrite != path {
+		m.overwrite = path
+		m.SetMessage(filepath.Base(path)+" exists: press Enter again to overwrite it", true)
+		return m, nil
+	}
+	return m, func() tea.Msg { return SaveMsg{Path: path} }
+}
+
+// listHeight is how many entries fit on screen.
+func (m Model) listHeight() int {
+	return max(3, m.height-5)
+}
+
+func (m *Model) clampScroll() {
+	m.scroll = widget.Scroll(m.cursor, m.scroll, m.listHeight())
+}
+
+// View renders the browser.
+func (m Model) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(0, 1)
+
+	title := "Open File"
+	hints := "  ↑/↓ navigate | type to filter or a path | Enter open | Tab enter directory | Backspace parent | Esc close"
+	if m.mode == Save {
+		title = "Save File"
+		hints = "  ↑/↓ navigate | Enter save | Tab enter directory or take name | Esc cancel"
+	}
+
+	status := ""
+	if m.message != "" {
+		style := theme.StyleSuccess
+		if m.err {
+			style = theme.StyleError
+		}
+		status = style.Render("  " + m.message)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render(title)+"  "+theme.StyleMuted.Render(m.dir),
+		" "+m.input.View(),
+		m.viewList(),
+		status,
+		theme.StyleMuted.Render(hints),
+	)
+}
+
+func (m Model) viewList() string {
+	height := m.listHeight()
+	if len(m.filtered) == 0 {
+		msg := "  No .sql files or directories here"
+		if len(m.entries) > 0 {
+			msg = "  No matching files"
+		}
+		return lipgloss.NewStyle().Height(height).Render(theme.StyleMuted.Render(msg))
+	}
+
+	dirStyle := lipgloss.NewStyle().Foreground(theme.ColorPrimary)
+	var lines []string
+	for i := m.scroll; i < len(m.filtered) && i < m.scroll+height; i++ {
+		e := m.filtered[i]
+		name := e.name
+		style := lipgloss.NewStyle()
+		if e.dir {
+			name += string(filepath.Separator)
+			style = dirStyle
+		}
+		prefix := "  "
+		if i == m.cursor {
+			prefix = "> "
+			style = style.Background(lipgloss.Color("236")).Bold(true)
+		}
+		lines = append(lines, prefix+style.Render(widget.Truncate(name, m.width-4)))
+	}
+	return lipgloss.NewStyle().Height(height).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joacominatel/minadb/internal/tui/filebrowser"
+)
+
+type (
+	fileOpenedMsg struct {
+		path    string
+		content string
+		err     error
+	}
+	fileSavedMsg struct {
+		tab     int
+		path    string
+		content string
+		err     error
+	}
+	// externalEditedMsg carries a tab's content back from $EDITOR
+	externalEditedMsg struct {
+		tab     int
+		content string
+		err     error
+	}
+)
+
+// OpenFile loads a .sql file into the editor, for a file given on the
+// command line. A file that does not exist yet opens as an empty buffer
+// that saving creates.
+func (m *Model) OpenFile(path string) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	m.loadFile(path, string(data))
+	return nil
+}
+
+// loadFile shows a file's content: in the tab that already has it open,
+// in the active tab when that one is empty, or in a new tab.
+func (m *Model) loadFile(path, content string) {
+	for i, t := range m.tabs {
+		if t.path == path {
+			m.switchTab(i)
+			m.statusbar.SetMessage(filepath.Base(path) + " is already open")
+			return
+		}
+	}
+	if cur := m.tabs[m.activeTab]; cur.path != "" || cur.running || m.editor.Value() != "" {
+		m.newTab()
+	}
+	m.editor.SetQuery(content)
+	t := &m.tabs[m.activeTab]
+	t.path = path
+	t.name = filepath.Base(path)
+	// the editor expands tabs and drops carriage returns; compare
+	// against what it holds, not the bytes on disk
+	t.saved = m.editor.Value()
+}
+
+// tabValue returns the editor content of tab i.
+func (m Model) tabValue(i int) string {
+	if i == m.activeTab {
+		return m.editor.Value()
+	}
+	return m.tabs[i].editor.Value()
+}
+
+// dirty reports whether tab i holds a file with changes not saved to
+// it. Scratch buffers are never dirty: what they run is in the history.
+func (m Model) dirty(i int) bool {
+	t := m.tabs[i]
+	return t.path != "" && m.tabValue(i) != t.saved
+}
+
+// dirtyTabs returns the names of the tabs with unsaved changes.
+func (m Model) dirtyTabs() []string {
+	var names []string
+	for i, t := range m.tabs {
+		if m.dirty(i) {
+			names = append(names, t.name)
+		}
+	}
+	return names
+}
+
+// browseFiles opens the file browser next to the active tab's file, or
+// in the working directory.
+func (m Model) browseFiles(mode filebrowser.Mode) (tea.Model, tea.Cmd) {
+	dir, _ := os.Getwd()
+	name := ""
+	if path := m.tabs[m.activeTab].path; path != "" {
+		dir, name = filepath.Split(path)
+	}
+	m.files = filebrowser.New(mode, dir, name)
+	m.files.SetSize(m.width, m.height)
+	m.mode = ModeFiles
+	return m, nil
+}
+
+// saveTab saves the active tab to its file, or asks where to save it.
+func (m Model) saveTab() (tea.Model, tea.Cmd) {
+	t := m.tabs[m.activeTab]
+	if t.path == "" {
+		return m.browseFiles(filebrowser.Save)
+	}
+	return m, saveFileCmd(t.id, t.path, m.editor.Value())
+}
+
+// quit leaves the app, asking first when files have unsaved changes.
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	if names := m.dirtyTabs(); len(names) > 0 {
+		// the question shows in the main screen's status bar
+		m.mode = ModeMain
+		m.confirm = confirmQuit
+		m.statusbar.SetMessage("Unsaved changes in " + strings.Join(names, ", ") + ": quit anyway? (y/n)")
+		return m, nil
+	}
+	return m, tea.Quit
+}
+
+// closeActiveTab closes the active tab, asking first when its file has
+// unsaved changes.
+func (m Model) closeActiveTab() (tea.Model, tea.Cmd) {
+	if m.dirty(m.activeTab) {
+		m.confirm = confirmClose
+		m.statusbar.SetMessage("Unsaved changes in " + m.tabs[m.activeTab].name + ": close anyway? (y/n)")
+		return m, nil
+	}
+	m.closeTab()
+	return m, nil
+}
+
+// updateConfirm answers a pending quit or close question: y goes ahead,
+// any other key cancels.
+func (m Model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	action := m.confirm
+	m.confirm = confirmNone
+	m.statusbar.SetMessage("")
+	if k := msg.String(); k != "y" && k != "Y" && k != "ctrl+c" {
+		return m, nil
+	}
+	if action == confirmQuit {
+		return m, tea.Quit
+	}
+	m.closeTab()
+	return m, nil
+}
+
+func openFileCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		data, err := os.ReadFile(path)
+		return fileOpenedMsg{path: path, content: string(data), err: err}
+	}
+}
+
+func saveFileCmd(tab int, path, content string) tea.Cmd {
+	return func() tea.Msg {
+		data := content
+		if !strings.HasSuffix(data, "\n") {
+			data += "\n"
+		}
+		err := os.WriteFile(path, []byte(data), 0o644)
+		return fileSavedMsg{tab: tab, path: path, content: content, err: err}
+	}
+}
+
+// externalEditCmd suspends the TUI to edit content in $VISUAL or $EDITOR
+// through a temporary file, which is read back when the editor exits.
+func externalEditCmd(tab int, content string) tea.Cmd {
+	f, err := os.CreateTemp("", "minadb-*.sql")
+	if err != nil {
+		return func() tea.Msg { return externalEditedMsg{tab: tab, err: err} }
+	}
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return func() tea.Msg { return externalEditedMsg{tab: tab, err: err} }
+	}
+
+	args := strings.Fields(os.Getenv("VISUAL"))
+	if len(args) == 0 {
+		args = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	path := f.Name()
+	c := exec.Command(args[0], append(args[1:], path)...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return externalEditedMsg{tab: tab, err: err}
+		}
+		data, err := os.ReadFile(path)
+		edited := string(data)
+		// editors end the last line; keep the buffer as it was
+		if !strings.HasSuffix(content, "\n") {
+			edited = strings.TrimSuffix(edited, "\n")
+		}
+		return externalEditedMsg{tab: tab, content: edited, err: err}
+	})
+}
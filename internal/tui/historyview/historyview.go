@@ -0,0 +1,278 @@
+// Package historyview is the query history screen: every executed query
+// with where and how it ran, filtered by text and connection, to load
+// into the editor or run again.
+package historyview
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// CloseMsg asks the app to leave the history screen.
+type CloseMsg struct{}
+
+// EditMsg asks the app to load a query into the editor.
+type EditMsg struct {
+	Query string
+}
+
+// RunMsg asks the app to load a query into the editor and run it, with
+// the values its parameters were bound to.
+type RunMsg struct {
+	Query  string
+	Params []history.Param
+}
+
+// Model is the query history component.
+type Model struct {
+	entries  []history.Entry // newest first
+	filtered []history.Entry
+	conns    []string
+	conn     int // index into conns of the connection filter, -1 for all
+
+	input  textinput.Model
+	cursor int
+	scroll int
+
+	width  int
+	height int
+}
+
+// New creates a history screen over entries, newest first.
+func New(entries []history.Entry) Model {
+	ti := widget.NewInput()
+	ti.Prompt = "/ "
+	ti.Placeholder = "filter by text..."
+	ti.Focus()
+
+	m := Model{entries: entries, conns: history.Connections(entries), conn: -1, input: ti}
+	m.filter()
+	return m
+}
+
+// SetSize updates the component dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.input.Width = max(10, w-4)
+}
+
+func (m *Model) filter() {
+	conn := ""
+	if m.conn >= 0 {
+		conn = m.conns[m.conn]
+	}
+	m.filtered = history.Filter(m.entries, m.input.Value(), conn, false)
+	m.cursor = 0
+	m.scroll = 0
+}
+
+func (m Model) selected() (history.Entry, bool) {
+	if m.cursor < len(m.filtered) {
+		return m.filtered[m.cursor], true
+	}
+	return history.Entry{}, false
+}
+
+// Update handles messages for the history screen.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "esc":
+		return m, func() tea.Msg { return CloseMsg{} }
+
+	case "up", "ctrl+p":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		m.clampScroll()
+		return m, nil
+
+	case "down", "ctrl+n":
+		if m.cursor < len(m.filtered)-1 {
+			m.cursor++
+		}
+		m.clampScroll()
+		return m, nil
+
+	case "pgup":
+		m.cursor = max(0, m.cursor-m.listHeight())
+		m.clampScroll()
+		return m, nil
+
+	case "pgdown":
+		m.cursor = max(0, min(len(m.filtered)-1, m.cursor+m.listHeight()))
+		m.clampScroll()
+		return m, nil
+
+	case "tab":
+		m.conn++
+		if m.conn >= len(m.conns) {
+			m.conn = -1
+		}
+		m.filter()
+		return m, nil
+
+	case "shift+tab":
+		m.conn--
+		if m.conn < -1 {
+			m.conn = len(m.conns) - 1
+		}
+		m.filter()
+		return m, nil
+
+	case "enter":
+		if e, ok := m.selected(); ok {
+			return m, func() tea.Msg { return EditMsg{Query: e.Query} }
+		}
+		return m, nil
+
+	case "ctrl+e", "f5":
+		if e, ok := m.selected(); ok {
+			return m, func() tea.Msg { return RunMsg{Query: e.Query, Params: e.Params} }
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	before := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != before {
+		m.filter()
+	}
+	return m, cmd
+}
+
+// listHeight is how many entries fit above the detail of the selected one.
+func (m Model) listHeight() int {
+	return max(3, (m.height-6)*3/5)
+}
+
+func (m *Model) clampScroll() {
+	m.scroll = widget.Scroll(m.cursor, m.scroll, m.listHeight())
+}
+
+// View renders the history screen.
+func (m Model) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(0, 1)
+
+	conn := "all connections"
+	if m.conn >= 0 {
+		conn = m.conns[m.conn]
+	}
+	heading := fmt.Sprintf("%d of %d  ·  %s", len(m.filtered), len(m.entries), conn)
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Query History")+"  "+theme.StyleMuted.Render(heading),
+		" "+m.input.View(),
+		m.viewList(),
+		m.viewDetail(),
+		theme.StyleMuted.Render("  ↑/↓ navigate | type to filter | Tab connection | Enter edit | Ctrl+E run | Esc close"),
+	)
+}
+
+func (m Model) viewList() string {
+	height := m.listHeight()
+	if len(m.filtered) == 0 {
+		msg := "  No queries recorded yet"
+		if len(m.entries) > 0 {
+			msg = "  No matching queries"
+		}
+		return lipgloss.NewStyle().Height(height).Render(theme.StyleMuted.Render(msg))
+	}
+
+	var lines []string
+	for i := m.scroll; i < len(m.filtered) && i < m.scroll+height; i++ {
+		e := m.filtered[i]
+		status := theme.StyleSuccess.Render(fmt.Sprintf("%7d rows", e.Rows))
+		if !e.OK() {
+			status = theme.StyleError.Render(fmt.Sprintf("%12s", "error"))
+		}
+		meta := fmt.Sprintf("%s  %8s  ", e.At.Format("2006-01-02 15:04"), formatDuration(e.Duration))
+		room := max(10, m.width-lipgloss.Width(meta)-lipgloss.Width(status)-6)
+		query := widget.Truncate(strings.Join(strings.Fields(e.Query), " "), room)
+
+		prefix := "  "
+		style := lipgloss.NewStyle()
+		if i == m.cursor {
+			prefix = "> "
+			style = style.Background(lipgloss.Color("236")).Bold(true)
+		}
+		lines = append(lines, prefix+theme.StyleMuted.Render(meta)+status+"  "+style.Render(query))
+	}
+	return lipgloss.NewStyle().Height(height).Render(strings.Join(lines, "\n"))
+}
+
+// viewDetail shows the whole query under the cursor with where it ran
+// and, for failures, the error.
+func (m Model) viewDetail() string {
+	height := max(3, m.height-6-m.listHeight())
+	e, ok := m.selected()
+	if !ok {
+		return lipgloss.NewStyle().Height(height).Render("")
+	}
+
+	info := e.Connection
+	if e.Database != "" && !strings.HasSuffix(info, "/"+e.Database) {
+		info += "  db " + e.Database
+	}
+	lines := []string{theme.StyleMuted.Render(info + "  ·  " + e.At.Format("Mon Jan 2 15:04:05 2006"))}
+	if !e.OK() {
+		lines = append(lines, theme.StyleError.Render(widget.Truncate(e.Error, m.width-4)))
+	}
+	if len(e.Params) > 0 {
+		var vals []string
+		for _, p := range e.Params {
+			v := p.Name + " = " + p.Value
+			switch p.Type {
+			case "null":
+				v = p.Name + " = NULL"
+			case "":
+			default:
+				v += " (" + p.Type + ")"
+			}
+			vals = append(vals, v)
+		}
+		lines = append(lines, theme.StyleMuted.Render(widget.Truncate(strings.Join(vals, ", "), m.width-4)))
+	}
+	for _, l := range strings.Split(e.Query, "\n") {
+		lines = append(lines, widget.Truncate(l, m.width-4))
+	}
+	if len(lines) > height {
+		lines = append(lines[:height-1], theme.StyleMuted.Render("…"))
+	}
+
+	return lipgloss.NewStyle().
+		Width(max(10, m.width-2)).
+		Height(height).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderTop(true).
+		BorderForeground(theme.ColorBorder).
+		PaddingLeft(1).
+		Render(strings.Join(lines, "\n"))
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(100 * time.Microsecond).String()
+	default:
+		return d.Round(10 * time.Millisecond).String()
+	}
+}
@@ -0,0 +1,339 @@
+// Package library is the saved query picker: the queries of the library
+// directory, filtered by name, description and tags, to insert into the
+// editor, and a form to save the editor's query to the library.
+package library
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/queries"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// CloseMsg asks the app to leave the picker.
+type CloseMsg struct{}
+
+// InsertMsg asks the app to insert a saved query into the editor.
+type InsertMsg struct {
+	Query queries.Query
+}
+
+// SaveMsg asks the app to save a query to the library.
+type SaveMsg struct {
+	Query queries.Query
+}
+
+// form field indices
+const (
+	fieldName = iota
+	fieldDescription
+	fieldTags
+	fieldCount
+)
+
+// Model is the saved query picker.
+type Model struct {
+	all      []queries.Query
+	filtered []queries.Query
+	dir      string
+	current  string // the editor's query, offered by the save form
+
+	input  textinput.Model
+	cursor int
+	scroll int
+
+	form    []textinput.Model // nil unless the save form is open
+	focus   int
+	message string
+	err     bool
+
+	width  int
+	height int
+}
+
+// New creates a picker over the queries of dir. current is the editor
+// content, which the save form stores.
+func New(list []queries.Query, dir, current string) Model {
+	m := Model{all: list, dir: dir, current: strings.TrimSpace(current), input: newInput("/ ", "filter by name, description or #tag...")}
+	m.input.Focus()
+	m.filter()
+	return m
+}
+
+func newInput(prompt, placeholder string) textinput.Model {
+	ti := widget.NewInput()
+	ti.Prompt = prompt
+	ti.Placeholder = placeholder
+	return ti
+}
+
+// SetSize updates the component dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.input.Width = max(10, w-4)
+	for i := range m.form {
+		m.form[i].Width = max(10, w-20)
+	}
+}
+
+// SetQueries replaces the listed queries, keeping the filter.
+func (m *Model) SetQueries(list []queries.Query) {
+	m.all = list
+	m.filter()
+}
+
+// SetMessage shows a status line under the list; isErr styles it as an
+// error.
+func (m *Model) SetMessage(msg string, isErr bool) {
+	m.message = msg
+	m.err = isErr
+}
+
+// CloseForm closes the save form, after a successful save.
+func (m *Model) CloseForm() {
+	m.form = nil
+	m.input.Focus()
+}
+
+func (m *Model) filter() {
+	m.filtered = queries.Filter(m.all, m.input.Value())
+	m.cursor = 0
+	m.scroll = 0
+}
+
+func (m Model) selected() (queries.Query, bool) {
+	if m.cursor < len(m.filtered) {
+		return m.filtered[m.cursor], true
+	}
+	return queries.Query{}, false
+}
+
+// Update handles messages for the picker.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.form != nil {
+		return m.updateForm(key)
+	}
+	switch key.String() {
+	case "esc":
+		return m, func() tea.Msg { return CloseMsg{} }
+
+	case "up", "ctrl+p":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		m.clampScroll()
+		return m, nil
+
+	case "down", "ctrl+n":
+		if m.cursor < len(m.filtered)-1 {
+			m.cursor++
+		}
+		m.clampScroll()
+		return m, nil
+
+	case "pgup":
+		m.cursor = max(0, m.cursor-m.listHeight())
+		m.clampScroll()
+		return m, nil
+
+	case "pgdown":
+		m.cursor = max(0, min(len(m.filtered)-1, m.cursor+m.listHeight()))
+		m.clampScroll()
+		return m, nil
+
+	case "enter":
+		if q, ok := m.selected(); ok {
+			return m, func() tea.Msg { return InsertMsg{Query: q} }
+		}
+		return m, nil
+
+	case "ctrl+s":
+		m.openForm()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	before := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != before {
+		m.filter()
+	}
+	return m, cmd
+}
+
+func (m *Model) openForm() {
+	if m.current == "" {
+		m.SetMessage("The editor is empty: nothing to save", true)
+		return
+	}
+	m.form = []textinput.Model{
+		fieldName:        newInput("Name:        ", "Blocking locks"),
+		fieldDescription: newInput("Description: ", "what the query shows"),
+		fieldTags:        newInput("Tags:        ", "locks, diagnostics"),
+	}
+	m.focus = fieldName
+	m.input.Blur()
+	m.form[fieldName].Focus()
+	m.message = ""
+	m.SetSize(m.width, m.height)
+}
+
+func (m Model) updateForm(key tea.KeyMsg) (Model, tea.Cmd) {
+	switch key.String() {
+	case "esc":
+		m.CloseForm()
+		return m, nil
+
+	case "tab", "down", "shift+tab", "up":
+		m.form[m.focus].Blur()
+		if key.String() == "tab" || key.String() == "down" {
+			m.focus = (m.focus + 1) % fieldCount
+		} else {
+			m.focus = (m.focus + fieldCount - 1) % fieldCount
+		}
+		m.form[m.focus].Focus()
+		return m, nil
+
+	case "enter":
+		name := strings.TrimSpace(m.form[fieldName].Value())
+		if name == "" {
+			m.SetMessage("A saved query needs a name", true)
+			return m, nil
+		}
+		q := queries.Query{
+			Name:        name,
+			Description: strings.TrimSpace(m.form[fieldDescription].Value()),
+			Tags:        queries.ParseTags(m.form[fieldTags].Value()),
+			Body:        m.current,
+		}
+		return m, func() tea.Msg { return SaveMsg{Query: q} }
+	}
+
+	var cmd tea.Cmd
+	m.form[m.focus], cmd = m.form[m.focus].Update(key)
+	return m, cmd
+}
+
+// listHeight is how many queries fit above the preview of the selected
+// one.
+func (m Model) listHeight() int {
+	return max(3, (m.height-7)*2/5)
+}
+
+func (m *Model) clampScroll() {
+	m.scroll = widget.Scroll(m.cursor, m.scroll, m.listHeight())
+}
+
+// View renders the picker.
+func (m Model) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(0, 1)
+
+	heading := fmt.Sprintf("%d of %d  ·  %s", len(m.filtered), len(m.all), m.dir)
+	hints := "  ↑/↓ navigate | type to filter, #tag for a tag | Enter insert | Ctrl+S save editor query | Esc close"
+	if m.form != nil {
+		hints = "  Tab next field | Enter save | Esc cancel"
+	}
+
+	status := ""
+	if m.message != "" {
+		style := theme.StyleSuccess
+		if m.err {
+			style = theme.StyleError
+		}
+		status = style.Render("  " + m.message)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Saved Queries")+"  "+theme.StyleMuted.Render(heading),
+		" "+m.input.View(),
+		m.viewList(),
+		m.viewDetail(),
+		status,
+		theme.StyleMuted.Render(hints),
+	)
+}
+
+func (m Model) viewList() string {
+	height := m.listHeight()
+	if len(m.filtered) == 0 {
+		msg := "  No saved queries yet: add .sql files to the directory above or press Ctrl+S"
+		if len(m.all) > 0 {
+			msg = "  No matching queries"
+		}
+		return lipgloss.NewStyle().Height(height).Render(theme.StyleMuted.Render(msg))
+	}
+
+	nameWidth := 0
+	for _, q := range m.filtered {
+		nameWidth = max(nameWidth, lipgloss.Width(q.Name))
+	}
+	nameWidth = min(nameWidth, max(10, m.width/3))
+
+	var lines []string
+	for i := m.scroll; i < len(m.filtered) && i < m.scroll+height; i++ {
+		q := m.filtered[i]
+		name := widget.Truncate(q.Name, nameWidth)
+		name += strings.Repeat(" ", nameWidth-lipgloss.Width(name))
+		tags := ""
+		if len(q.Tags) > 0 {
+			tags = "#" + strings.Join(q.Tags, " #")
+		}
+		room := max(0, m.width-nameWidth-lipgloss.Width(tags)-8)
+		desc := widget.Truncate(q.Description, room)
+
+		prefix := "  "
+		style := lipgloss.NewStyle()
+		if i == m.cursor {
+			prefix = "> "
+			style = style.Background(lipgloss.Color("236")).Bold(true)
+		}
+		lines = append(lines, prefix+style.Render(name)+"  "+desc+"  "+theme.StyleMuted.Render(tags))
+	}
+	return lipgloss.NewStyle().Height(height).Render(strings.Join(lines, "\n"))
+}
+
+// viewDetail shows the save form, or the selected query's file and body.
+func (m Model) viewDetail() string {
+	height := max(3, m.height-7-m.listHeight())
+	var lines []string
+	if m.form != nil {
+		lines = append(lines, theme.StyleMuted.Render("Save the editor's query to the library"), "")
+		for _, f := range m.form {
+			lines = append(lines, f.View())
+		}
+		lines = append(lines, "")
+		for _, l := range strings.Split(m.current, "\n") {
+			lines = append(lines, theme.StyleMuted.Render(widget.Truncate(l, m.width-4)))
+		}
+	} else if q, ok := m.selected(); ok {
+		lines = append(lines, theme.StyleMuted.Render(q.Path))
+		for _, l := range strings.Split(q.Body, "\n") {
+			lines = append(lines, widget.Truncate(l, m.width-4))
+		}
+	}
+	if len(lines) > height {
+		lines = append(lines[:height-1], theme.StyleMuted.Render("…"))
+	}
+
+	return lipgloss.NewStyle().
+		Width(max(10, m.width-2)).
+		Height(height).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderTop(true).
+		BorderForeground(theme.ColorBorder).
+		PaddingLeft(1).
+		Render(strings.Join(lines, "\n"))
+}
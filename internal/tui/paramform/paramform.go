@@ -0,0 +1,224 @@
+// Package paramform asks for the values of a query's $1, :name and
+// ${name} parameters, and the type to bind each one as, before the query
+// runs.
+package paramform
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/app"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/sql"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// CloseMsg asks the app to leave the form without running the query.
+type CloseMsg struct{}
+
+// RunMsg asks the app to run the query with the values given.
+type RunMsg struct {
+	Query  string
+	Params []history.Param
+}
+
+// field is the value and type of one parameter.
+type field struct {
+	label string // $1 or :name
+	input textinput.Model
+	typ   int // index into app.ParamTypes
+}
+
+// Model is the parameter form.
+type Model struct {
+	query  string
+	fields []field
+	focus  int
+	err    string
+
+	width  int
+	height int
+}
+
+// New creates a form for the parameters of query, filled in with the
+// values in last, keyed by label.
+func New(query string, params []sql.Parameter, last map[string]history.Param) Model {
+	m := Model{query: query}
+	width := 0
+	for _, p := range params {
+		width = max(width, len(p.String()))
+	}
+	for _, p := range params {
+		label := p.String()
+		ti := widget.NewInput()
+		ti.Prompt = label + strings.Repeat(" ", width-len(label)) + "  "
+		ti.Placeholder = "value"
+		f := field{label: label, input: ti}
+		if v, ok := last[label]; ok {
+			f.input.SetValue(v.Value)
+			f.typ = max(0, slices.Index(app.ParamTypes, v.Type))
+		}
+		m.fields = append(m.fields, f)
+	}
+	m.fields[0].input.Focus()
+	return m
+}
+
+// SetSize updates the component dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	for i := range m.fields {
+		m.fields[i].input.Width = max(10, w/2)
+	}
+}
+
+// Params returns the values as they stand, with the "auto" type left
+// empty.
+func (m Model) Params() []history.Param {
+	out := make([]history.Param, len(m.fields))
+	for i, f := range m.fields {
+		p := history.Param{Name: f.label, Type: app.ParamTypes[f.typ], Value: f.input.Value()}
+		if p.Type == "auto" {
+			p.Type = ""
+		}
+		out[i] = p
+	}
+	return out
+}
+
+func (m *Model) setFocus(i int) {
+	m.fields[m.focus].input.Blur()
+	m.focus = i
+	m.fields[m.focus].input.Focus()
+}
+
+// Update handles messages for the form.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	n := len(m.fields)
+	switch key.String() {
+	case "esc":
+		return m, func() tea.Msg { return CloseMsg{} }
+
+	case "tab", "down":
+		m.setFocus((m.focus + 1) % n)
+		return m, nil
+
+	case "shift+tab", "up":
+		m.setFocus((m.focus + n - 1) % n)
+		return m, nil
+
+	case "ctrl+t":
+		f := &m.fields[m.focus]
+		f.typ = (f.typ + 1) % len(app.ParamTypes)
+		m.err = ""
+		return m, nil
+
+	case "enter":
+		if m.focus < n-1 {
+			m.setFocus(m.focus + 1)
+			return m, nil
+		}
+		return m.run()
+
+	case "ctrl+e", "f5":
+		return m.run()
+	}
+
+	var cmd tea.Cmd
+	m.fields[m.focus].input, cmd = m.fields[m.focus].input.Update(msg)
+	m.err = ""
+	return m, cmd
+}
+
+// run checks every value against its type before asking to run the
+// query, moving to the first that does not fit.
+func (m Model) run() (Model, tea.Cmd) {
+	params := m.Params()
+	for i, p := range params {
+		if err := app.CheckParam(p); err != nil {
+			m.err = err.Error()
+			m.setFocus(i)
+			return m, nil
+		}
+	}
+	query := m.query
+	return m, func() tea.Msg { return RunMsg{Query: query, Params: params} }
+}
+
+// View renders the form.
+func (m Model) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(0, 1)
+
+	heading := fmt.Sprintf("%d parameters", len(m.fields))
+	if len(m.fields) == 1 {
+		heading = "1 parameter"
+	}
+
+	lines := []string{
+		titleStyle.Render("Query Parameters") + "  " + theme.StyleMuted.Render(heading),
+		"",
+	}
+	typeWidth := 0
+	for _, t := range app.ParamTypes {
+		typeWidth = max(typeWidth, len(t))
+	}
+	for i, f := range m.fields {
+		typ := app.ParamTypes[f.typ]
+		style := theme.StyleMuted
+		if i == m.focus {
+			style = lipgloss.NewStyle().Foreground(theme.ColorParam).Bold(true)
+		}
+		value := f.input.View()
+		if typ == "null" {
+			value = theme.StyleMuted.Render(f.input.Prompt + "NULL")
+		}
+		lines = append(lines, " "+style.Render(fmt.Sprintf("%-*s", typeWidth, typ))+"  "+value)
+	}
+	lines = append(lines, "")
+	if m.err != "" {
+		lines = append(lines, theme.StyleError.Render("  "+m.err))
+	} else {
+		lines = append(lines, "")
+	}
+	form := strings.Join(lines, "\n")
+
+	hints := theme.StyleMuted.Render("  Tab/↑/↓ next field | Ctrl+T change type | Enter next, run on the last | Ctrl+E run | Esc cancel")
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		form,
+		m.viewQuery(max(3, m.height-lipgloss.Height(form)-2)),
+		hints,
+	)
+}
+
+// viewQuery shows the query the values are for.
+func (m Model) viewQuery(height int) string {
+	var lines []string
+	for _, l := range strings.Split(m.query, "\n") {
+		lines = append(lines, theme.StyleMuted.Render(widget.Truncate(l, m.width-4)))
+	}
+	if len(lines) > height {
+		lines = append(lines[:height-1], theme.StyleMuted.Render("…"))
+	}
+	return lipgloss.NewStyle().
+		Width(max(10, m.width-2)).
+		Height(height).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderTop(true).
+		BorderForeground(theme.ColorBorder).
+		PaddingLeft(1).
+		Render(strings.Join(lines, "\n"))
+}
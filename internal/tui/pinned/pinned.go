@@ -0,0 +1,307 @@
+// Package pinned is the pinned results screen: results kept under a name
+// in a tab strip, shown one at a time or two side by side to compare
+// them by eye.
+package pinned
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/tui/results"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// CloseMsg asks the app to leave the pinned results.
+type CloseMsg struct{}
+
+// Model is the pinned results screen.
+type Model struct {
+	store *results.Store
+	panes [2]results.Model
+	shown [2]*results.Snapshot // pinned result in each pane, nil for none
+	split bool                 // show the second pane
+	focus int                  // pane keys go to
+
+	input    textinput.Model
+	renaming bool
+
+	width  int
+	height int
+}
+
+// New creates the screen over the results pinned in store, showing the
+// first one.
+func New(store *results.Store) Model {
+	m := Model{store: store}
+	if pins := store.Pins(); len(pins) > 0 {
+		m.show(0, pins[0])
+	}
+	return m
+}
+
+// SetSize updates the component dimensions.
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.resize()
+}
+
+func (m *Model) resize() {
+	w, h := m.paneSize()
+	for i := range m.panes {
+		m.panes[i].SetSize(w, h)
+	}
+}
+
+// paneSize is the size of each pane: the full width, or half of it less
+// the separator when split.
+func (m Model) paneSize() (int, int) {
+	h := max(5, m.height-3)
+	if m.split {
+		return max(20, (m.width-1)/2), h
+	}
+	return m.width, h
+}
+
+// show puts a pinned result in pane i.
+func (m *Model) show(i int, snap *results.Snapshot) {
+	p := results.New()
+	p.SetResult(snap.Result)
+	p.SetLastQuery(snap.Query)
+	p.SetFocused(i == m.focus)
+	m.panes[i] = p
+	m.shown[i] = snap
+	m.resize()
+}
+
+func (m *Model) setFocus(i int) {
+	m.focus = i
+	for j := range m.panes {
+		m.panes[j].SetFocused(j == i)
+	}
+}
+
+// step shows the pinned result dir places from the focused pane's one.
+func (m *Model) step(dir int) {
+	pins := m.store.Pins()
+	if len(pins) == 0 {
+		return
+	}
+	i := slices.Index(pins, m.shown[m.focus])
+	m.show(m.focus, pins[(i+dir+len(pins))%len(pins)])
+}
+
+// toggleSplit shows or hides the second pane, which opens on the pinned
+// result after the first pane's one.
+func (m *Model) toggleSplit() {
+	pins := m.store.Pins()
+	if m.split {
+		m.split = false
+		if m.focus == 1 {
+			m.shown[0], m.panes[0] = m.shown[1], m.panes[1]
+		}
+		m.shown[1] = nil
+		m.setFocus(0)
+		m.resize()
+		return
+	}
+	if len(pins) < 2 {
+		return
+	}
+	m.split = true
+	i := slices.Index(pins, m.shown[0])
+	m.setFocus(1)
+	m.show(1, pins[(i+1)%len(pins)])
+}
+
+// unpin drops the focused pane's result from the pins, showing another
+// one in its place.
+func (m *Model) unpin() {
+	snap := m.shown[m.focus]
+	m.store.Unpin(snap)
+	pins := m.store.Pins()
+	if m.split && len(pins) < 2 {
+		m.split = false
+		m.shown[1] = nil
+		m.setFocus(0)
+	}
+	for i := range m.shown {
+		if m.shown[i] != snap {
+			continue
+		}
+		m.shown[i] = nil
+		if i == 1 && !m.split {
+			continue
+		}
+		for _, p := range pins {
+			if p != m.shown[1-i] {
+				m.show(i, p)
+				break
+			}
+		}
+	}
+	m.resize()
+}
+
+// Update handles messages for the screen.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.renaming {
+		return m.updateRename(key)
+	}
+	if m.shown[m.focus] == nil {
+		if k := key.String(); k == "esc" || k == "q" {
+			return m, func() tea.Msg { return CloseMsg{} }
+		}
+		return m, nil
+	}
+	if m.panes[m.focus].Overlay() {
+		m.panes[m.focus], _ = m.panes[m.focus].Update(msg)
+		return m, nil
+	}
+
+	switch k := key.String(); k {
+	case "esc", "q":
+		return m, func() tea.Msg { return CloseMsg{} }
+	case "tab", "shift+tab":
+		if m.split {
+			m.setFocus(1 - m.focus)
+		}
+		return m, nil
+	case "<":
+		m.step(-1)
+		return m, nil
+	case ">":
+		m.step(1)
+		return m, nil
+	case "s":
+		m.toggleSplit()
+		return m, nil
+	case "r":
+		ti := widget.NewInput()
+		ti.Prompt = "Rename: "
+		ti.CharLimit = 40
+		ti.SetValue(m.shown[m.focus].Name)
+		ti.CursorEnd()
+		ti.Focus()
+		m.input = ti
+		m.renaming = true
+		return m, nil
+	case "x":
+		m.unpin()
+		return m, nil
+	default:
+		if n, err := strconv.Atoi(k); err == nil && n >= 1 && n <= len(m.store.Pins()) {
+			m.show(m.focus, m.store.Pins()[n-1])
+			return m, nil
+		}
+	}
+
+	// queries asked for from a pinned result would replace the
+	// active tab's result behind this screen, so they are dropped
+	m.panes[m.focus], _ = m.panes[m.focus].Update(msg)
+	return m, nil
+}
+
+func (m Model) updateRename(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		if name := strings.TrimSpace(m.input.Value()); name != "" {
+			m.store.Pin(m.shown[m.focus], name)
+		}
+		m.renaming = false
+		return m, nil
+	case "esc":
+		m.renaming = false
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// View renders the screen.
+func (m Model) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(0, 1)
+
+	pins := m.store.Pins()
+	used, limit := m.store.Usage()
+	stats := fmt.Sprintf("%d pinned | %s of %s kept", len(pins), results.FormatBytes(used), results.FormatBytes(limit))
+	title := titleStyle.Render("Pinned Results") + "  " + theme.StyleMuted.Render(stats)
+
+	if len(pins) == 0 {
+		return lipgloss.JoinVertical(lipgloss.Left,
+			title,
+			"",
+			theme.StyleMuted.Render("  No pinned results: press p on a result to pin it"),
+			"",
+			theme.StyleMuted.Render("  Esc close"),
+		)
+	}
+
+	strip := m.viewStrip(pins)
+	if m.renaming {
+		strip = " " + m.input.View()
+	}
+
+	w, h := m.paneSize()
+	// cut lines to the pane rather than wrap them, then fill it
+	box := func(view string) string {
+		view = lipgloss.NewStyle().MaxWidth(w).MaxHeight(h).Render(view)
+		return lipgloss.NewStyle().Width(w).Height(h).Render(view)
+	}
+	body := box(m.panes[0].View())
+	if m.split {
+		sep := lipgloss.NewStyle().Foreground(theme.ColorBorder).
+			Render(strings.TrimSuffix(strings.Repeat("│\n", h), "\n"))
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, sep, box(m.panes[1].View()))
+	}
+
+	hints := "  1-9 / </> show pin | s side by side | r rename | x unpin | Esc close"
+	if m.split {
+		hints = "  Tab other side | 1-9 / </> show pin | s single | r rename | x unpin | Esc close"
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		strip,
+		body,
+		theme.StyleMuted.Render(hints),
+	)
+}
+
+// viewStrip renders the pin names, marking the ones on screen.
+func (m Model) viewStrip(pins []*results.Snapshot) string {
+	focused := lipgloss.NewStyle().
+		Foreground(theme.ColorHighlight).
+		Background(lipgloss.Color("236")).
+		Bold(true)
+	other := lipgloss.NewStyle().Foreground(theme.ColorHighlight)
+
+	var parts []string
+	for i, p := range pins {
+		label := fmt.Sprintf(" %d %s ", i+1, p.Name)
+		switch {
+		case p == m.shown[m.focus]:
+			label = focused.Render(label)
+		case m.split && p == m.shown[1-m.focus]:
+			label = other.Render(label)
+		default:
+			label = theme.StyleMuted.Render(label)
+		}
+		parts = append(parts, label)
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(strings.Join(parts, theme.StyleMuted.Render("│")))
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/sql"
 )
 
 func (m Model) getCellValue() string {
@@ -117,22 +120,20 @@ func (m *Model) doCopyRowText() {
 // --- Filter ---
 
 func (m *Model) doFilterByValue() tea.Cmd {
-	col := m.getColumnName()
-	val := m.getCellValue()
-	table := extractTableName(m.lastQuery)
-	if col == "" || table == "" {
+	name := m.getColumnName()
+	if name == "" || m.lastQuery == "" {
 		m.statusMessage = "Cannot filter: no cell selected"
 		return nil
 	}
 
-	var condition string
-	if val == "null" {
-		condition = col + " IS NULL"
-	} else {
-		escaped := strings.ReplaceAll(val, "'", "''")
-		condition = fmt.Sprintf("%s = '%s'", col, escaped)
+	col, ok := m.columnInfo(name)
+	if !ok && m.tableInfo != nil {
+		// a computed or renamed column the table does not have
+		m.statusMessage = fmt.Sprintf("Cannot filter: %s is not a column of %s", name, m.targetTable())
+		return nil
 	}
-	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", table, condition)
+	condition, _ := cellMatch(col, m.getCellValue())
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", m.targetTable(), condition)
 
 	return func() tea.Msg {
 		return SetEditorQueryMsg{Query: query}
@@ -141,30 +142,30 @@ func (m *Model) doFilterByValue() tea.Cmd {
 
 // --- Delete ---
 
-func (m *Model) doGenerateDelete() tea.Cmd {
+// deleteTargets returns the marked rows, or the cursor row when none are marked.
+func (m Model) deleteTargets() []int {
+	if len(m.marked) > 0 {
+		rows := make([]int, 0, len(m.marked))
+		for r := range m.marked {
+			rows = append(rows, r)
+		}
+		slices.Sort(rows)
+		return rows
+	}
 	if m.result == nil || m.cursorY < 0 || m.cursorY >= len(m.result.Rows) {
 		return nil
 	}
-	table := extractTableName(m.lastQuery)
-	row := m.result.Rows[m.cursorY]
-
-	var conditions []string
-	for i, col := range m.result.Columns {
-		if i >= len(row) {
-			break
-		}
-		val := row[i]
-		if val == "null" {
-			conditions = append(conditions, col+" IS NULL")
-		} else {
-			escaped := strings.ReplaceAll(val, "'", "''")
-			conditions = append(conditions, fmt.Sprintf("%s = '%s'", col, escaped))
-		}
-	}
+	return []int{m.cursorY}
+}
 
+func (m *Model) doGenerateDelete() tea.Cmd {
 	// send to editor for review, never auto-execute deletes
-	query := fmt.Sprintf("-- review before executing!\nDELETE FROM %s WHERE %s",
-		table, strings.Join(conditions, " AND "))
+	query, err := m.buildDelete(m.deleteTargets())
+	if err != nil {
+		m.statusMessage = "Cannot generate DELETE: " + err.Error()
+		return nil
+	}
+	m.marked = nil
 
 	return func() tea.Msg {
 		return SetEditorQueryMsg{Query: query}
@@ -231,25 +232,14 @@ func (m Model) exportCSVCmd() tea.Cmd {
 
 // --- Helpers ---
 
+// extractTableName returns the first table the query reads or writes,
+// for statements generated from a result without table metadata.
 func extractTableName(query string) string {
-	if query == "" {
+	refs := sql.Tables(sql.Lex(query))
+	if len(refs) == 0 {
 		return "<table>"
 	}
-	tokens := strings.Fields(query)
-	upper := make([]string, len(tokens))
-	for i, t := range tokens {
-		upper[i] = strings.ToUpper(t)
-	}
-	for i, tok := range upper {
-		if (tok == "FROM" || tok == "INTO" || tok == "UPDATE") && i+1 < len(tokens) {
-			name := tokens[i+1]
-			name = strings.TrimRight(name, ";,()")
-			if name != "" {
-				return name
-			}
-		}
-	}
-	return "<table>"
+	return database.QualifiedName(refs[0].Schema, refs[0].Name)
 }
 
 // rowToJSON preserves column order unlike map marshaling
@@ -0,0 +1,549 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+)
+
+// widthStep is how much + and - widen or narrow a column.
+const widthStep = 4
+
+// minColumnWidth is the narrowest a column can be made by hand.
+const minColumnWidth = 4
+
+// maxLayouts caps the remembered layouts; the least recently changed
+// are dropped first.
+const maxLayouts = 500
+
+// Layouts remembers how columns were laid out for each table or query,
+// so a result of the same table or query comes back laid out the same.
+// It is shared by the tabs and saved for later sessions with Save.
+type Layouts struct {
+	byKey map[string]*columnLayout
+	dirty bool // changed since loaded or saved
+}
+
+// NewLayouts creates an empty set of remembered layouts.
+func NewLayouts() *Layouts {
+	return &Layouts{byKey: make(map[string]*columnLayout)}
+}
+
+// columnLayout is a layout by column name rather than position, so it
+// applies to every result of a table whatever columns the query picked.
+type columnLayout struct {
+	order  []string // columns in display order
+	hidden map[string]bool
+	widths map[string]int // widths set by hand or fitted
+	frozen int            // leading shown columns kept in view
+	saved  time.Time      // when it was last changed
+}
+
+// savedLayout is how a columnLayout is written by Save.
+type savedLayout struct {
+	Order  []string       `json:"order"`
+	Hidden []string       `json:"hidden,omitempty"`
+	Widths map[string]int `json:"widths,omitempty"`
+	Frozen int            `json:"frozen,omitempty"`
+	Saved  time.Time      `json:"saved"`
+}
+
+// Load replaces the remembered layouts with data written by Save. Data
+// that cannot be read is ignored.
+func (l *Layouts) Load(data []byte) {
+	var saved map[string]savedLayout
+	if len(data) == 0 || json.Unmarshal(data, &saved) != nil {
+		return
+	}
+	l.byKey = make(map[string]*columnLayout, len(saved))
+	for key, s := range saved {
+		cl := &columnLayout{
+			order:  s.Order,
+			hidden: make(map[string]bool, len(s.Hidden)),
+			widths: s.Widths,
+			frozen: s.Frozen,
+			saved:  s.Saved,
+		}
+		for _, k := range s.Hidden {
+			cl.hidden[k] = true
+		}
+		if cl.widths == nil {
+			cl.widths = make(map[string]int)
+		}
+		l.byKey[key] = cl
+	}
+	l.dirty = false
+}
+
+// Save returns the remembered layouts to keep for later sessions, and
+// whether they changed since they were loaded or last saved.
+func (l *Layouts) Save() ([]byte, bool) {
+	if !l.dirty {
+		return nil, false
+	}
+	saved := make(map[string]savedLayout, len(l.byKey))
+	for key, cl := range l.byKey {
+		s := savedLayout{
+			Order:  cl.order,
+			Widths: cl.widths,
+			Frozen: cl.frozen,
+			Saved:  cl.saved,
+		}
+		for _, k := range cl.order {
+			if cl.hidden[k] {
+				s.Hidden = append(s.Hidden, k)
+			}
+		}
+		saved[key] = s
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return nil, false
+	}
+	l.dirty = false
+	return data, true
+}
+
+// put remembers a layout, dropping the oldest past maxLayouts.
+func (l *Layouts) put(key string, cl *columnLayout) {
+	cl.saved = time.Now()
+	l.byKey[key] = cl
+	l.dirty = true
+	for len(l.byKey) > maxLayouts {
+		oldest := ""
+		for k, c := range l.byKey {
+			if oldest == "" || c.saved.Before(l.byKey[oldest].saved) {
+				oldest = k
+			}
+		}
+		delete(l.byKey, oldest)
+	}
+}
+
+// SetLayouts sets where column layouts are remembered. Without it the
+// layout only lasts as long as the result.
+func (m *Model) SetLayouts(l *Layouts) {
+	m.layouts = l
+}
+
+// resetColumns shows every column of a new result in its own order.
+func (m *Model) resetColumns() {
+	m.order = nil
+	if m.result != nil {
+		for i := range m.result.Columns {
+			m.order = append(m.order, i)
+		}
+	}
+	m.hidden = nil
+	m.sized = nil
+	m.frozen = 0
+	m.colOffset = 0
+}
+
+// layoutKey names the layout of the result on screen: its source table
+// when it has one, or else its query.
+func (m Model) layoutKey() string {
+	if schema, table, ok := m.SourceTable(); ok {
+		if schema != "" {
+			table = schema + "." + table
+		}
+		return "table " + table
+	}
+	if q := strings.Join(strings.Fields(m.lastQuery), " "); q != "" {
+		return "query " + q
+	}
+	return ""
+}
+
+// loadLayout lays the columns out as last remembered for the result's
+// table or query.
+func (m *Model) loadLayout() {
+	m.resetColumns()
+	m.calculateColumnWidths()
+	if m.layouts == nil || !m.HasResult() {
+		return
+	}
+	l := m.layouts.byKey[m.layoutKey()]
+	if l == nil {
+		return
+	}
+
+	keys := columnKeys(m.result.Columns)
+	at := make(map[string]int, len(keys))
+	for i, k := range keys {
+		at[k] = i
+	}
+	order := make([]int, 0, len(keys))
+	for _, k := range l.order {
+		if i, ok := at[k]; ok {
+			order = append(order, i)
+			delete(at, k)
+		}
+	}
+	for i, k := range keys {
+		if _, ok := at[k]; ok {
+			order = append(order, i)
+		}
+	}
+	m.order = order
+
+	for i, k := range keys {
+		if l.hidden[k] {
+			if m.hidden == nil {
+				m.hidden = make(map[int]bool)
+			}
+			m.hidden[i] = true
+		}
+		if w, ok := l.widths[k]; ok {
+			if m.sized == nil {
+				m.sized = make(map[int]int)
+			}
+			m.sized[i] = w
+			m.colWidths[i] = w
+		}
+	}
+	if len(m.hidden) == len(keys) {
+		m.hidden = nil
+	}
+	m.frozen = min(l.frozen, len(m.shown()))
+	m.ensureHorizontalWindow()
+}
+
+// saveLayout remembers the layout of the result on screen. Columns of
+// the table this result does not have keep what was remembered for them.
+func (m *Model) saveLayout() {
+	key := m.layoutKey()
+	if m.layouts == nil || key == "" || !m.HasResult() {
+		return
+	}
+	l := &columnLayout{
+		hidden: make(map[string]bool),
+		widths: make(map[string]int),
+		frozen: m.frozen,
+	}
+	keys := columnKeys(m.result.Columns)
+	for _, c := range m.order {
+		l.order = append(l.order, keys[c])
+		if m.hidden[c] {
+			l.hidden[keys[c]] = true
+		}
+	}
+	for c, w := range m.sized {
+		l.widths[keys[c]] = w
+	}
+	if old := m.layouts.byKey[key]; old != nil {
+		for _, k := range old.order {
+			if !slices.Contains(keys, k) {
+				l.order = append(l.order, k)
+				l.hidden[k] = old.hidden[k]
+				if w, ok := old.widths[k]; ok {
+					l.widths[k] = w
+				}
+			}
+		}
+	}
+	m.layouts.put(key, l)
+}
+
+// columnsChanged remembers the layout after columns were shown, hidden
+// or moved, and searches the columns now shown.
+func (m *Model) columnsChanged() {
+	m.saveLayout()
+	m.refreshSearch()
+}
+
+// forgetLayout drops the remembered layout and shows the result as it
+// came.
+func (m *Model) forgetLayout() {
+	if m.layouts != nil {
+		if _, ok := m.layouts.byKey[m.layoutKey()]; ok {
+			delete(m.layouts.byKey, m.layoutKey())
+			m.layouts.dirty = true
+		}
+	}
+	m.resetColumns()
+	m.calculateColumnWidths()
+	m.ensureHorizontalWindow()
+	m.refreshSearch()
+}
+
+// columnKeys names result columns for layouts; a name repeated in the
+// result, as joins often do with id, is told apart by its occurrence.
+func columnKeys(cols []string) []string {
+	keys := make([]string, len(cols))
+	seen := make(map[string]int, len(cols))
+	for i, c := range cols {
+		seen[c]++
+		keys[i] = c
+		if n := seen[c]; n > 1 {
+			keys[i] = c + "#" + strconv.Itoa(n)
+		}
+	}
+	return keys
+}
+
+// shown returns the result columns not hidden, in display order.
+func (m Model) shown() []int {
+	cols := make([]int, 0, len(m.order))
+	for _, c := range m.order {
+		if !m.hidden[c] {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// frozenShown returns how many of the frozen columns are kept in view:
+// they take at most half the width, so there is always room to scroll
+// the others past them.
+func (m Model) frozenShown(shown []int) int {
+	half := max(20, m.width-4) / 2
+	total := 1
+	n := 0
+	for n < min(m.frozen, len(shown)) {
+		w := m.colWidths[shown[n]] + 3
+		if n > 0 && total+w > half {
+			break
+		}
+		total += w
+		n++
+	}
+	return n
+}
+
+// stepColumn moves the cursor dir shown columns along.
+func (m *Model) stepColumn(dir int) {
+	shown := m.shown()
+	if i := slices.Index(shown, m.cursorX) + dir; i >= 0 && i < len(shown) {
+		m.cursorX = shown[i]
+		m.ensureHorizontalWindow()
+	}
+}
+
+// edgeColumn moves the cursor to the first or the last shown column.
+func (m *Model) edgeColumn(last bool) {
+	shown := m.shown()
+	if len(shown) == 0 {
+		return
+	}
+	m.cursorX = shown[0]
+	if last {
+		m.cursorX = shown[len(shown)-1]
+	}
+	m.ensureHorizontalWindow()
+}
+
+// hideColumn hides result column col, unless it is the last one shown.
+func (m *Model) hideColumn(col int) {
+	if len(m.shown()) <= 1 {
+		m.statusMessage = "Cannot hide the only column shown"
+		return
+	}
+	if m.hidden == nil {
+		m.hidden = make(map[int]bool)
+	}
+	m.hidden[col] = true
+	m.frozen = min(m.frozen, len(m.shown()))
+	m.ensureHorizontalWindow()
+	m.columnsChanged()
+}
+
+// moveColumn moves the cursor's column dir places among the shown
+// columns; hidden columns between keep their place.
+func (m *Model) moveColumn(dir int) {
+	shown := m.shown()
+	i := slices.Index(shown, m.cursorX)
+	if i < 0 || i+dir < 0 || i+dir >= len(shown) {
+		return
+	}
+	from := slices.Index(m.order, m.cursorX)
+	to := slices.Index(m.order, shown[i+dir])
+	m.order = slices.Insert(slices.Delete(m.order, from, from+1), to, m.cursorX)
+	m.ensureHorizontalWindow()
+	m.columnsChanged()
+}
+
+// resizeColumn widens or narrows the cursor's column by delta.
+func (m *Model) resizeColumn(delta int) {
+	w := m.colWidths[m.cursorX] + delta
+	m.setWidth(m.cursorX, min(max(w, minColumnWidth), m.maxColumnWidth()))
+}
+
+// fitColumn sizes the cursor's column to its widest value, as far as the
+// pane allows.
+func (m *Model) fitColumn() {
+	col := m.cursorX
+	w := lipgloss.Width(m.result.Columns[col])
+	for _, row := range m.result.Rows {
+		if col < len(row) {
+			w = max(w, lipgloss.Width(row[col]))
+		}
+	}
+	m.setWidth(col, min(max(w, minColumnWidth), m.maxColumnWidth()))
+}
+
+func (m *Model) setWidth(col, w int) {
+	if m.sized == nil {
+		m.sized = make(map[int]int)
+	}
+	m.sized[col] = w
+	m.colWidths[col] = w
+	m.ensureHorizontalWindow()
+	m.saveLayout()
+}
+
+// resetWidths goes back to widths worked out from the values.
+func (m *Model) resetWidths() {
+	m.sized = nil
+	m.calculateColumnWidths()
+	m.ensureHorizontalWindow()
+	m.saveLayout()
+	m.statusMessage = "Column widths reset"
+}
+
+// maxColumnWidth is the widest a column fits the pane at.
+func (m Model) maxColumnWidth() int {
+	return max(8, m.width-7)
+}
+
+// toggleFreeze keeps the shown columns up to the cursor's in view while
+// scrolling sideways, or unfreezes them when they already are.
+func (m *Model) toggleFreeze() {
+	n := slices.Index(m.shown(), m.cursorX) + 1
+	if n == 0 {
+		return
+	}
+	if m.frozen == n {
+		m.frozen = 0
+		m.statusMessage = "Columns unfrozen"
+	} else {
+		m.frozen = n
+		m.statusMessage = fmt.Sprintf("Froze %d column(s)", n)
+	}
+	m.colOffset = 0
+	m.ensureHorizontalWindow()
+	m.saveLayout()
+}
+
+// ── Column picker ───────────────────────────────────────────────────────
+
+// openColumns opens the column picker on the cursor's column.
+func (m *Model) openColumns() {
+	m.viewMode = ViewColumns
+	m.menuCursor = max(0, slices.Index(m.order, m.cursorX))
+}
+
+func (m Model) updateColumns(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if len(m.order) == 0 {
+		m.viewMode = ViewNormal
+		return m, nil
+	}
+	col := m.order[m.menuCursor]
+	switch msg.String() {
+	case "esc", "enter", "C":
+		m.viewMode = ViewNormal
+	case "up", "k":
+		if m.menuCursor > 0 {
+			m.menuCursor--
+		}
+	case "down", "j":
+		if m.menuCursor < len(m.order)-1 {
+			m.menuCursor++
+		}
+	case " ", "x":
+		if m.hidden[col] {
+			delete(m.hidden, col)
+			m.ensureHorizontalWindow()
+			m.columnsChanged()
+		} else {
+			m.hideColumn(col)
+		}
+	case "K", "shift+up":
+		if m.menuCursor > 0 {
+			m.order[m.menuCursor], m.order[m.menuCursor-1] = m.order[m.menuCursor-1], col
+			m.menuCursor--
+			m.ensureHorizontalWindow()
+			m.columnsChanged()
+		}
+	case "J", "shift+down":
+		if m.menuCursor < len(m.order)-1 {
+			m.order[m.menuCursor], m.order[m.menuCursor+1] = m.order[m.menuCursor+1], col
+			m.menuCursor++
+			m.ensureHorizontalWindow()
+			m.columnsChanged()
+		}
+	case "z":
+		if !m.hidden[col] {
+			m.cursorX = col
+			m.toggleFreeze()
+		}
+	case "a":
+		m.hidden = nil
+		m.ensureHorizontalWindow()
+		m.columnsChanged()
+	case "r":
+		m.forgetLayout()
+		m.menuCursor = 0
+		m.statusMessage = "Layout reset"
+	}
+	return m, nil
+}
+
+func (m Model) renderColumns() string {
+	var b strings.Builder
+	shown := m.shown()
+	title := fmt.Sprintf("  Columns: %d of %d shown", len(shown), len(m.order))
+	if m.frozen > 0 {
+		title += fmt.Sprintf(", %d frozen", m.frozen)
+	}
+	b.WriteString(lipgloss.NewStyle().
+		Foreground(theme.ColorHighlight).
+		Bold(true).
+		Render(title))
+	b.WriteString("\n")
+
+	nameWidth := 10
+	for _, c := range m.result.Columns {
+		nameWidth = max(nameWidth, min(30, lipgloss.Width(c)))
+	}
+	visible := max(1, m.height-5)
+	scrollOff := max(0, m.menuCursor-visible+1)
+	for i := scrollOff; i < len(m.order) && i < scrollOff+visible; i++ {
+		c := m.order[i]
+		box := "[x]"
+		if m.hidden[c] {
+			box = "[ ]"
+		}
+		line := box + " " + fitCell(m.result.Columns[c], nameWidth)
+		if info, ok := m.columnInfo(m.result.Columns[c]); ok {
+			line += "  " + info.DataType
+		}
+		if n := slices.Index(shown, c); n >= 0 && n < m.frozen {
+			line += "  frozen"
+		}
+		switch {
+		case i == m.menuCursor:
+			line = lipgloss.NewStyle().Foreground(theme.ColorHighlight).Bold(true).Render("> " + line)
+		case m.hidden[c]:
+			line = theme.StyleMuted.Render("  " + line)
+		default:
+			line = "  " + line
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	if m.statusMessage != "" {
+		b.WriteString(theme.StyleSuccess.Render("  " + m.statusMessage))
+		b.WriteString("  ")
+	}
+	b.WriteString(theme.StyleMuted.Render("Space show/hide | K/J move | z freeze to here | a show all | r reset | Esc close"))
+	return b.String()
+}
@@ -0,0 +1,482 @@
+package results
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/datadiff"
+	"github.com/joacominatel/minadb/internal/sql"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+)
+
+// diffState is the comparison of the current result against another one.
+type diffState struct {
+	other  *database.QueryResult // the older side: baseline or other connection
+	label  string
+	result *datadiff.Result
+	all    bool // show unchanged rows too
+	rows   []datadiff.Row
+	widths []int
+
+	cursorY   int
+	cursorX   int
+	scrollY   int
+	colOffset int
+	exporting bool
+}
+
+// SetConnections sets the saved connection names offered as diff sources.
+func (m *Model) SetConnections(names []string) {
+	m.connections = names
+}
+
+// SetDiffAgainst compares the current result with other, which the app
+// fetched from another connection in response to DiffWithConnectionMsg.
+func (m *Model) SetDiffAgainst(other *database.QueryResult, label string, err error) {
+	if err != nil {
+		m.statusMessage = "Diff failed: " + err.Error()
+		return
+	}
+	m.startDiff(other, label)
+}
+
+func (m *Model) saveBaseline() {
+	m.baseline = m.result
+	m.baselineAt = time.Now()
+	m.hold(&m.inBaseline, m.current())
+	m.statusMessage = fmt.Sprintf("Saved %d row(s) as diff baseline", m.result.RowCount)
+}
+
+// diffSources lists the picker entries: the baseline first, if any, then
+// every saved connection.
+func (m Model) diffSources() []string {
+	var sources []string
+	if m.baseline != nil {
+		sources = append(sources, fmt.Sprintf("Baseline from %s (%d rows)",
+			m.baselineAt.Format("15:04:05"), m.baseline.RowCount))
+	}
+	for _, name := range m.connections {
+		sources = append(sources, "Same query on "+name)
+	}
+	return sources
+}
+
+func (m *Model) startDiff(other *database.QueryResult, label string) {
+	if m.result == nil {
+		return
+	}
+	d := &diffState{other: other, label: label}
+	if err := m.computeDiff(d, m.defaultDiffKeys(other)); err != nil {
+		m.statusMessage = "Cannot diff: " + err.Error()
+		return
+	}
+	m.diff = d
+	m.viewMode = ViewDataDiff
+}
+
+// defaultDiffKeys prefers the source table's primary key and falls back to
+// the column under the cursor.
+func (m Model) defaultDiffKeys(other *database.QueryResult) []string {
+	if m.tableInfo != nil && len(m.tableInfo.PrimaryKey) > 0 {
+		ok := true
+		for _, k := range m.tableInfo.PrimaryKey {
+			if !slices.Contains(m.result.Columns, k) || !slices.Contains(other.Columns, k) {
+				ok = false
+			}
+		}
+		if ok {
+			return slices.Clone(m.tableInfo.PrimaryKey)
+		}
+	}
+	if name := m.getColumnName(); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+func (m Model) computeDiff(d *diffState, keys []string) error {
+	res, err := datadiff.Compare(d.other, m.result, keys)
+	if err != nil {
+		return err
+	}
+	d.result = res
+	d.refresh()
+	return nil
+}
+
+// refresh rebuilds the visible rows and column widths.
+func (d *diffState) refresh() {
+	if d.all {
+		d.rows = d.result.Rows
+	} else {
+		d.rows = d.result.Differences()
+	}
+
+	d.widths = make([]int, len(d.result.Columns))
+	for i, col := range d.result.Columns {
+		d.widths[i] = lipgloss.Width(col)
+	}
+	for _, row := range d.rows {
+		for i := range d.widths {
+			if w := lipgloss.Width(row.Value(i)); w > d.widths[i] {
+				d.widths[i] = w
+			}
+		}
+	}
+	for i := range d.widths {
+		d.widths[i] = min(max(d.widths[i], 8), 40)
+	}
+
+	d.cursorY = min(d.cursorY, max(0, len(d.rows)-1))
+	d.cursorX = min(d.cursorX, max(0, len(d.result.Columns)-1))
+	d.scrollY = min(d.scrollY, d.cursorY)
+}
+
+func (m Model) updateDiffSource(msg tea.KeyMsg) (Model, tea.Cmd) {
+	sources := m.diffSources()
+	switch msg.String() {
+	case "esc":
+		m.viewMode = ViewNormal
+	case "up", "k":
+		if m.menuCursor > 0 {
+			m.menuCursor--
+		}
+	case "down", "j":
+		if m.menuCursor < len(sources)-1 {
+			m.menuCursor++
+		}
+	case "enter":
+		m.viewMode = ViewNormal
+		idx := m.menuCursor
+		if m.baseline != nil {
+			if idx == 0 {
+				m.startDiff(m.baseline, "baseline from "+m.baselineAt.Format("15:04:05"))
+				return m, nil
+			}
+			idx--
+		}
+		if idx >= 0 && idx < len(m.connections) {
+			// the query runs again unasked, so it must not change anything
+			if !sql.ReturnsRows(m.lastQuery) {
+				m.statusMessage = "Only a single SELECT, VALUES or WITH query can run on another connection"
+				return m, nil
+			}
+			m.statusMessage = "Running query on " + m.connections[idx] + "..."
+			msg := DiffWithConnectionMsg{Connection: idx, Query: m.lastQuery}
+			return m, func() tea.Msg { return msg }
+		}
+	}
+	return m, nil
+}
+
+func (m Model) updateDataDiff(msg tea.KeyMsg) (Model, tea.Cmd) {
+	d := m.diff
+	if d == nil {
+		m.viewMode = ViewNormal
+		return m, nil
+	}
+	// work on a copy so the previous model value stays untouched
+	cp := *d
+	d = &cp
+	m.diff = d
+
+	if d.exporting {
+		d.exporting = false
+		switch msg.String() {
+		case "j":
+			m.statusMessage = "Exporting diff as JSON..."
+			return m, exportDiffCmd(d.result, "json")
+		case "c":
+			m.statusMessage = "Exporting diff as CSV..."
+			return m, exportDiffCmd(d.result, "csv")
+		}
+		return m, nil
+	}
+
+	cols := len(d.result.Columns)
+	switch msg.String() {
+	case "esc", "q":
+		m.viewMode = ViewNormal
+		m.diff = nil
+	case "up", "k":
+		d.cursorY = max(0, d.cursorY-1)
+	case "down", "j":
+		d.cursorY = max(0, min(len(d.rows)-1, d.cursorY+1))
+	case "left", "h":
+		d.cursorX = max(0, d.cursorX-1)
+	case "right", "l":
+		d.cursorX = min(cols-1, d.cursorX+1)
+	case "pgup":
+		d.cursorY = max(0, d.cursorY-m.visibleRows())
+	case "pgdown":
+		d.cursorY = max(0, min(len(d.rows)-1, d.cursorY+m.visibleRows()))
+	case "home":
+		d.cursorX = 0
+	case "end":
+		d.cursorX = cols - 1
+	case "g":
+		d.cursorY = 0
+	case "G":
+		d.cursorY = max(0, len(d.rows)-1)
+	case "a":
+		d.all = !d.all
+		d.refresh()
+	case "m":
+		// match on the cursor column alone
+		m.rekeyDiff(d, []string{d.result.Columns[d.cursorX]})
+	case "M":
+		// add or remove the cursor column from a composite key
+		col := d.result.Columns[d.cursorX]
+		keys := slices.Clone(d.result.Keys)
+		if i := slices.Index(keys, col); i >= 0 {
+			if len(keys) == 1 {
+				m.statusMessage = "At least one key column is required"
+				return m, nil
+			}
+			keys = slices.Delete(keys, i, i+1)
+		} else {
+			keys = append(keys, col)
+		}
+		m.rekeyDiff(d, keys)
+	case "e":
+		d.exporting = true
+	}
+
+	d.ensureWindow(m.visibleRows(), max(20, m.width-4))
+	return m, nil
+}
+
+func (m *Model) rekeyDiff(d *diffState, keys []string) {
+	if err := m.computeDiff(d, keys); err != nil {
+		m.statusMessage = "Cannot diff: " + err.Error()
+		return
+	}
+	m.statusMessage = "Matching rows on " + strings.Join(keys, ", ")
+}
+
+func (d *diffState) ensureWindow(visible, available int) {
+	if d.cursorY < d.scrollY {
+		d.scrollY = d.cursorY
+	}
+	if d.cursorY >= d.scrollY+visible {
+		d.scrollY = d.cursorY - visible + 1
+	}
+
+	if d.cursorX < d.colOffset {
+		d.colOffset = d.cursorX
+	}
+	for d.colOffset < len(d.widths) {
+		if _, to := d.columnRange(available); d.cursorX < to {
+			break
+		}
+		d.colOffset++
+	}
+}
+
+// columnRange returns the columns that fit in the available width, after
+// the status marker column.
+func (d *diffState) columnRange(available int) (int, int) {
+	start := min(d.colOffset, max(0, len(d.widths)-1))
+	end := start
+	total := 1 + 4 // left border plus the status column
+	for i := start; i < len(d.widths); i++ {
+		w := d.widths[i] + 3
+		if end > start && total+w > available {
+			break
+		}
+		total += w
+		end = i + 1
+	}
+	return start, end
+}
+
+func (m Model) renderDataDiff() string {
+	d := m.diff
+	res := d.result
+
+	var b strings.Builder
+	summary := fmt.Sprintf("  Diff vs %s  ", d.label)
+	b.WriteString(lipgloss.NewStyle().Foreground(theme.ColorHighlight).Bold(true).Render(summary))
+	b.WriteString(theme.StyleSuccess.Render(fmt.Sprintf("+%d ", res.Added)))
+	b.WriteString(theme.StyleError.Render(fmt.Sprintf("-%d ", res.Removed)))
+	b.WriteString(theme.StyleWarning.Render(fmt.Sprintf("~%d ", res.Changed)))
+	b.WriteString(theme.StyleMuted.Render(fmt.Sprintf("=%d  key: %s", res.Unchanged, strings.Join(res.Keys, ", "))))
+	b.WriteString("\n")
+
+	if len(d.rows) == 0 {
+		if res.Identical() {
+			b.WriteString(theme.StyleSuccess.Render(fmt.Sprintf("  Results are identical (%d rows)", res.Unchanged)))
+		} else {
+			b.WriteString(theme.StyleMuted.Render("  No rows"))
+		}
+		b.WriteString("\n")
+		b.WriteString(m.renderDiffFooter())
+		return b.String()
+	}
+
+	from, to := d.columnRange(max(20, m.width-4))
+	widths := d.widths[from:to]
+	borderStyle := lipgloss.NewStyle().Foreground(theme.ColorBorder)
+	border := func(left, center, right string) string {
+		parts := []string{"───"}
+		for _, w := range widths {
+			parts = append(parts, strings.Repeat("─", w+2))
+		}
+		return borderStyle.Render(left + strings.Join(parts, center) + right)
+	}
+
+	b.WriteString(border("┌", "┬", "┐"))
+	b.WriteString("\n")
+	header := "│   "
+	for i, col := range res.Columns[from:to] {
+		style := lipgloss.NewStyle().Bold(true).Foreground(theme.ColorPrimary)
+		if from+i == d.cursorX {
+			style = style.Underline(true)
+		}
+		header += "│" + style.Render(" "+fitCell(col, widths[i])+" ")
+	}
+	b.WriteString(header + "│\n")
+	b.WriteString(border("├", "┼", "┤"))
+	b.WriteString("\n")
+
+	end := min(len(d.rows), d.scrollY+m.visibleRows())
+	for ri := d.scrollY; ri < end; ri++ {
+		b.WriteString(m.renderDiffRow(d.rows[ri], from, to, ri == d.cursorY))
+		b.WriteString("\n")
+	}
+	b.WriteString(border("└", "┴", "┘"))
+	b.WriteString("\n")
+	b.WriteString(m.renderDiffFooter())
+	return b.String()
+}
+
+func (m Model) renderDiffRow(row datadiff.Row, from, to int, selected bool) string {
+	d := m.diff
+	base := lipgloss.NewStyle()
+	switch row.Status {
+	case datadiff.Added:
+		base = base.Foreground(theme.ColorSuccess)
+	case datadiff.Removed:
+		base = base.Foreground(theme.ColorError)
+	}
+	if selected {
+		base = base.Background(lipgloss.Color("236"))
+	}
+	sep := lipgloss.NewStyle()
+	if selected {
+		sep = sep.Background(lipgloss.Color("236"))
+	}
+
+	var b strings.Builder
+	b.WriteString(sep.Render("│"))
+	b.WriteString(base.Bold(row.Status != datadiff.Unchanged).Render(" " + row.Status.Symbol() + " "))
+	for i := from; i < to; i++ {
+		b.WriteString(sep.Render("│"))
+		style := base
+		if row.Changed[i] {
+			style = style.Foreground(theme.ColorWarning).Bold(true)
+		}
+		if selected && i == d.cursorX {
+			style = lipgloss.NewStyle().Background(theme.ColorPrimary).
+				Foreground(lipgloss.Color("255")).Bold(true)
+		}
+		b.WriteString(style.Render(" " + fitCell(row.Value(i), d.widths[i]) + " "))
+	}
+	b.WriteString(sep.Render("│"))
+	return b.String()
+}
+
+func (m Model) renderDiffFooter() string {
+	d := m.diff
+	if d.exporting {
+		label := lipgloss.NewStyle().
+			Foreground(theme.ColorHighlight).
+			Bold(true).
+			Render("Export diff: ")
+		return label + theme.StyleMuted.Render("[j]JSON file  [c]CSV file  [Esc]cancel")
+	}
+
+	info := ""
+	if d.cursorY < len(d.rows) {
+		row := d.rows[d.cursorY]
+		info = fmt.Sprintf("Row %d/%d", d.cursorY+1, len(d.rows))
+		if row.Changed[d.cursorX] {
+			info += " | " + d.result.Columns[d.cursorX] + ": " +
+				truncateStatus(row.Old[d.cursorX], 30) + " → " + truncateStatus(row.New[d.cursorX], 30)
+		}
+	}
+
+	scope := "a:show all"
+	if d.all {
+		scope = "a:changes only"
+	}
+	actions := scope + "  m:key on column  M:add/remove key column  e:export  Esc:close"
+	if m.statusMessage != "" {
+		return theme.StyleSuccess.Render("  "+m.statusMessage) + "  " + theme.StyleMuted.Render(info)
+	}
+	if info != "" {
+		actions = info + " | " + actions
+	}
+	return theme.StyleMuted.Render(actions)
+}
+
+func (m Model) renderDiffSource() string {
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().
+		Foreground(theme.ColorHighlight).
+		Bold(true).
+		Render("  Compare the current result with:"))
+	b.WriteString("\n")
+
+	sources := m.diffSources()
+	if len(sources) == 0 {
+		b.WriteString(theme.StyleMuted.Render("  Nothing to compare with: press B to save a baseline first"))
+		b.WriteString("\n")
+	}
+	visible := max(1, m.height-4)
+	scrollOff := max(0, m.menuCursor-visible+1)
+	for i := scrollOff; i < len(sources) && i < scrollOff+visible; i++ {
+		line := "  " + sources[i]
+		if i == m.menuCursor {
+			line = lipgloss.NewStyle().
+				Foreground(theme.ColorHighlight).
+				Bold(true).
+				Render("> " + sources[i])
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString(theme.StyleMuted.Render("↑/↓ navigate | Enter compare | Esc cancel"))
+	return b.String()
+}
+
+func exportDiffCmd(res *datadiff.Result, format string) tea.Cmd {
+	return func() tea.Msg {
+		ts := time.Now().Format("20060102_150405")
+		filename := fmt.Sprintf("minadb_diff_%s.%s", ts, format)
+
+		f, err := os.Create(filename)
+		if err != nil {
+			return StatusNotifyMsg{Message: "Export failed: " + err.Error()}
+		}
+		defer f.Close()
+
+		if format == "json" {
+			err = res.WriteJSON(f)
+		} else {
+			err = res.WriteCSV(f)
+		}
+		if err != nil {
+			return StatusNotifyMsg{Message: "Export failed: " + err.Error()}
+		}
+		n := res.Added + res.Removed + res.Changed
+		return StatusNotifyMsg{Message: fmt.Sprintf("Exported %d differences to %s", n, filename)}
+	}
+}
@@ -0,0 +1,460 @@
+package results
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A filter expression keeps the loaded rows it is true for:
+//
+//	amount > 100 and status != 'done'
+//	email ~ /gmail/i or not (name is null)
+//
+// Comparisons are =, !=, <>, <, <=, > and >=, made as numbers, times or
+// text by the column's type. ~ and !~ match a /regex/ or a 'string'
+// pattern, with an i after the closing slash to ignore case. NULL only
+// passes is null, as in SQL. An expression that starts with an operator
+// tests the column under the cursor.
+
+// filterExpr is a parsed filter expression.
+type filterExpr interface {
+	keep(row []string) bool
+}
+
+type andExpr struct{ left, right filterExpr }
+
+func (e andExpr) keep(row []string) bool { return e.left.keep(row) && e.right.keep(row) }
+
+type orExpr struct{ left, right filterExpr }
+
+func (e orExpr) keep(row []string) bool { return e.left.keep(row) || e.right.keep(row) }
+
+type notExpr struct{ expr filterExpr }
+
+func (e notExpr) keep(row []string) bool { return !e.expr.keep(row) }
+
+// nullTest is col is [not] null.
+type nullTest struct {
+	col int
+	not bool
+}
+
+func (t nullTest) keep(row []string) bool {
+	return (cell(row, t.col) == "null") != t.not
+}
+
+// matchTest is col ~ pattern or col !~ pattern.
+type matchTest struct {
+	col int
+	re  *regexp.Regexp
+	not bool
+}
+
+func (t matchTest) keep(row []string) bool {
+	v := cell(row, t.col)
+	return v != "null" && t.re.MatchString(v) != t.not
+}
+
+// compareTest compares a column with a value.
+type compareTest struct {
+	col   int
+	op    string
+	kind  valueKind
+	value string
+	num   float64
+	at    time.Time
+}
+
+func (t compareTest) keep(row []string) bool {
+	v := cell(row, t.col)
+	if v == "null" {
+		return false
+	}
+	var c int
+	switch t.kind {
+	case kindNumber:
+		x, ok := parseNumber(v)
+		if !ok {
+			return false
+		}
+		c = cmp.Compare(x, t.num)
+	case kindTime:
+		x, ok := parseTime(v)
+		if !ok {
+			return false
+		}
+		c = x.Compare(t.at)
+	default:
+		switch t.op {
+		case "=":
+			return v == t.value
+		case "!=":
+			return v != t.value
+		}
+		c = compareValues(v, t.value, kindText)
+	}
+	switch t.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+func cell(row []string, col int) string {
+	if col < len(row) {
+		return row[col]
+	}
+	return "null"
+}
+
+// ── Parsing ─────────────────────────────────────────────────────────────
+
+type filterTokenKind int
+
+const (
+	tokEnd    filterTokenKind = iota
+	tokName                   // column name or keyword
+	tokQuoted                 // "quoted column name"
+	tokString                 // 'text'
+	tokNumber                 // 42, -1.5, 2024-01-31
+	tokRegex                  // /pattern/flags
+	tokOp                     // = != <> < <= > >= ~ !~
+	tokParen                  // ( )
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string // the value: unquoted, or the pattern without slashes
+	flag string // regex flags
+}
+
+// is reports whether the token is the given keyword, ignoring case.
+func (t filterToken) is(word string) bool {
+	return t.kind == tokName && strings.EqualFold(t.text, word)
+}
+
+// lexFilter splits a filter expression into tokens. A slash starts a
+// regex only after ~ or !~, so patterns need no escaping but their own
+// slashes.
+func lexFilter(src string) ([]filterToken, error) {
+	var toks []filterToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			toks = append(toks, filterToken{kind: tokParen, text: string(c)})
+			i++
+		case c == '\'' || c == '"':
+			text, n, err := unquote(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			kind := tokString
+			if c == '"' {
+				kind = tokQuoted
+			}
+			toks = append(toks, filterToken{kind: kind, text: text})
+			i += n
+		case c == '/' && len(toks) > 0 && toks[len(toks)-1].kind == tokOp &&
+			strings.HasSuffix(toks[len(toks)-1].text, "~"):
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '/'; j++ {
+				if src[j] == '\\' && j+1 < len(src) && src[j+1] == '/' {
+					j++
+				}
+				b.WriteByte(src[j])
+			}
+			if j == len(src) {
+				return nil, errors.New("regex is missing its closing /")
+			}
+			j++
+			k := j
+			for k < len(src) && isLetter(src[k:]) {
+				k++
+			}
+			toks = append(toks, filterToken{kind: tokRegex, text: b.String(), flag: src[j:k]})
+			i = k
+		case strings.ContainsRune("=!<>~", rune(c)):
+			op := string(c)
+			if i+1 < len(src) {
+				if two := src[i : i+2]; two == "!=" || two == "<>" || two == "<=" || two == ">=" || two == "!~" || two == "==" {
+					op = two
+				}
+			}
+			if op == "!" {
+				return nil, errors.New("! must be followed by = or ~")
+			}
+			i += len(op)
+			switch op {
+			case "==":
+				op = "="
+			case "<>":
+				op = "!="
+			}
+			toks = append(toks, filterToken{kind: tokOp, text: op})
+		case isDigitByte(c) || c == '-' || c == '.':
+			// dates and times such as 2024-01-31 read as one value
+			j := i + 1
+			for j < len(src) && (isDigitByte(src[j]) || strings.IndexByte(".eE:+-", src[j]) >= 0) {
+				j++
+			}
+			toks = append(toks, filterToken{kind: tokNumber, text: src[i:j]})
+			i = j
+		case isLetter(src[i:]) || c == '_':
+			j := i
+			for j < len(src) && (isLetter(src[j:]) || isDigitByte(src[j]) || src[j] == '_' || src[j] == '.' || src[j] == '$') {
+				_, size := utf8.DecodeRuneInString(src[j:])
+				j += size
+			}
+			toks = append(toks, filterToken{kind: tokName, text: src[i:j]})
+			i = j
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("unexpected %q", r)
+		}
+	}
+	return append(toks, filterToken{kind: tokEnd}), nil
+}
+
+// unquote reads a quoted string at the start of s, where a doubled quote
+// stands for one, returning its value and length.
+func unquote(s string) (string, int, error) {
+	q := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != q {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			b.WriteByte(q)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("missing closing %c", q)
+}
+
+func isLetter(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLetter(r)
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// filterParser parses a filter expression for the columns of a result.
+type filterParser struct {
+	toks []filterToken
+	i    int
+
+	columns    []string
+	kind       func(col int) valueKind
+	defaultCol int  // column tested when a test starts with an operator
+	defaulted  bool // a test did
+}
+
+// parseFilter parses src for a result with the given columns. kind
+// tells how each column compares. defaulted reports whether a test left
+// out its column and so tests defaultCol.
+func parseFilter(src string, columns []string, kind func(int) valueKind, defaultCol int) (expr filterExpr, defaulted bool, err error) {
+	toks, err := lexFilter(src)
+	if err != nil {
+		return nil, false, err
+	}
+	p := &filterParser{toks: toks, columns: columns, kind: kind, defaultCol: defaultCol}
+	if p.peek().kind == tokEnd {
+		return nil, false, errors.New("empty filter")
+	}
+	expr, err = p.or()
+	if err != nil {
+		return nil, false, err
+	}
+	if t := p.peek(); t.kind != tokEnd {
+		return nil, false, fmt.Errorf("unexpected %s", describeToken(t))
+	}
+	return expr, p.defaulted, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.toks[p.i]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.toks[p.i]
+	if t.kind != tokEnd {
+		p.i++
+	}
+	return t
+}
+
+func (p *filterParser) or() (filterExpr, error) {
+	left, err := p.and()
+	for err == nil && p.peek().is("or") {
+		p.next()
+		var right filterExpr
+		if right, err = p.and(); err == nil {
+			left = orExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) and() (filterExpr, error) {
+	left, err := p.not()
+	for err == nil && p.peek().is("and") {
+		p.next()
+		var right filterExpr
+		if right, err = p.not(); err == nil {
+			left = andExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) not() (filterExpr, error) {
+	if p.peek().is("not") {
+		p.next()
+		e, err := p.not()
+		return notExpr{e}, err
+	}
+	if t := p.peek(); t.kind == tokParen && t.text == "(" {
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokParen || t.text != ")" {
+			return nil, fmt.Errorf("expected ) but found %s", describeToken(t))
+		}
+		return e, nil
+	}
+	return p.test()
+}
+
+// test parses one test of a column: a comparison, a match or is null.
+func (p *filterParser) test() (filterExpr, error) {
+	col := -1
+	switch t := p.peek(); {
+	case t.kind == tokOp || t.is("is"):
+		if p.defaultCol < 0 {
+			return nil, fmt.Errorf("expected a column before %s", describeToken(t))
+		}
+		col = p.defaultCol
+		p.defaulted = true
+	case t.kind == tokName || t.kind == tokQuoted:
+		p.next()
+		col = p.column(t.text, t.kind == tokQuoted)
+		if col < 0 {
+			return nil, fmt.Errorf("no column %s", t.text)
+		}
+	default:
+		return nil, fmt.Errorf("expected a column but found %s", describeToken(t))
+	}
+
+	t := p.next()
+	if t.is("is") {
+		not := false
+		if p.peek().is("not") {
+			p.next()
+			not = true
+		}
+		if v := p.next(); !v.is("null") {
+			return nil, fmt.Errorf("expected null after is but found %s", describeToken(v))
+		}
+		return nullTest{col: col, not: not}, nil
+	}
+	if t.kind != tokOp {
+		return nil, fmt.Errorf("expected an operator after %s but found %s", p.columns[col], describeToken(t))
+	}
+
+	v := p.next()
+	if t.text == "~" || t.text == "!~" {
+		if v.kind != tokRegex && v.kind != tokString {
+			return nil, fmt.Errorf("expected a /regex/ after %s but found %s", t.text, describeToken(v))
+		}
+		pattern := v.text
+		if strings.Contains(v.flag, "i") {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad regex: %w", err)
+		}
+		return matchTest{col: col, re: re, not: t.text == "!~"}, nil
+	}
+
+	switch {
+	case v.is("null") && (t.text == "=" || t.text == "!="):
+		return nullTest{col: col, not: t.text == "!="}, nil
+	case v.kind != tokString && v.kind != tokNumber && v.kind != tokName:
+		return nil, fmt.Errorf("expected a value after %s but found %s", t.text, describeToken(v))
+	}
+	test := compareTest{col: col, op: t.text, kind: p.kind(col), value: v.text}
+	switch test.kind {
+	case kindNumber:
+		n, ok := parseNumber(v.text)
+		if !ok {
+			return nil, fmt.Errorf("%s is a number column, %s is not a number", p.columns[col], v.text)
+		}
+		test.num = n
+	case kindTime:
+		at, ok := parseTime(v.text)
+		if !ok {
+			return nil, fmt.Errorf("%s is a time column, %s is not a date or time", p.columns[col], v.text)
+		}
+		test.at = at
+	}
+	return test, nil
+}
+
+// column finds a result column by name, ignoring case unless quoted when
+// no column has the exact name.
+func (p *filterParser) column(name string, quoted bool) int {
+	for i, c := range p.columns {
+		if c == name {
+			return i
+		}
+	}
+	if !quoted {
+		for i, c := range p.columns {
+			if strings.EqualFold(c, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func describeToken(t filterToken) string {
+	switch t.kind {
+	case tokEnd:
+		return "the end"
+	case tokString:
+		return "'" + t.text + "'"
+	case tokQuoted:
+		return `"` + t.text + `"`
+	case tokRegex:
+		return "/" + t.text + "/"
+	}
+	return t.text
+}
@@ -0,0 +1,128 @@
+package results
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var filterColumns = []string{"id", "name", "email", "created", "Note"}
+
+var filterRows = [][]string{
+	{"1", "ann", "ann@gmail.com", "2024-01-05", "null"},
+	{"2", "bob", "bob@example.org", "2024-03-01", "vip"},
+	{"10", "Cara", "CARA@GMAIL.COM", "2023-12-31", "null"},
+	{"null", "dan", "null", "null", "x"},
+}
+
+func filterKind(col int) valueKind {
+	switch col {
+	case 0:
+		return kindNumber
+	case 3:
+		return kindTime
+	}
+	return kindText
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		src       string
+		defCol    int
+		want      string // names of the rows kept
+		defaulted bool
+	}{
+		{"id > 1", -1, "bob Cara", false},
+		{"id >= 2 and id < 10", -1, "bob", false},
+		{"id = 10", -1, "Cara", false},
+		{"id != 1", -1, "bob Cara", false},
+		{"id <> 1", -1, "bob Cara", false},
+		{"name = 'bob' or name = 'dan'", -1, "bob dan", false},
+		{"NAME = ann", -1, "ann", false},
+		{"email ~ /gmail/", -1, "ann", false},
+		{"email ~ /gmail/i", -1, "ann Cara", false},
+		{"email !~ 'example'", -1, "ann Cara", false},
+		{"note is null", -1, "ann Cara", false},
+		{`"Note" is not null`, -1, "bob dan", false},
+		{"note = null", -1, "ann Cara", false},
+		{"not (name = 'ann' or id > 5)", -1, "bob dan", false},
+		{"not name = 'ann' and id < 5", -1, "bob", false},
+		{"created >= 2024-01-01", -1, "ann bob", false},
+		{"created < '2024-01-01'", -1, "Cara", false},
+		{"> 1", 0, "bob Cara", true},
+		{"is null", 2, "dan", true},
+	}
+	for _, tt := range tests {
+		expr, defaulted, err := parseFilter(tt.src, filterColumns, filterKind, tt.defCol)
+		if err != nil {
+			t.Errorf("parseFilter(%q): %v", tt.src, err)
+			continue
+		}
+		var kept []string
+		for _, row := range filterRows {
+			if expr.keep(row) {
+				kept = append(kept, row[1])
+			}
+		}
+		if got := strings.Join(kept, " "); got != tt.want {
+			t.Errorf("parseFilter(%q) keeps %q, want %q", tt.src, got, tt.want)
+		}
+		if defaulted != tt.defaulted {
+			t.Errorf("parseFilter(%q) defaulted = %v, want %v", tt.src, defaulted, tt.defaulted)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", "empty filter"},
+		{"nope = 1", "no column nope"},
+		{"= 1", "expected a column before ="},
+		{"id > abc", "not a number"},
+		{"created > someday", "not a date or time"},
+		{"name ~ 'a'(", "unexpected ("},
+		{"(id = 1", "expected ) but found the end"},
+		{"name ~ /[/", "bad regex"},
+		{"name is 1", "expected null after is"},
+		{"name 'x'", "expected an operator after name"},
+		{"id = 1 and", "expected a column but found the end"},
+		{`"note" is null`, "no column note"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseFilter(tt.src, filterColumns, filterKind, -1)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseFilter(%q) error = %v, want one containing %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestLexFilter(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"a>=1", []string{"a", ">=", "1"}},
+		{`"Odd Col" = 'it''s'`, []string{"Odd Col", "=", "it's"}},
+		{"x ~ /a\\/b/i", []string{"x", "~", "a/b"}},
+		{"(a<>b)", []string{"(", "a", "!=", "b", ")"}},
+	}
+	for _, tt := range tests {
+		toks, err := lexFilter(tt.src)
+		if err != nil {
+			t.Errorf("lexFilter(%q): %v", tt.src, err)
+			continue
+		}
+		var got []string
+		for _, tok := range toks {
+			if tok.kind != tokEnd {
+				got = append(got, tok.text)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("lexFilter(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -9,3 +9,25 @@ type SetEditorQueryMsg struct {
 type StatusNotifyMsg struct {
 	Message string
 }
+
+// RunQueryMsg tells the app to load a query into the editor and execute it
+type RunQueryMsg struct {
+	Query string
+}
+
+// RerunQueryMsg tells the app to run a variant of the tab's last query,
+// such as the query sorted on the server, with the parameters it was
+// bound to, leaving the editor as it is
+type RerunQueryMsg struct {
+	Query string
+}
+
+// DiffWithConnectionMsg asks the app to run Query on a saved connection
+// and hand the result back through SetDiffAgainst
+type DiffWithConnectionMsg struct {
+	Connection int
+	Query      string
+}
+
+// ShowPinnedMsg asks the app to show the pinned results
+type ShowPinnedMsg struct{}
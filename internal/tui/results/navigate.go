@@ -0,0 +1,214 @@
+package results
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/sql"
+)
+
+// maxNavHistory bounds the back/forward stacks.
+const maxNavHistory = 50
+
+// navEntry is a visited result, restored by re-running its query. Only
+// queries that return rows without changing any are recorded, so going
+// back never repeats an UPDATE or DELETE.
+type navEntry struct {
+	query   string
+	cursorY int
+	cursorX int
+}
+
+// recordVisit pushes the current result onto the back stack before a new
+// result replaces it. Jumps made through the stacks push for themselves.
+func (m *Model) recordVisit() {
+	if m.navigating {
+		m.navigating = false
+		return
+	}
+	if m.lastQuery == "" || m.result == nil {
+		return
+	}
+	if n := len(m.navBack); n > 0 && m.navBack[n-1].query == m.lastQuery {
+		return
+	}
+	m.pushBack(m.currentEntry())
+	m.navForward = nil
+}
+
+func (m Model) currentEntry() navEntry {
+	return navEntry{query: m.lastQuery, cursorY: m.cursorY, cursorX: m.cursorX}
+}
+
+func (m *Model) pushBack(e navEntry) {
+	if !sql.ReturnsRows(e.query) {
+		return
+	}
+	m.navBack = append(m.navBack, e)
+	if len(m.navBack) > maxNavHistory {
+		m.navBack = m.navBack[len(m.navBack)-maxNavHistory:]
+	}
+}
+
+// restoreCursor puts the cursor back where it was when a query is revisited.
+func (m *Model) restoreCursor() {
+	if m.restore == nil || m.restore.query != m.lastQuery {
+		return
+	}
+	// the query may return fewer rows or columns this time
+	if m.result != nil {
+		m.cursorY = min(m.restore.cursorY, max(0, m.result.RowCount-1))
+		m.cursorX = min(m.restore.cursorX, max(0, len(m.result.Columns)-1))
+	}
+	m.restore = nil
+	m.ensureVerticalWindow()
+	m.ensureHorizontalWindow()
+}
+
+// jumpTo runs a query as a new step in the navigation history.
+func (m *Model) jumpTo(query string) tea.Cmd {
+	m.pushBack(m.currentEntry())
+	m.navForward = nil
+	m.navigating = true
+	return runQuery(query)
+}
+
+func (m *Model) navigateBack() tea.Cmd {
+	n := len(m.navBack)
+	if n == 0 {
+		m.statusMessage = "Nothing to go back to"
+		return nil
+	}
+	target := m.navBack[n-1]
+	m.navBack = m.navBack[:n-1]
+	if !sql.ReturnsRows(target.query) {
+		m.statusMessage = "Cannot go back to a query that changes data"
+		return nil
+	}
+	if sql.ReturnsRows(m.lastQuery) {
+		m.navForward = append(m.navForward, m.currentEntry())
+	}
+	m.navigating = true
+	m.restore = &target
+	return runQuery(target.query)
+}
+
+func (m *Model) navigateForward() tea.Cmd {
+	n := len(m.navForward)
+	if n == 0 {
+		m.statusMessage = "Nothing to go forward to"
+		return nil
+	}
+	target := m.navForward[n-1]
+	m.navForward = m.navForward[:n-1]
+	if !sql.ReturnsRows(target.query) {
+		m.statusMessage = "Cannot go forward to a query that changes data"
+		return nil
+	}
+	m.pushBack(m.currentEntry())
+	m.navigating = true
+	m.restore = &target
+	return runQuery(target.query)
+}
+
+func runQuery(query string) tea.Cmd {
+	return func() tea.Msg {
+		return RunQueryMsg{Query: query}
+	}
+}
+
+// ── Foreign keys ────────────────────────────────────────────────────────
+
+// cursorForeignKey returns the outgoing foreign key that contains the
+// column under the cursor.
+func (m Model) cursorForeignKey() (database.ForeignKey, bool) {
+	if m.tableInfo == nil {
+		return database.ForeignKey{}, false
+	}
+	name := m.getColumnName()
+	for _, fk := range m.tableInfo.ForeignKeys {
+		for _, c := range fk.Columns {
+			if c == name {
+				return fk, true
+			}
+		}
+	}
+	return database.ForeignKey{}, false
+}
+
+// doFollowParent queries the row referenced by the foreign key under the cursor.
+func (m *Model) doFollowParent() tea.Cmd {
+	fk, ok := m.cursorForeignKey()
+	if !ok {
+		m.statusMessage = "Not a foreign-key column"
+		return nil
+	}
+
+	cond, err := m.referenceCondition(fk.Columns, fk.RefColumns)
+	if err != nil {
+		m.statusMessage = "Cannot follow reference: " + err.Error()
+		return nil
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s;",
+		database.QualifiedName(fk.RefSchema, fk.RefTable), cond)
+	return m.jumpTo(query)
+}
+
+// doListChildren queries the rows that reference the current row. With
+// several referencing constraints a picker is shown first.
+func (m *Model) doListChildren() tea.Cmd {
+	if m.tableInfo == nil || len(m.tableInfo.ReferencedBy) == 0 {
+		m.statusMessage = "No tables reference this table"
+		return nil
+	}
+	if len(m.tableInfo.ReferencedBy) == 1 {
+		return m.followChild(m.tableInfo.ReferencedBy[0])
+	}
+	m.viewMode = ViewReferencePicker
+	m.menuCursor = 0
+	return nil
+}
+
+func (m *Model) followChild(fk database.ForeignKey) tea.Cmd {
+	// values come from the parent columns of the current row
+	cond, err := m.referenceCondition(fk.RefColumns, fk.Columns)
+	if err != nil {
+		m.statusMessage = "Cannot list referencing rows: " + err.Error()
+		return nil
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT 100;",
+		database.QualifiedName(fk.Schema, fk.Table), cond)
+	return m.jumpTo(query)
+}
+
+// referenceCondition matches target columns against the values of the
+// paired source columns in the cursor row.
+func (m Model) referenceCondition(source, target []string) (string, error) {
+	conds := make([]string, len(source))
+	for i, name := range source {
+		idx := m.resultColumnIndex(name)
+		if idx < 0 {
+			return "", fmt.Errorf("column %s is not in the result", name)
+		}
+		v := m.getCellValueAt(m.cursorY, idx)
+		if v == "null" {
+			return "", fmt.Errorf("%s is NULL", name)
+		}
+		col, _ := m.columnInfo(name)
+		lit, ok := sqlLiteral(col, v)
+		if !ok {
+			return "", fmt.Errorf("cannot compare %s values exactly", col.DataType)
+		}
+		conds[i] = database.QuoteIdent(target[i]) + " = " + lit
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+func describeReference(fk database.ForeignKey) string {
+	return fmt.Sprintf("%s (%s) → %s",
+		database.QualifiedName(fk.Schema, fk.Table),
+		strings.Join(fk.Columns, ", "),
+		strings.Join(fk.RefColumns, ", "))
+}
@@ -0,0 +1,205 @@
+package results
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// SetStore sets where results are kept to flip back to and pin. Without
+// a store, as for the pinned results screen, results are not kept.
+func (m *Model) SetStore(s *Store) {
+	m.store = s
+}
+
+// runsQueries reports whether keys may run queries, which the app runs
+// in the active tab. Panes of the pinned results screen have no store and
+// no tab of their own, so they do not.
+func (m *Model) runsQueries() bool {
+	if m.store == nil {
+		m.statusMessage = "Not available on a pinned result"
+		return false
+	}
+	return true
+}
+
+// Release lets the store evict this model's unpinned results, when the
+// tab showing them closes.
+func (m *Model) Release() {
+	m.hold(&m.onScreen, nil)
+	m.hold(&m.inBaseline, nil)
+	if m.store != nil {
+		m.store.release(m.recent)
+	}
+	m.recent = nil
+	m.showing = 0
+}
+
+// hold points use, one of the model's uses of a kept result, at snap,
+// telling the store which results are in use.
+func (m *Model) hold(use **Snapshot, snap *Snapshot) {
+	if *use == snap || m.store == nil {
+		return
+	}
+	old := *use
+	*use = snap
+	if snap != nil {
+		m.store.use(snap)
+	}
+	if old != nil {
+		m.store.unuse(old)
+	}
+}
+
+// remember keeps a new result in the store as the newest of this tab.
+func (m *Model) remember() {
+	if m.store == nil || !m.HasResult() {
+		m.showing = len(m.recent)
+		m.hold(&m.onScreen, nil)
+		return
+	}
+	snap := &Snapshot{Result: m.result, At: time.Now()}
+	m.store.keep(snap)
+	m.hold(&m.onScreen, snap)
+	m.recent = slices.DeleteFunc(m.recent, func(s *Snapshot) bool { return s.Result == nil })
+	m.recent = append(m.recent, snap)
+	if extra := len(m.recent) - maxRecentResults; extra > 0 {
+		m.store.release(m.recent[:extra])
+		m.recent = slices.Clone(m.recent[extra:])
+	}
+	m.showing = len(m.recent) - 1
+}
+
+// current returns the kept snapshot of the result on screen, or nil.
+func (m Model) current() *Snapshot {
+	if m.showing < 0 || m.showing >= len(m.recent) {
+		return nil
+	}
+	if snap := m.recent[m.showing]; snap.Result != nil && snap.Result == m.base() {
+		return snap
+	}
+	return nil
+}
+
+// flip shows the next kept result in direction dir, skipping evicted
+// ones: -1 for older, 1 for newer.
+func (m *Model) flip(dir int) {
+	for i := m.showing + dir; i >= 0 && i < len(m.recent); i += dir {
+		if m.recent[i].Result != nil {
+			m.showSnapshot(i)
+			return
+		}
+	}
+	if dir < 0 {
+		m.statusMessage = "No older results"
+	} else {
+		m.statusMessage = "No newer results"
+	}
+}
+
+// showSnapshot puts kept result i on screen. Row actions stay available
+// on the newest result only, as older ones may no longer match the
+// table.
+func (m *Model) showSnapshot(i int) {
+	snap := m.recent[i]
+	m.showing = i
+	m.hold(&m.onScreen, snap)
+	m.result = snap.Result
+	m.lastQuery = snap.Query
+	m.tableInfo = nil
+	if i == len(m.recent)-1 {
+		m.tableInfo = snap.info
+	}
+	m.err = nil
+	m.scrollY = 0
+	m.cursorY = 0
+	m.cursorX = 0
+	m.colOffset = 0
+	m.viewMode = ViewNormal
+	m.menuCursor = 0
+	m.marked = nil
+	m.diff = nil
+	m.sort, m.loaded = nil, nil
+	m.filters = nil
+	m.search = nil
+	m.loadLayout()
+}
+
+// startPin asks for the name to pin the result on screen under.
+func (m *Model) startPin() {
+	snap := m.current()
+	if snap == nil {
+		m.statusMessage = "Nothing to pin"
+		return
+	}
+	name := snap.Name
+	if name == "" {
+		if _, table, ok := m.SourceTable(); ok {
+			name = table
+		} else {
+			name = fmt.Sprintf("Result %d", len(m.store.Pins())+1)
+		}
+	}
+	ti := widget.NewInput()
+	ti.Prompt = "Pin as: "
+	ti.CharLimit = 40
+	ti.SetValue(name)
+	ti.CursorEnd()
+	ti.Focus()
+	m.pinInput = ti
+	m.viewMode = ViewPinPrompt
+}
+
+func (m Model) updatePinPrompt(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.viewMode = ViewNormal
+		return m, nil
+	case "enter":
+		name := strings.TrimSpace(m.pinInput.Value())
+		if name == "" {
+			return m, nil
+		}
+		m.viewMode = ViewNormal
+		snap := m.current()
+		if snap == nil {
+			m.statusMessage = "Nothing to pin"
+			return m, nil
+		}
+		if err := m.store.Pin(snap, name); err != nil {
+			m.statusMessage = "Cannot pin: " + err.Error()
+			return m, nil
+		}
+		m.statusMessage = "Pinned as " + name + " (P: pinned results)"
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.pinInput, cmd = m.pinInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderPinPrompt() string {
+	return "  " + m.pinInput.View() + theme.StyleMuted.Render("  Enter pin | Esc cancel")
+}
+
+// recentInfo describes the result on screen when it is an older or a
+// pinned one, for the header.
+func (m Model) recentInfo() string {
+	snap := m.current()
+	if snap == nil {
+		return ""
+	}
+	var parts []string
+	if n := len(m.recent); m.showing < n-1 {
+		parts = append(parts, fmt.Sprintf("result %d/%d from %s", m.showing+1, n, snap.At.Format("15:04:05")))
+	}
+	if snap.pinned {
+		parts = append(parts, "pinned as "+snap.Name)
+	}
+	return strings.Join(parts, " | ")
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/joacominatel/minadb/internal/database"
@@ -16,11 +17,18 @@ import (
 type ViewMode int
 
 const (
-	ViewNormal        ViewMode = iota
-	ViewRecordDetail           // vertical single-record view
-	ViewCopyRowPrompt          // format picker for copy row
-	ViewExportPrompt           // format picker for export
-	ViewDeleteConfirm          // red delete warning
+	ViewNormal          ViewMode = iota
+	ViewRecordDetail             // vertical single-record view
+	ViewCopyRowPrompt            // format picker for copy row
+	ViewExportPrompt             // format picker for export
+	ViewDeleteConfirm            // red delete warning
+	ViewReferencePicker          // choose a referencing table to list
+	ViewDiffSource               // choose what to compare the result with
+	ViewDataDiff                 // row-by-row comparison of two results
+	ViewPinPrompt                // name to pin the result under
+	ViewColumns                  // show, hide, reorder and freeze columns
+	ViewSearch                   // search pattern being typed
+	ViewFilter                   // filter bar over the loaded rows
 )
 
 // Model is the query results component.
@@ -33,7 +41,7 @@ type Model struct {
 	scrollY   int
 	cursorY   int
 	cursorX   int
-	colOffset int
+	colOffset int // first scrolled column, as a place among the shown ones
 	loading   bool
 	colWidths []int
 
@@ -41,6 +49,53 @@ type Model struct {
 	menuCursor    int    // field selector in record detail
 	lastQuery     string // SQL that produced the current result
 	statusMessage string // temporary feedback
+
+	tableInfo *database.TableInfo // source table metadata, nil for joins or until loaded
+	marked    map[int]bool        // rows marked for multi-row actions
+
+	// relationship navigation
+	navBack    []navEntry
+	navForward []navEntry
+	navigating bool      // next result comes from a jump, not a new query
+	restore    *navEntry // cursor to restore once a revisited query returns
+
+	// data diff
+	baseline    *database.QueryResult
+	baselineAt  time.Time
+	connections []string // saved connection names, offered as diff sources
+	diff        *diffState
+
+	// kept results
+	store    *Store
+	recent   []*Snapshot // this tab's results, oldest first
+	showing  int         // index into recent of the result on screen
+	pinInput textinput.Model
+
+	// kept results in use, which the store counts but does not evict
+	onScreen   *Snapshot
+	inBaseline *Snapshot
+
+	// sorting
+	sort         []sortKey
+	sortInMemory bool                  // sort was applied here rather than by the server
+	loaded       *database.QueryResult // the result as it came, while sorted or filtered in memory
+	pendingSort  []sortKey             // order asked of the server for sortQuery
+	sortQuery    string
+
+	// column layout
+	order   []int        // result columns in display order, hidden ones too
+	hidden  map[int]bool // result columns hidden
+	sized   map[int]int  // widths set by hand or fitted, by result column
+	frozen  int          // leading shown columns kept in view
+	layouts *Layouts
+
+	search *searchState // nil when not searching
+
+	// filters on the loaded rows
+	filters     []rowFilter
+	filterInput textinput.Model
+	filterErr   string
+	chip        int // filter Backspace removes in the bar
 }
 
 // New creates a new results model.
@@ -71,6 +126,7 @@ func (m *Model) SetLoading(l bool) {
 
 // SetResult sets the query result to display.
 func (m *Model) SetResult(r *database.QueryResult) {
+	m.recordVisit()
 	m.result = r
 	m.err = nil
 	m.scrollY = 0
@@ -81,7 +137,16 @@ func (m *Model) SetResult(r *database.QueryResult) {
 	m.viewMode = ViewNormal
 	m.menuCursor = 0
 	m.statusMessage = ""
+	m.tableInfo = nil
+	m.marked = nil
+	m.diff = nil
+	m.sort, m.pendingSort = m.pendingSort, nil
+	m.sortInMemory = false
+	m.loaded = nil
 	m.calculateColumnWidths()
+	m.resetColumns()
+	m.search = nil
+	m.remember()
 }
 
 // SetError sets an error to display.
@@ -96,11 +161,46 @@ func (m *Model) SetError(err error) {
 	m.viewMode = ViewNormal
 	m.menuCursor = 0
 	m.statusMessage = ""
+	m.tableInfo = nil
+	m.marked = nil
+	m.diff = nil
+	m.navigating = false
+	m.restore = nil
+	m.showing = len(m.recent)
+	m.hold(&m.onScreen, nil)
+	m.sort, m.pendingSort, m.loaded = nil, nil, nil
+	m.filters = nil
+	m.resetColumns()
+	m.search = nil
 }
 
 // SetLastQuery stores the SQL that produced the current result.
 func (m *Model) SetLastQuery(q string) {
+	// filters stay on while the same query is run again or sorted
+	keepFilters := q == m.lastQuery || q == m.sortQuery
 	m.lastQuery = q
+	if m.sort != nil && m.sortQuery != q {
+		// not the result of the sorted query asked for
+		m.sort = nil
+	}
+	m.sortQuery = ""
+	m.loadLayout()
+	if !keepFilters {
+		m.filters = nil
+	}
+	if len(m.filters) > 0 {
+		m.derive()
+	}
+	if snap := m.current(); snap != nil && m.showing == len(m.recent)-1 {
+		snap.Query = q
+	}
+	m.restoreCursor()
+}
+
+// Overlay reports whether a detail view, prompt or picker is open over
+// the table, so Esc belongs to it.
+func (m Model) Overlay() bool {
+	return m.viewMode != ViewNormal
 }
 
 // HasResult reports whether there's a result with columns.
@@ -162,6 +262,20 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			return m.updateExportPrompt(msg)
 		case ViewDeleteConfirm:
 			return m.updateDeleteConfirm(msg)
+		case ViewReferencePicker:
+			return m.updateReferencePicker(msg)
+		case ViewDiffSource:
+			return m.updateDiffSource(msg)
+		case ViewDataDiff:
+			return m.updateDataDiff(msg)
+		case ViewPinPrompt:
+			return m.updatePinPrompt(msg)
+		case ViewColumns:
+			return m.updateColumns(msg)
+		case ViewSearch:
+			return m.updateSearch(msg)
+		case ViewFilter:
+			return m.updateFilterBar(msg)
 		default:
 			return m.updateNormal(msg)
 		}
@@ -184,15 +298,9 @@ func (m Model) updateNormal(msg tea.KeyMsg) (Model, tea.Cmd) {
 			m.ensureVerticalWindow()
 		}
 	case "left", "h":
-		if m.result != nil && m.cursorX > 0 {
-			m.cursorX--
-			m.ensureHorizontalWindow()
-		}
+		m.stepColumn(-1)
 	case "right", "l":
-		if m.result != nil && m.cursorX < len(m.result.Columns)-1 {
-			m.cursorX++
-			m.ensureHorizontalWindow()
-		}
+		m.stepColumn(1)
 	case "pgup":
 		if m.result != nil {
 			step := max(1, m.visibleRows())
@@ -212,13 +320,9 @@ func (m Model) updateNormal(msg tea.KeyMsg) (Model, tea.Cmd) {
 			m.ensureVerticalWindow()
 		}
 	case "home":
-		m.cursorX = 0
-		m.ensureHorizontalWindow()
+		m.edgeColumn(false)
 	case "end":
-		if m.result != nil && len(m.result.Columns) > 0 {
-			m.cursorX = len(m.result.Columns) - 1
-			m.ensureHorizontalWindow()
-		}
+		m.edgeColumn(true)
 	case "g":
 		m.cursorY = 0
 		m.ensureVerticalWindow()
@@ -228,6 +332,40 @@ func (m Model) updateNormal(msg tea.KeyMsg) (Model, tea.Cmd) {
 			m.ensureVerticalWindow()
 		}
 
+	// marking
+	case " ":
+		if m.HasResult() && m.cursorY < len(m.result.Rows) {
+			if m.marked == nil {
+				m.marked = make(map[int]bool)
+			}
+			if m.marked[m.cursorY] {
+				delete(m.marked, m.cursorY)
+			} else {
+				m.marked[m.cursorY] = true
+			}
+			if m.cursorY < m.result.RowCount-1 {
+				m.cursorY++
+				m.ensureVerticalWindow()
+			}
+		}
+	case "esc":
+		m.marked = nil
+		m.search = nil
+
+	// search
+	case "/":
+		if m.HasResult() {
+			m.startSearch()
+		}
+	case "n":
+		if m.HasResult() {
+			m.nextMatch(1)
+		}
+	case "N":
+		if m.HasResult() {
+			m.nextMatch(-1)
+		}
+
 	// actions
 	case "c":
 		m.doCopyCell()
@@ -241,7 +379,11 @@ func (m Model) updateNormal(msg tea.KeyMsg) (Model, tea.Cmd) {
 		}
 	case "D":
 		if m.HasResult() {
-			m.viewMode = ViewDeleteConfirm
+			if m.tableInfo == nil {
+				m.statusMessage = "Cannot delete: result has no single source table"
+			} else {
+				m.viewMode = ViewDeleteConfirm
+			}
 		}
 	case "enter":
 		if m.HasResult() {
@@ -249,10 +391,104 @@ func (m Model) updateNormal(msg tea.KeyMsg) (Model, tea.Cmd) {
 			m.menuCursor = m.cursorX
 		}
 	case "f":
-		if m.HasResult() {
+		if m.HasResult() && m.runsQueries() {
 			cmd := m.doFilterByValue()
 			return m, cmd
 		}
+	case "|":
+		if m.HasResult() {
+			m.openFilterBar()
+		}
+
+	// relationships
+	case "F":
+		if m.HasResult() && m.runsQueries() {
+			return m, m.doFollowParent()
+		}
+	case "R":
+		if m.HasResult() && m.runsQueries() {
+			return m, m.doListChildren()
+		}
+	// data diff
+	case "B":
+		if m.HasResult() {
+			m.saveBaseline()
+		}
+	case "=":
+		if m.HasResult() {
+			m.viewMode = ViewDiffSource
+			m.menuCursor = 0
+		}
+
+	case "s":
+		return m, m.cycleSort(false)
+	case "S":
+		return m, m.cycleSort(true)
+
+	// columns
+	case "C":
+		if m.HasResult() {
+			m.openColumns()
+		}
+	case "H":
+		if m.HasResult() {
+			m.hideColumn(m.cursorX)
+		}
+	case "{":
+		if m.HasResult() {
+			m.moveColumn(-1)
+		}
+	case "}":
+		if m.HasResult() {
+			m.moveColumn(1)
+		}
+	case "+":
+		if m.HasResult() {
+			m.resizeColumn(widthStep)
+		}
+	case "-":
+		if m.HasResult() {
+			m.resizeColumn(-widthStep)
+		}
+	case "a":
+		if m.HasResult() {
+			m.fitColumn()
+		}
+	case "A":
+		if m.HasResult() {
+			m.resetWidths()
+		}
+	case "z":
+		if m.HasResult() {
+			m.toggleFreeze()
+		}
+
+	case "[":
+		if m.runsQueries() {
+			return m, m.navigateBack()
+		}
+	case "]":
+		if m.runsQueries() {
+			return m, m.navigateForward()
+		}
+
+	// kept results
+	case "<":
+		if m.store != nil {
+			m.flip(-1)
+		}
+	case ">":
+		if m.store != nil {
+			m.flip(1)
+		}
+	case "p":
+		if m.store != nil && m.HasResult() {
+			m.startPin()
+		}
+	case "P":
+		if m.store != nil {
+			return m, func() tea.Msg { return ShowPinnedMsg{} }
+		}
 	}
 
 	return m, nil
@@ -273,7 +509,7 @@ func (m Model) updateRecordDetail(msg tea.KeyMsg) (Model, tea.Cmd) {
 	case "c":
 		m.doCopyCellAt(m.cursorY, m.menuCursor)
 	case "f":
-		if m.result != nil && m.menuCursor < len(m.result.Columns) {
+		if m.result != nil && m.menuCursor < len(m.result.Columns) && m.runsQueries() {
 			origX := m.cursorX
 			m.cursorX = m.menuCursor
 			cmd := m.doFilterByValue()
@@ -330,6 +566,32 @@ func (m Model) updateDeleteConfirm(msg tea.KeyMsg) (Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m Model) updateReferencePicker(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if m.tableInfo == nil {
+		m.viewMode = ViewNormal
+		return m, nil
+	}
+	refs := m.tableInfo.ReferencedBy
+	switch msg.String() {
+	case "esc":
+		m.viewMode = ViewNormal
+	case "up", "k":
+		if m.menuCursor > 0 {
+			m.menuCursor--
+		}
+	case "down", "j":
+		if m.menuCursor < len(refs)-1 {
+			m.menuCursor++
+		}
+	case "enter":
+		m.viewMode = ViewNormal
+		if m.menuCursor < len(refs) {
+			return m, m.followChild(refs[m.menuCursor])
+		}
+	}
+	return m, nil
+}
+
 // ── View ────────────────────────────────────────────────────────────────
 
 func (m Model) View() string {
@@ -352,10 +614,14 @@ func (m Model) View() string {
 			theme.StyleMuted.Render("  Execute a query to see results")
 	}
 
-	stats := fmt.Sprintf("%d row(s) | %s",
-		m.result.RowCount,
-		m.result.Duration.Round(time.Microsecond).String(),
-	)
+	rows := fmt.Sprintf("%d row(s)", m.result.RowCount)
+	if len(m.filters) > 0 {
+		rows = fmt.Sprintf("%d of %d row(s)", m.result.RowCount, m.base().RowCount)
+	}
+	stats := rows + " | " + m.result.Duration.Round(time.Microsecond).String()
+	if info := m.recentInfo(); info != "" {
+		stats += " | " + info
+	}
 	header := titleStyle.Render("Results") + "  " +
 		theme.StyleMuted.Render(stats)
 
@@ -367,36 +633,61 @@ func (m Model) View() string {
 		return header + "\n" + m.renderRecordDetail()
 	}
 
+	if m.viewMode == ViewReferencePicker {
+		return header + "\n" + m.renderReferencePicker()
+	}
+
+	if m.viewMode == ViewColumns {
+		return header + "\n" + m.renderColumns()
+	}
+
+	if m.viewMode == ViewDiffSource {
+		return header + "\n" + m.renderDiffSource()
+	}
+
+	if m.viewMode == ViewDataDiff && m.diff != nil {
+		return header + "\n" + m.renderDataDiff()
+	}
+
 	return header + "\n" + m.renderTableView()
 }
 
 func (m Model) renderTableView() string {
 	var b strings.Builder
 
-	fromCol, toCol := m.visibleColumnRange()
-	tableCols := m.result.Columns[fromCol:toCol]
-	widths := slices.Clone(m.colWidths[fromCol:toCol])
+	if len(m.filters) > 0 {
+		b.WriteString(m.renderChips())
+		b.WriteString("\n")
+	}
 
-	activeCol := -1
-	if m.cursorX >= fromCol && m.cursorX < toCol {
-		activeCol = m.cursorX - fromCol
+	cols, frozen := m.visibleColumns()
+	widths := make([]int, len(cols))
+	headers := make([]string, len(cols))
+	activeCol := slices.Index(cols, m.cursorX)
+	for i, c := range cols {
+		widths[i] = m.colWidths[c]
+		headers[i] = m.headerLabel(c, widths[i])
 	}
 
-	b.WriteString(m.renderTopBorder(widths))
+	b.WriteString(m.renderTopBorder(widths, frozen))
 	b.WriteString("\n")
-	b.WriteString(m.renderRow(tableCols, widths, true, false, activeCol))
+	b.WriteString(m.renderRow(headers, widths, frozen, true, false, false, activeCol, nil))
 	b.WriteString("\n")
-	b.WriteString(m.renderSeparator(widths))
+	b.WriteString(m.renderSeparator(widths, frozen))
 	b.WriteString("\n")
 
 	visibleRows := m.visibleRows()
 	rowEnd := min(len(m.result.Rows), m.scrollY+visibleRows)
+	cells := make([]string, len(cols))
 	for i := m.scrollY; i < rowEnd; i++ {
-		line := m.renderRow(m.result.Rows[i][fromCol:toCol], widths, false, i == m.cursorY, activeCol)
+		for j, c := range cols {
+			cells[j] = m.result.Rows[i][c]
+		}
+		line := m.renderRow(cells, widths, frozen, false, i == m.cursorY, m.marked[i], activeCol, m.rowMatches(i, cols))
 		b.WriteString(line)
 		b.WriteString("\n")
 	}
-	b.WriteString(m.renderBottomBorder(widths))
+	b.WriteString(m.renderBottomBorder(widths, frozen))
 	b.WriteString("\n")
 
 	switch m.viewMode {
@@ -406,14 +697,22 @@ func (m Model) renderTableView() string {
 		b.WriteString(m.renderExportPrompt())
 	case ViewDeleteConfirm:
 		b.WriteString(m.renderDeleteConfirm())
+	case ViewPinPrompt:
+		b.WriteString(m.renderPinPrompt())
+	case ViewSearch:
+		b.WriteString(m.renderSearchPrompt())
+	case ViewFilter:
+		b.WriteString(m.renderFilterBar())
 	default:
-		b.WriteString(m.renderNormalFooter(fromCol, toCol, visibleRows))
+		b.WriteString(m.renderNormalFooter())
 	}
 
 	return b.String()
 }
 
-func (m Model) renderRow(cells []string, widths []int, isHeader bool, selected bool, activeCol int) string {
+// renderRow renders one line of cells; the first frozen ones are set
+// apart by a double rule, and hits marks the ones matching the search.
+func (m Model) renderRow(cells []string, widths []int, frozen int, isHeader bool, selected bool, marked bool, activeCol int, hits []bool) string {
 	var b strings.Builder
 
 	sepStyle := lipgloss.NewStyle()
@@ -424,7 +723,10 @@ func (m Model) renderRow(cells []string, widths []int, isHeader bool, selected b
 	b.WriteString(sepStyle.Render("│"))
 
 	for i, cell := range cells {
-		if i > 0 {
+		switch {
+		case i > 0 && i == frozen:
+			b.WriteString(sepStyle.Render("║"))
+		case i > 0:
 			b.WriteString(sepStyle.Render("│"))
 		}
 
@@ -441,8 +743,14 @@ func (m Model) renderRow(cells []string, widths []int, isHeader bool, selected b
 		case selected && activeCol >= 0 && i == activeCol:
 			style = style.Background(theme.ColorPrimary).
 				Foreground(lipgloss.Color("255")).Bold(true)
+		case hits != nil && hits[i]:
+			style = style.Background(theme.ColorWarning).Foreground(lipgloss.Color("0"))
+		case selected && marked:
+			style = style.Background(lipgloss.Color("236")).Foreground(theme.ColorWarning)
 		case selected:
 			style = style.Background(lipgloss.Color("236"))
+		case marked:
+			style = style.Foreground(theme.ColorWarning)
 		}
 
 		b.WriteString(style.Render(content))
@@ -452,29 +760,39 @@ func (m Model) renderRow(cells []string, widths []int, isHeader bool, selected b
 	return b.String()
 }
 
-func (m Model) renderTopBorder(widths []int) string {
-	return m.renderBorder("┌", "┬", "┐", widths)
+func (m Model) renderTopBorder(widths []int, frozen int) string {
+	return m.renderBorder("┌", "┬", "╥", "┐", widths, frozen)
 }
 
-func (m Model) renderBottomBorder(widths []int) string {
-	return m.renderBorder("└", "┴", "┘", widths)
+func (m Model) renderBottomBorder(widths []int, frozen int) string {
+	return m.renderBorder("└", "┴", "╨", "┘", widths, frozen)
 }
 
-func (m Model) renderSeparator(widths []int) string {
-	return m.renderBorder("├", "┼", "┤", widths)
+func (m Model) renderSeparator(widths []int, frozen int) string {
+	return m.renderBorder("├", "┼", "╫", "┤", widths, frozen)
 }
 
-func (m Model) renderBorder(left, center, right string, widths []int) string {
-	parts := make([]string, len(widths))
+func (m Model) renderBorder(left, center, split, right string, widths []int, frozen int) string {
+	var b strings.Builder
+	b.WriteString(left)
 	for i, w := range widths {
-		parts[i] = strings.Repeat("─", w+2)
+		switch {
+		case i > 0 && i == frozen:
+			b.WriteString(split)
+		case i > 0:
+			b.WriteString(center)
+		}
+		b.WriteString(strings.Repeat("─", w+2))
 	}
-	line := left + strings.Join(parts, center) + right
-	return lipgloss.NewStyle().Foreground(theme.ColorBorder).Render(line)
+	b.WriteString(right)
+	return lipgloss.NewStyle().Foreground(theme.ColorBorder).Render(b.String())
 }
 
 func (m Model) visibleRows() int {
 	v := m.height - 8
+	if len(m.filters) > 0 {
+		v-- // filter chips
+	}
 	if v < 1 {
 		v = 1
 	}
@@ -509,78 +827,115 @@ func (m *Model) ensureVerticalWindow() {
 	}
 }
 
+// ensureHorizontalWindow scrolls the columns so the cursor's is in view,
+// moving the cursor to the nearest shown column when its own is hidden.
 func (m *Model) ensureHorizontalWindow() {
-	if m.result == nil || len(m.result.Columns) == 0 {
+	if !m.HasResult() {
 		return
 	}
-	if m.cursorX < 0 {
-		m.cursorX = 0
+	shown := m.shown()
+	pos := slices.Index(shown, m.cursorX)
+	if pos < 0 {
+		// the next shown column after it in display order, or the last
+		at := slices.Index(m.order, m.cursorX)
+		pos = len(shown) - 1
+		for i, c := range shown {
+			if slices.Index(m.order, c) > at {
+				pos = i
+				break
+			}
+		}
+		m.cursorX = shown[pos]
 	}
-	if m.cursorX >= len(m.result.Columns) {
-		m.cursorX = len(m.result.Columns) - 1
+
+	frozen := m.frozenShown(shown)
+	m.colOffset = min(max(m.colOffset, frozen), len(shown)-1)
+	if pos < frozen {
+		return
 	}
 
 	// scroll left if cursor went past the left edge
-	if m.cursorX < m.colOffset {
-		m.colOffset = m.cursorX
+	if pos < m.colOffset {
+		m.colOffset = pos
 	}
 
 	// scroll right until cursor is visible
-	for m.colOffset < len(m.result.Columns) {
-		_, toCol := m.visibleColumnRange()
-		if m.cursorX < toCol {
+	for m.colOffset < pos {
+		if cols, _ := m.visibleColumns(); slices.Contains(cols, m.cursorX) {
 			break
 		}
 		m.colOffset++
 	}
 }
 
-func (m Model) visibleColumnRange() (int, int) {
-	if m.result == nil || len(m.result.Columns) == 0 {
-		return 0, 0
-	}
-
-	if m.colOffset >= len(m.result.Columns) {
-		return 0, len(m.result.Columns)
+// visibleColumns returns the result columns that fit the width, in
+// display order: the frozen ones, then the others from colOffset on. n
+// is how many of them are frozen.
+func (m Model) visibleColumns() (cols []int, n int) {
+	if !m.HasResult() {
+		return nil, 0
 	}
 
+	shown := m.shown()
+	n = m.frozenShown(shown)
 	available := max(20, m.width-4)
-	start := max(0, m.colOffset)
-	end := start
 	total := 1
-
-	for i := start; i < len(m.colWidths); i++ {
-		colWidth := m.colWidths[i] + 3
-		if end > start && total+colWidth > available {
+	for i, c := range shown {
+		if i >= n && i < m.colOffset {
+			continue
+		}
+		colWidth := m.colWidths[c] + 3
+		if len(cols) > 0 && total+colWidth > available {
 			break
 		}
 		total += colWidth
-		end = i + 1
-	}
-
-	if end == start {
-		end = min(start+1, len(m.result.Columns))
+		cols = append(cols, c)
 	}
-
-	return start, end
+	return cols, min(n, len(cols))
 }
 
 // ── Footer / Overlays ───────────────────────────────────────────────────
 
-func (m Model) renderNormalFooter(fromCol, toCol, visibleRows int) string {
+func (m Model) renderNormalFooter() string {
 	if m.result == nil {
 		return ""
 	}
 
-	colInfo := fmt.Sprintf("Col %d/%d", m.cursorX+1, len(m.result.Columns))
+	shown := m.shown()
+	colInfo := fmt.Sprintf("Col %d/%d", slices.Index(shown, m.cursorX)+1, len(shown))
+	if hidden := len(m.result.Columns) - len(shown); hidden > 0 {
+		colInfo += fmt.Sprintf(" (%d hidden)", hidden)
+	}
 	rowInfo := fmt.Sprintf("Row %d/%d", m.cursorY+1, m.result.RowCount)
+	if len(m.marked) > 0 {
+		rowInfo += fmt.Sprintf(" (%d marked)", len(m.marked))
+	}
+	if info := m.searchInfo(); info != "" {
+		rowInfo += " | " + info
+	}
 
 	if m.statusMessage != "" {
 		return theme.StyleSuccess.Render("  "+m.statusMessage) + "  " +
 			theme.StyleMuted.Render(colInfo+" | "+rowInfo)
 	}
 
-	actions := "c:copy  y:row  e:export  f:filter  D:delete  Enter:detail"
+	actions := "c:copy  y:row  e:export  f:filter  |:filter rows  /:search  s/S:sort  C:columns  space:mark  D:delete  Enter:detail"
+	if fk, ok := m.cursorForeignKey(); ok {
+		actions = "F:→ " + database.QualifiedName(fk.RefSchema, fk.RefTable) + "  " + actions
+	}
+	if m.tableInfo != nil && len(m.tableInfo.ReferencedBy) > 0 {
+		actions += "  R:referencing"
+	}
+	actions += "  B:baseline  =:diff"
+	if len(m.navBack) > 0 || len(m.navForward) > 0 {
+		actions += "  [/]:back/fwd"
+	}
+	if m.store != nil {
+		if len(m.recent) > 1 {
+			actions += "  </>:older/newer"
+		}
+		actions += "  p:pin"
+	}
 	return theme.StyleMuted.Render(colInfo + " | " + rowInfo + " | " + actions)
 }
 
@@ -680,6 +1035,33 @@ func (m Model) renderRecordDetail() string {
 	return b.String()
 }
 
+func (m Model) renderReferencePicker() string {
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().
+		Foreground(theme.ColorHighlight).
+		Bold(true).
+		Render("  Rows referencing this record from:"))
+	b.WriteString("\n")
+
+	refs := m.tableInfo.ReferencedBy
+	visible := max(1, m.height-4)
+	scrollOff := max(0, m.menuCursor-visible+1)
+	for i := scrollOff; i < len(refs) && i < scrollOff+visible; i++ {
+		line := "  " + describeReference(refs[i])
+		if i == m.menuCursor {
+			line = lipgloss.NewStyle().
+				Foreground(theme.ColorHighlight).
+				Bold(true).
+				Render("> " + describeReference(refs[i]))
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString(theme.StyleMuted.Render("↑/↓ navigate | Enter open | Esc cancel"))
+	return b.String()
+}
+
 func (m Model) renderCopyRowPrompt() string {
 	label := lipgloss.NewStyle().
 		Foreground(theme.ColorHighlight).
@@ -699,10 +1081,14 @@ func (m Model) renderExportPrompt() string {
 }
 
 func (m Model) renderDeleteConfirm() string {
+	prompt := "⚠ DELETE this record? "
+	if n := len(m.marked); n > 0 {
+		prompt = fmt.Sprintf("⚠ DELETE %d marked records? ", n)
+	}
 	warning := lipgloss.NewStyle().
 		Foreground(theme.ColorError).
 		Bold(true).
-		Render("⚠ DELETE this record? ")
+		Render(prompt)
 	hint := theme.StyleMuted.Render("Sends to editor for review. [y]Yes [Esc]Cancel")
 	return warning + hint
 }
@@ -0,0 +1,226 @@
+package results
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// rowFilter is a filter on the loaded rows, kept as typed so it applies
+// again to the next result of the same query.
+type rowFilter struct {
+	text   string
+	column string // column tested by tests that leave theirs out
+}
+
+// label is the filter as shown on its chip.
+func (f rowFilter) label() string {
+	switch {
+	case f.column == "":
+		return f.text
+	case isPlainName(f.column):
+		return f.column + " " + f.text
+	}
+	return `"` + strings.ReplaceAll(f.column, `"`, `""`) + `" ` + f.text
+}
+
+// compileFilter parses the filter for the columns of r.
+func (m Model) compileFilter(f rowFilter, r *database.QueryResult) (filterExpr, bool, error) {
+	col := -1
+	for i, c := range r.Columns {
+		if c == f.column {
+			col = i
+			break
+		}
+	}
+	kind := func(col int) valueKind { return m.columnKind(r, col) }
+	return parseFilter(f.text, r.Columns, kind, col)
+}
+
+// derive rebuilds the rows shown from the result as it came, sorting
+// them when the sort is done in memory and keeping the ones every filter
+// passes. Filters that no longer apply to the result are dropped.
+func (m *Model) derive() {
+	base := m.base()
+	m.marked = nil
+	defer m.refreshSearch()
+	if !m.sortInMemory && len(m.filters) == 0 {
+		m.loaded = nil
+		m.result = base
+		m.ensureVerticalWindow()
+		return
+	}
+
+	rows := base.Rows
+	if m.sortInMemory {
+		rows = m.sortRows(rows)
+	}
+	if len(m.filters) > 0 {
+		var exprs []filterExpr
+		var kept []rowFilter
+		for _, f := range m.filters {
+			expr, _, err := m.compileFilter(f, base)
+			if err != nil {
+				m.statusMessage = "Dropped filter " + f.label() + ": " + err.Error()
+				continue
+			}
+			exprs = append(exprs, expr)
+			kept = append(kept, f)
+		}
+		m.filters = kept
+
+		passed := make([][]string, 0, len(rows))
+	rowLoop:
+		for _, row := range rows {
+			for _, expr := range exprs {
+				if !expr.keep(row) {
+					continue rowLoop
+				}
+			}
+			passed = append(passed, row)
+		}
+		rows = passed
+	}
+
+	shown := *base
+	shown.Rows = rows
+	shown.RowCount = len(rows)
+	m.loaded = base
+	m.result = &shown
+	m.ensureVerticalWindow()
+}
+
+// ── Filter bar ──────────────────────────────────────────────────────────
+
+// openFilterBar opens the bar to type a filter and remove others.
+func (m *Model) openFilterBar() {
+	ti := widget.NewInput()
+	ti.Prompt = "Filter: "
+	ti.Placeholder = "amount > 100 and status != 'done'"
+	ti.CharLimit = 300
+	ti.Focus()
+	m.filterInput = ti
+	m.filterErr = ""
+	m.chip = len(m.filters) - 1
+	m.viewMode = ViewFilter
+}
+
+func (m Model) updateFilterBar(msg tea.KeyMsg) (Model, tea.Cmd) {
+	empty := m.filterInput.Value() == ""
+	switch msg.String() {
+	case "esc":
+		m.viewMode = ViewNormal
+		return m, nil
+	case "enter":
+		if empty {
+			m.viewMode = ViewNormal
+			return m, nil
+		}
+		return m.addFilter(), nil
+	case "left":
+		if empty && m.chip > 0 {
+			m.chip--
+			return m, nil
+		}
+	case "right":
+		if empty && m.chip < len(m.filters)-1 {
+			m.chip++
+			return m, nil
+		}
+	case "backspace", "delete":
+		if empty && m.chip >= 0 {
+			m.removeFilter(m.chip)
+			return m, nil
+		}
+	case "ctrl+x":
+		if len(m.filters) > 0 {
+			m.filters = nil
+			m.chip = -1
+			m.derive()
+			m.statusMessage = "Filters cleared"
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.filterErr = ""
+	if text := strings.TrimSpace(m.filterInput.Value()); text != "" {
+		if _, _, err := m.compileFilter(rowFilter{text: text, column: m.getColumnName()}, m.base()); err != nil {
+			m.filterErr = err.Error()
+		}
+	}
+	return m, cmd
+}
+
+// addFilter adds the filter typed in the bar, closing it, or shows why
+// the filter is not valid.
+func (m Model) addFilter() Model {
+	f := rowFilter{text: strings.TrimSpace(m.filterInput.Value()), column: m.getColumnName()}
+	_, defaulted, err := m.compileFilter(f, m.base())
+	if err != nil {
+		m.filterErr = err.Error()
+		return m
+	}
+	if !defaulted {
+		f.column = ""
+	}
+	m.filters = append(m.filters, f)
+	m.viewMode = ViewNormal
+	m.cursorY, m.scrollY = 0, 0
+	m.derive()
+	m.statusMessage = fmt.Sprintf("%d of %d rows pass", m.result.RowCount, m.base().RowCount)
+	return m
+}
+
+// removeFilter removes filter i, showing the rows it kept out again.
+func (m *Model) removeFilter(i int) {
+	m.filters = append(m.filters[:i:i], m.filters[i+1:]...)
+	m.chip = min(m.chip, len(m.filters)-1)
+	m.derive()
+}
+
+// isPlainName reports whether a column name reads back as itself in a
+// filter without quotes.
+func isPlainName(name string) bool {
+	toks, err := lexFilter(name)
+	return err == nil && len(toks) == 2 && toks[0].kind == tokName && toks[0].text == name &&
+		!toks[0].is("and") && !toks[0].is("or") && !toks[0].is("not") && !toks[0].is("is") && !toks[0].is("null")
+}
+
+// renderChips renders the filters in place, one chip each, marking the
+// one Backspace removes while the bar is open.
+func (m Model) renderChips() string {
+	chip := lipgloss.NewStyle().Foreground(theme.ColorHighlight).Background(lipgloss.Color("236"))
+	selected := lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Background(theme.ColorPrimary).Bold(true)
+
+	parts := []string{theme.StyleMuted.Render(" Filters:")}
+	for i, f := range m.filters {
+		style := chip
+		if m.viewMode == ViewFilter && i == m.chip {
+			style = selected
+		}
+		parts = append(parts, style.Render(" "+f.label()+" ×"))
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(strings.Join(parts, " "))
+}
+
+func (m Model) renderFilterBar() string {
+	bar := "  " + m.filterInput.View()
+	switch {
+	case m.filterErr != "":
+		bar += theme.StyleError.Render("  " + m.filterErr)
+	case m.filterInput.Value() == "" && len(m.filters) > 0:
+		bar += theme.StyleMuted.Render("  ←/→ pick filter | Backspace remove | ^X clear all | Esc close")
+	case m.filterInput.Value() == "":
+		bar += theme.StyleMuted.Render("  col ~ /regex/ | col is null | Esc close")
+	default:
+		bar += theme.StyleMuted.Render("  Enter add | Esc close")
+	}
+	return bar
+}
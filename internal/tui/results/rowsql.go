@@ -0,0 +1,338 @@
+package results
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/sql"
+)
+
+// clauseKeywords end the FROM list of a simple SELECT.
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true, "OFFSET": true,
+	"HAVING": true, "WINDOW": true, "FOR": true, "FETCH": true,
+}
+
+// sourceTable finds the single table a SELECT reads from. Queries that
+// join, list several tables or combine selects have no single source.
+func sourceTable(query string) (schema, table string, ok bool) {
+	stmts := sql.Split(query)
+	if len(stmts) != 1 {
+		return "", "", false
+	}
+	toks := sql.Significant(stmts[0].Tokens)
+	if len(toks) < 4 || !toks[0].Is("SELECT") {
+		return "", "", false
+	}
+
+	// the FROM list runs from the first top-level FROM to the next clause
+	from, end, depth := -1, len(toks), 0
+	for i, t := range toks {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth > 0:
+		case t.Is("UNION") || t.Is("INTERSECT") || t.Is("EXCEPT"):
+			return "", "", false
+		case t.Is("FROM") && from < 0:
+			from = i
+		case from >= 0 && end == len(toks) && t.Kind == sql.Keyword && clauseKeywords[strings.ToUpper(t.Text)]:
+			end = i
+		case from >= 0 && end == len(toks) && (t.Is("JOIN") || t.IsPunct(",")):
+			return "", "", false
+		}
+	}
+	if from < 0 {
+		return "", "", false
+	}
+
+	refs := sql.Tables(toks[from:end])
+	if len(refs) != 1 || refs[0].Pos != toks[from+1].Pos {
+		return "", "", false
+	}
+	return refs[0].Schema, refs[0].Name, true
+}
+
+// SourceTable reports the table the current query reads from, when there is
+// exactly one.
+func (m Model) SourceTable() (schema, table string, ok bool) {
+	return sourceTable(m.lastQuery)
+}
+
+// SetTableInfo attaches metadata for the result's source table. It is
+// ignored when it no longer matches the current query.
+func (m *Model) SetTableInfo(info *database.TableInfo) {
+	schema, table, ok := m.SourceTable()
+	if !ok || info == nil || info.Name != table || (schema != "" && info.Schema != schema) {
+		return
+	}
+	m.tableInfo = info
+	if snap := m.current(); snap != nil {
+		snap.info = info
+	}
+}
+
+// columnInfo returns the metadata for a result column. Without table
+// metadata the column is returned with an unknown type.
+func (m Model) columnInfo(name string) (database.Column, bool) {
+	if m.tableInfo == nil {
+		return database.Column{Name: name}, false
+	}
+	return m.tableInfo.Column(name)
+}
+
+// targetTable returns the quoted name for generated statements.
+func (m Model) targetTable() string {
+	if m.tableInfo != nil {
+		return database.QualifiedName(m.tableInfo.Schema, m.tableInfo.Name)
+	}
+	if schema, table, ok := m.SourceTable(); ok {
+		return database.QualifiedName(schema, table)
+	}
+	return extractTableName(m.lastQuery)
+}
+
+// ── Literals ────────────────────────────────────────────────────────────
+
+func isIntegerType(typ string) bool {
+	switch typ {
+	case "smallint", "integer", "bigint", "oid":
+		return true
+	}
+	return false
+}
+
+func isTextType(typ string) bool {
+	return typ == "text" || typ == "name" || typ == "citext" ||
+		strings.HasPrefix(typ, "character varying") ||
+		strings.HasPrefix(typ, "character(") || typ == "character"
+}
+
+// castTypes are displayed verbatim by the driver, so the grid text can be
+// cast back to the column type without loss.
+var castTypes = map[string]bool{
+	"uuid": true, "date": true, "inet": true, "cidr": true,
+	"macaddr": true, "macaddr8": true, "real": true, "double precision": true,
+}
+
+// sqlLiteral renders v as a literal of the column's type. ok is false when
+// the grid shows the type lossily and no exact literal can be rebuilt.
+// Columns of unknown type get a plain string literal and let the server
+// coerce it.
+func sqlLiteral(col database.Column, v string) (string, bool) {
+	typ := strings.ToLower(col.DataType)
+	switch {
+	case typ == "" || isTextType(typ):
+		return database.QuoteLiteral(v), true
+	case isIntegerType(typ):
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return v, true
+		}
+	case typ == "boolean":
+		if v == "true" || v == "false" {
+			return v, true
+		}
+	case typ == "bytea":
+		if hex, found := strings.CutPrefix(v, "0x"); found {
+			return `'\x` + hex + `'::bytea`, true
+		}
+	case castTypes[typ]:
+		return database.QuoteLiteral(v) + "::" + typ, true
+	}
+	return "", false
+}
+
+// cellMatch returns a condition that holds when the column contains the
+// value shown in the grid. exact is false when the display loses
+// information, so the condition may also match neighbouring values.
+func cellMatch(col database.Column, v string) (cond string, exact bool) {
+	ident := database.QuoteIdent(col.Name)
+	if v == "null" {
+		return ident + " IS NULL", true
+	}
+	if lit, ok := sqlLiteral(col, v); ok {
+		return ident + " = " + lit, col.DataType != ""
+	}
+
+	typ := strings.ToLower(col.DataType)
+	lit := database.QuoteLiteral(v)
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		return "to_jsonb(" + ident + ") = " + lit + "::jsonb", false
+	case typ == "json" || typ == "jsonb":
+		return ident + "::jsonb = " + lit + "::jsonb", false
+	case strings.HasPrefix(typ, "numeric"):
+		// numerics are displayed rounded to two decimals
+		return "round(" + ident + ", 2) = " + lit + "::numeric", false
+	case strings.HasPrefix(typ, "timestamp") && strings.HasSuffix(typ, "with time zone"):
+		// timestamptz values are displayed in local time without a zone
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", v, time.Local); err == nil {
+			lit = database.QuoteLiteral(t.Format("2006-01-02 15:04:05-07:00"))
+		}
+		return "date_trunc('second', " + ident + ") = " + lit + "::timestamptz", false
+	case strings.HasPrefix(typ, "timestamp"):
+		return "date_trunc('second', " + ident + ") = " + lit + "::timestamp", false
+	case typ == "bytea":
+		return ident + " = convert_to(" + lit + ", 'UTF8')", false
+	}
+	return ident + "::text = " + lit, false
+}
+
+// ── Row addressing ──────────────────────────────────────────────────────
+
+// rowKey picks the columns that identify the given rows: the primary key,
+// or else the first unique key. A key qualifies only when all its columns
+// are in the result, render as exact literals and are non-null in every row.
+func (m Model) rowKey(rows []int) (cols []string, label string) {
+	if m.tableInfo == nil {
+		return nil, ""
+	}
+
+	usable := func(key []string) bool {
+		if len(key) == 0 {
+			return false
+		}
+		for _, name := range key {
+			idx := m.resultColumnIndex(name)
+			col, ok := m.tableInfo.Column(name)
+			if idx < 0 || !ok {
+				return false
+			}
+			for _, r := range rows {
+				v := m.getCellValueAt(r, idx)
+				if v == "null" {
+					return false
+				}
+				if _, exact := sqlLiteral(col, v); !exact {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if usable(m.tableInfo.PrimaryKey) {
+		return m.tableInfo.PrimaryKey, "primary key"
+	}
+	for _, key := range m.tableInfo.UniqueKeys {
+		if usable(key) {
+			return key, "unique key"
+		}
+	}
+	return nil, ""
+}
+
+func (m Model) resultColumnIndex(name string) int {
+	if m.result == nil {
+		return -1
+	}
+	for i, c := range m.result.Columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// buildDelete generates a DELETE for the given result rows. It never runs
+// anything; the statement goes to the editor for review.
+func (m Model) buildDelete(rows []int) (string, error) {
+	if m.tableInfo == nil {
+		return "", fmt.Errorf("table metadata not available for this result")
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("no rows selected")
+	}
+
+	var b strings.Builder
+	b.WriteString("-- review before executing!\n")
+
+	key, label := m.rowKey(rows)
+	if key != nil {
+		quoted := make([]string, len(key))
+		for i, k := range key {
+			quoted[i] = database.QuoteIdent(k)
+		}
+		fmt.Fprintf(&b, "-- rows matched by %s (%s)\n", label, strings.Join(quoted, ", "))
+		fmt.Fprintf(&b, "DELETE FROM %s\nWHERE %s;", m.targetTable(), m.keyCondition(key, quoted, rows))
+		return b.String(), nil
+	}
+
+	var inexact []string
+	seen := make(map[string]bool)
+	var rowConds []string
+	for _, r := range rows {
+		var conds []string
+		for i, name := range m.result.Columns {
+			col, ok := m.tableInfo.Column(name)
+			if !ok {
+				continue // computed column, not part of the table
+			}
+			cond, exact := cellMatch(col, m.getCellValueAt(r, i))
+			if !exact && !seen[name] {
+				seen[name] = true
+				inexact = append(inexact, database.QuoteIdent(name))
+			}
+			conds = append(conds, cond)
+		}
+		if len(conds) == 0 {
+			return "", fmt.Errorf("result has no columns of %s", m.targetTable())
+		}
+		rowConds = append(rowConds, strings.Join(conds, " AND "))
+	}
+
+	b.WriteString("-- no usable primary or unique key: matching on every column,\n")
+	b.WriteString("-- which also deletes duplicate rows\n")
+	if len(inexact) > 0 {
+		fmt.Fprintf(&b, "-- approximate matches on: %s\n", strings.Join(inexact, ", "))
+	}
+	fmt.Fprintf(&b, "DELETE FROM %s\nWHERE ", m.targetTable())
+	if len(rowConds) == 1 {
+		b.WriteString(rowConds[0])
+	} else {
+		for i, c := range rowConds {
+			if i > 0 {
+				b.WriteString("\n   OR ")
+			}
+			b.WriteString("(" + c + ")")
+		}
+	}
+	b.WriteString(";")
+	return b.String(), nil
+}
+
+// keyCondition renders the WHERE condition for key columns, using IN lists
+// when several rows are targeted.
+func (m Model) keyCondition(key, quoted []string, rows []int) string {
+	tuples := make([]string, len(rows))
+	for ri, r := range rows {
+		lits := make([]string, len(key))
+		for ki, name := range key {
+			col, _ := m.tableInfo.Column(name)
+			lits[ki], _ = sqlLiteral(col, m.getCellValueAt(r, m.resultColumnIndex(name)))
+		}
+		if len(rows) == 1 {
+			conds := make([]string, len(key))
+			for ki := range key {
+				conds[ki] = quoted[ki] + " = " + lits[ki]
+			}
+			return strings.Join(conds, " AND ")
+		}
+		if len(key) == 1 {
+			tuples[ri] = lits[0]
+		} else {
+			tuples[ri] = "(" + strings.Join(lits, ", ") + ")"
+		}
+	}
+
+	target := quoted[0]
+	if len(key) > 1 {
+		target = "(" + strings.Join(quoted, ", ") + ")"
+	}
+	return target + " IN (" + strings.Join(tuples, ", ") + ")"
+}
@@ -0,0 +1,283 @@
+package results
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// match is a cell whose value matches the search.
+type match struct {
+	row int
+	col int // result column
+	pos int // place of the column among the shown ones, to order matches
+}
+
+// searchState is the search over the result's cells. It covers every row
+// fetched, not just the ones on screen, and the shown columns only.
+type searchState struct {
+	input     textinput.Model
+	regex     bool
+	matchCase bool
+	col       int // the only result column searched, -1 for every one
+	matches   []match
+	at        int    // index into matches of the current one, -1 for none
+	err       string // why the pattern does not compile
+
+	// cursor when the prompt opened, restored when it is cancelled
+	fromY, fromX int
+}
+
+// Prompting reports whether a text prompt is open and capturing keys.
+func (m Model) Prompting() bool {
+	return m.viewMode == ViewSearch || m.viewMode == ViewPinPrompt || m.viewMode == ViewFilter
+}
+
+// startSearch opens the search prompt, keeping the last pattern and
+// modes to edit.
+func (m *Model) startSearch() {
+	s := m.search
+	if s == nil {
+		s = &searchState{col: -1}
+		ti := widget.NewInput()
+		ti.Prompt = "/"
+		ti.CharLimit = 200
+		s.input = ti
+	}
+	s.input.Focus()
+	s.input.CursorEnd()
+	s.fromY, s.fromX = m.cursorY, m.cursorX
+	m.search = s
+	m.viewMode = ViewSearch
+	m.findMatches()
+	m.showMatch()
+}
+
+func (m Model) updateSearch(msg tea.KeyMsg) (Model, tea.Cmd) {
+	s := m.search
+	switch msg.String() {
+	case "esc":
+		m.cursorY, m.cursorX = s.fromY, s.fromX
+		m.ensureVerticalWindow()
+		m.ensureHorizontalWindow()
+		m.viewMode = ViewNormal
+		m.search = nil
+		return m, nil
+	case "enter":
+		m.viewMode = ViewNormal
+		s.input.Blur()
+		switch {
+		case s.input.Value() == "":
+			m.search = nil
+		case s.err != "":
+			m.statusMessage = "Bad pattern: " + s.err
+			m.search = nil
+		case len(s.matches) == 0:
+			m.statusMessage = "No matches"
+		}
+		return m, nil
+	case "tab":
+		if s.col < 0 {
+			s.col = s.fromX
+		} else {
+			s.col = -1
+		}
+	case "ctrl+r":
+		s.regex = !s.regex
+	case "ctrl+t":
+		s.matchCase = !s.matchCase
+	default:
+		before := s.input.Value()
+		var cmd tea.Cmd
+		s.input, cmd = s.input.Update(msg)
+		if s.input.Value() == before {
+			return m, cmd
+		}
+		m.findMatches()
+		m.showMatch()
+		return m, cmd
+	}
+	m.findMatches()
+	m.showMatch()
+	return m, nil
+}
+
+// findMatches searches the result again, as the pattern, the modes or
+// the rows change. The current match is the first one from where the
+// search started.
+func (m *Model) findMatches() {
+	s := m.search
+	if s == nil {
+		return
+	}
+	s.matches, s.at, s.err = nil, -1, ""
+	pattern := s.input.Value()
+	if pattern == "" || !m.HasResult() {
+		return
+	}
+
+	var matches func(string) bool
+	switch {
+	case s.regex:
+		if !s.matchCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			s.err = err.Error()
+			return
+		}
+		matches = re.MatchString
+	case s.matchCase:
+		matches = func(v string) bool { return strings.Contains(v, pattern) }
+	default:
+		lower := strings.ToLower(pattern)
+		matches = func(v string) bool { return strings.Contains(strings.ToLower(v), lower) }
+	}
+
+	cols := m.shown()
+	if s.col >= 0 {
+		cols = []int{s.col}
+	}
+	for r, row := range m.result.Rows {
+		for pos, c := range cols {
+			if c < len(row) && matches(row[c]) {
+				s.matches = append(s.matches, match{row: r, col: c, pos: pos})
+			}
+		}
+	}
+	if len(s.matches) == 0 {
+		return
+	}
+
+	// the first match at or after where the search started
+	from := match{row: s.fromY, pos: max(0, slices.Index(cols, s.fromX))}
+	i, _ := slices.BinarySearchFunc(s.matches, from, compareMatches)
+	s.at = i % len(s.matches)
+}
+
+// refreshSearch searches again after the rows or the columns shown
+// changed, keeping the cursor where it is.
+func (m *Model) refreshSearch() {
+	if m.search == nil {
+		return
+	}
+	m.search.fromY, m.search.fromX = m.cursorY, m.cursorX
+	m.findMatches()
+}
+
+func compareMatches(a, b match) int {
+	return cmp.Or(cmp.Compare(a.row, b.row), cmp.Compare(a.pos, b.pos))
+}
+
+// nextMatch moves to the next match in direction dir, wrapping around
+// the ends.
+func (m *Model) nextMatch(dir int) {
+	s := m.search
+	if s == nil || len(s.matches) == 0 {
+		m.statusMessage = "No search: press / to search"
+		if s != nil {
+			m.statusMessage = "No matches"
+		}
+		return
+	}
+	n := len(s.matches)
+	s.at = (s.at + dir + n) % n
+	switch {
+	case dir > 0 && s.at == 0:
+		m.statusMessage = "Search wrapped to the top"
+	case dir < 0 && s.at == n-1:
+		m.statusMessage = "Search wrapped to the bottom"
+	}
+	m.showMatch()
+}
+
+// showMatch puts the cursor on the current match, or back where the
+// search started when there is none.
+func (m *Model) showMatch() {
+	if m.search.at < 0 {
+		m.cursorY, m.cursorX = m.search.fromY, m.search.fromX
+		m.ensureVerticalWindow()
+		m.ensureHorizontalWindow()
+		return
+	}
+	hit := m.search.matches[m.search.at]
+	m.cursorY, m.cursorX = hit.row, hit.col
+	m.ensureVerticalWindow()
+	m.ensureHorizontalWindow()
+}
+
+// rowMatches returns which of cols match the search in row, or nil when
+// none do.
+func (m Model) rowMatches(row int, cols []int) []bool {
+	if m.search == nil {
+		return nil
+	}
+	ms := m.search.matches
+	i, _ := slices.BinarySearchFunc(ms, row, func(h match, row int) int { return cmp.Compare(h.row, row) })
+	var hits []bool
+	for ; i < len(ms) && ms[i].row == row; i++ {
+		if j := slices.Index(cols, ms[i].col); j >= 0 {
+			if hits == nil {
+				hits = make([]bool, len(cols))
+			}
+			hits[j] = true
+		}
+	}
+	return hits
+}
+
+// searchInfo describes the search for the footer.
+func (m Model) searchInfo() string {
+	s := m.search
+	if s == nil || s.input.Value() == "" {
+		return ""
+	}
+	info := "/" + s.input.Value()
+	switch {
+	case s.err != "":
+		return info + " (bad pattern)"
+	case len(s.matches) == 0:
+		return info + " (no matches)"
+	}
+	return info + fmt.Sprintf(" (%d/%d)", s.at+1, len(s.matches))
+}
+
+func (m Model) renderSearchPrompt() string {
+	s := m.search
+	var modes []string
+	if s.col >= 0 {
+		modes = append(modes, "in "+m.result.Columns[s.col])
+	}
+	if s.regex {
+		modes = append(modes, "regex")
+	}
+	if s.matchCase {
+		modes = append(modes, "match case")
+	}
+
+	var status string
+	switch {
+	case s.err != "":
+		status = theme.StyleError.Render("  bad pattern")
+	case s.input.Value() == "":
+	case len(s.matches) == 0:
+		status = theme.StyleMuted.Render("  no matches")
+	default:
+		status = lipgloss.NewStyle().Foreground(theme.ColorHighlight).
+			Render(fmt.Sprintf("  %d/%d", s.at+1, len(s.matches)))
+	}
+	if len(modes) > 0 {
+		status += theme.StyleMuted.Render("  [" + strings.Join(modes, ", ") + "]")
+	}
+	hints := theme.StyleMuted.Render("  Tab column/all | ^R regex | ^T case | Enter keep | Esc cancel")
+	return "  " + s.input.View() + status + hints
+}
@@ -0,0 +1,296 @@
+package results
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/database"
+	"github.com/joacominatel/minadb/internal/sql"
+)
+
+// sortKey is one column the result is sorted by.
+type sortKey struct {
+	col  int
+	desc bool
+}
+
+// valueKind is how a column's values compare.
+type valueKind int
+
+const (
+	kindText valueKind = iota
+	kindNumber
+	kindTime
+)
+
+// timeLayouts are the forms dates and timestamps are displayed in.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"15:04:05",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+}
+
+// numericTypes are the number types besides numeric(p,s).
+var numericTypes = map[string]bool{
+	"smallint": true, "integer": true, "bigint": true, "real": true,
+	"double precision": true, "oid": true,
+}
+
+// cycleSort sorts by the column under the cursor: ascending, then
+// descending, then not at all. With add the column joins the columns
+// already sorted by instead of replacing them.
+func (m *Model) cycleSort(add bool) tea.Cmd {
+	if !m.HasResult() {
+		return nil
+	}
+	keys := slices.Clone(m.sort)
+	i := slices.IndexFunc(keys, func(k sortKey) bool { return k.col == m.cursorX })
+	switch {
+	case i < 0 && add:
+		keys = append(keys, sortKey{col: m.cursorX})
+	case i < 0:
+		keys = []sortKey{{col: m.cursorX}}
+	case !keys[i].desc:
+		keys[i].desc = true
+		if !add {
+			keys = []sortKey{keys[i]}
+		}
+	case add:
+		keys = slices.Delete(keys, i, i+1)
+	default:
+		keys = nil
+	}
+	return m.applySort(keys)
+}
+
+// applySort sorts the rows in memory when the result is complete. A
+// result cut short by the query's LIMIT is asked for again from the
+// server with an ORDER BY, so the sort covers every row and not just the
+// ones shown. Pinned results only sort the rows they hold.
+func (m *Model) applySort(keys []sortKey) tea.Cmd {
+	if m.partial() && m.store != nil {
+		if query, ok := sql.OrderBy(m.lastQuery, m.orderList(keys)); ok && len(keys) > 0 {
+			m.pendingSort = keys
+			m.sortQuery = query
+			m.statusMessage = "Sorting on the server..."
+			return func() tea.Msg { return RerunQueryMsg{Query: query} }
+		}
+	}
+	m.sort = keys
+	m.sortInMemory = len(keys) > 0
+	m.derive()
+	switch {
+	case len(keys) == 0:
+		m.statusMessage = "Original order"
+	case m.partial():
+		m.statusMessage = fmt.Sprintf("Sorted the %d rows shown only", m.base().RowCount)
+	}
+	return nil
+}
+
+// sortRows returns rows sorted by the sort keys, leaving rows as they are.
+func (m Model) sortRows(rows [][]string) [][]string {
+	base := m.base()
+	kinds := make([]valueKind, len(base.Columns))
+	for _, k := range m.sort {
+		kinds[k.col] = m.columnKind(base, k.col)
+	}
+	rows = slices.Clone(rows)
+	slices.SortStableFunc(rows, func(a, b []string) int {
+		for _, k := range m.sort {
+			c := compareValues(a[k.col], b[k.col], kinds[k.col])
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return rows
+}
+
+// base returns the result as it came, before any sort or filter in
+// memory.
+func (m Model) base() *database.QueryResult {
+	if m.loaded != nil {
+		return m.loaded
+	}
+	return m.result
+}
+
+// partial reports whether the query's LIMIT cut the result short, so
+// sorting the rows in memory would not give the rows the query would
+// return in that order.
+func (m Model) partial() bool {
+	n, ok := sql.Limit(m.lastQuery)
+	return ok && m.base().RowCount >= n
+}
+
+// orderList renders keys as an ORDER BY list. Columns are named where
+// the name is unambiguous, by position otherwise. NULLs go where the
+// in-memory sort puts them, which is also the server's default.
+func (m Model) orderList(keys []sortKey) string {
+	cols := m.base().Columns
+	uses := make(map[string]int, len(cols))
+	for _, c := range cols {
+		uses[c]++
+	}
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		name := cols[k.col]
+		expr := strconv.Itoa(k.col + 1)
+		if uses[name] == 1 && name != "?column?" {
+			expr = database.QuoteIdent(name)
+		}
+		if k.desc {
+			expr += " DESC"
+		}
+		parts[i] = expr
+	}
+	return strings.Join(parts, ", ")
+}
+
+// columnKind decides how a column compares: from its type when the
+// source table is known, or else from its values.
+func (m Model) columnKind(r *database.QueryResult, col int) valueKind {
+	if info, ok := m.columnInfo(r.Columns[col]); ok && info.DataType != "" {
+		typ := strings.ToLower(info.DataType)
+		switch {
+		case strings.HasSuffix(typ, "[]"):
+			return kindText
+		case numericTypes[typ] || strings.HasPrefix(typ, "numeric") || strings.HasPrefix(typ, "decimal"):
+			return kindNumber
+		case strings.HasPrefix(typ, "timestamp") || typ == "date" || strings.HasPrefix(typ, "time"):
+			return kindTime
+		}
+		return kindText
+	}
+
+	number, when, seen := true, true, false
+	for _, row := range r.Rows {
+		v := row[col]
+		if v == "null" {
+			continue
+		}
+		seen = true
+		if number {
+			_, number = parseNumber(v)
+		}
+		if when {
+			_, when = parseTime(v)
+		}
+		if !number && !when {
+			return kindText
+		}
+	}
+	switch {
+	case !seen:
+		return kindText
+	case number:
+		return kindNumber
+	case when:
+		return kindTime
+	}
+	return kindText
+}
+
+// compareValues orders two displayed values of a kind. NULLs sort after
+// every value, as PostgreSQL sorts them ascending; values that do not
+// parse as the kind sort after the ones that do.
+func compareValues(a, b string, kind valueKind) int {
+	if a == "null" || b == "null" {
+		return cmp.Compare(boolRank(a == "null"), boolRank(b == "null"))
+	}
+	switch kind {
+	case kindNumber:
+		x, okx := parseNumber(a)
+		y, oky := parseNumber(b)
+		if okx && oky {
+			// NaN is greater than any number in PostgreSQL
+			return cmp.Compare(nanRank(x), nanRank(y))*2 + cmp.Compare(x, y)
+		}
+		if okx != oky {
+			return cmp.Compare(boolRank(!okx), boolRank(!oky))
+		}
+	case kindTime:
+		x, okx := parseTime(a)
+		y, oky := parseTime(b)
+		if okx && oky {
+			return x.Compare(y)
+		}
+		if okx != oky {
+			return cmp.Compare(boolRank(!okx), boolRank(!oky))
+		}
+	}
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func nanRank(f float64) int {
+	return boolRank(math.IsNaN(f))
+}
+
+func parseNumber(v string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	return f, err == nil
+}
+
+// parseTime reads a displayed date or timestamp; infinity and -infinity
+// sort after and before every time.
+func parseTime(v string) (time.Time, bool) {
+	switch v {
+	case "infinity":
+		return time.Date(math.MaxInt32, 1, 1, 0, 0, 0, 0, time.UTC), true
+	case "-infinity":
+		return time.Date(math.MinInt32, 1, 1, 0, 0, 0, 0, time.UTC), true
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// headerLabel adds the sort arrow of column col to its name, keeping the
+// arrow in view when the name is cut to width. With several columns
+// sorted, the arrow carries the column's place in the order.
+func (m Model) headerLabel(col, width int) string {
+	name := m.result.Columns[col]
+	i := slices.IndexFunc(m.sort, func(k sortKey) bool { return k.col == col })
+	if i < 0 {
+		return name
+	}
+	arrow := "▲"
+	if m.sort[i].desc {
+		arrow = "▼"
+	}
+	if len(m.sort) > 1 {
+		arrow += strconv.Itoa(i + 1)
+	}
+	room := width - lipgloss.Width(arrow) - 1
+	if lipgloss.Width(name) > room {
+		name = truncateDisplay(name, room)
+	}
+	return name + " " + arrow
+}
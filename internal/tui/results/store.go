@@ -0,0 +1,185 @@
+package results
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+// maxRecentResults bounds how many past results a tab keeps to flip back
+// through, whatever memory is left.
+const maxRecentResults = 30
+
+// Snapshot is a query result kept after it was shown, to flip back to or
+// to pin under a name.
+type Snapshot struct {
+	Name   string // set once pinned
+	Query  string
+	Result *database.QueryResult // nil once evicted
+	At     time.Time
+
+	info   *database.TableInfo // source table metadata, if it was loaded
+	size   int64
+	pinned bool
+	users  int // tabs showing it or holding it as a diff baseline
+}
+
+// Store holds the recent results of every tab and the pinned ones. The
+// oldest unpinned results are evicted to keep their estimated size
+// within a memory cap; pinned results are never evicted, so pinning is
+// refused when they alone would go over it. Results a tab shows or
+// holds as a diff baseline stay in memory whatever the store does, so
+// they are not evicted either until the tab lets go of them, and count
+// towards the cap meanwhile.
+type Store struct {
+	limit  int64
+	used   int64
+	recent []*Snapshot // unpinned, oldest first
+	pins   []*Snapshot
+}
+
+// NewStore creates a store holding up to limit bytes of results.
+func NewStore(limit int64) *Store {
+	return &Store{limit: limit}
+}
+
+// Pins returns the pinned results in the order they were pinned.
+func (s *Store) Pins() []*Snapshot {
+	return s.pins
+}
+
+// Usage returns the estimated size of the results held and the cap.
+func (s *Store) Usage() (used, limit int64) {
+	return s.used, s.limit
+}
+
+// keep adds a result just shown, evicting older ones as needed. The new
+// one itself is kept even when it is over the cap on its own, as it is
+// on screen anyway.
+func (s *Store) keep(snap *Snapshot) {
+	snap.size = resultSize(snap.Result)
+	s.used += snap.size
+	s.recent = append(s.recent, snap)
+	s.evict(snap)
+}
+
+// use marks a result as in use by a tab.
+func (s *Store) use(snap *Snapshot) {
+	snap.users++
+}
+
+// unuse undoes use, evicting the result if the store is over its cap.
+func (s *Store) unuse(snap *Snapshot) {
+	snap.users--
+	s.evict(nil)
+}
+
+// evict drops the oldest unpinned results other than keep and those in
+// use until the store fits its cap.
+func (s *Store) evict(keep *Snapshot) {
+	for i := 0; s.used > s.limit && i < len(s.recent); {
+		if s.recent[i] == keep || s.recent[i].users > 0 {
+			i++
+			continue
+		}
+		s.drop(s.recent[i])
+	}
+}
+
+// drop forgets an unpinned result.
+func (s *Store) drop(snap *Snapshot) {
+	i := slices.Index(s.recent, snap)
+	if i < 0 {
+		return
+	}
+	s.recent = slices.Delete(s.recent, i, i+1)
+	s.used -= snap.size
+	snap.Result = nil
+	snap.info = nil
+}
+
+// release forgets the unpinned results of a closed tab, or those a tab
+// no longer keeps. Results still in use are left to evict.
+func (s *Store) release(snaps []*Snapshot) {
+	for _, snap := range snaps {
+		if !snap.pinned && snap.users == 0 {
+			s.drop(snap)
+		}
+	}
+}
+
+// Pin keeps a result under name until it is unpinned. Pinning a pinned
+// result renames it.
+func (s *Store) Pin(snap *Snapshot, name string) error {
+	if snap.Result == nil {
+		return fmt.Errorf("the result was evicted")
+	}
+	if snap.pinned {
+		snap.Name = name
+		return nil
+	}
+	var pinned int64
+	for _, p := range s.pins {
+		pinned += p.size
+	}
+	if pinned+snap.size > s.limit {
+		return fmt.Errorf("pinned results would use %s of the %s cap; unpin some first",
+			FormatBytes(pinned+snap.size), FormatBytes(s.limit))
+	}
+	if i := slices.Index(s.recent, snap); i >= 0 {
+		s.recent = slices.Delete(s.recent, i, i+1)
+	}
+	snap.Name = name
+	snap.pinned = true
+	s.pins = append(s.pins, snap)
+	s.evict(nil)
+	return nil
+}
+
+// Unpin lets a pinned result be evicted again like any recent one.
+func (s *Store) Unpin(snap *Snapshot) {
+	i := slices.Index(s.pins, snap)
+	if i < 0 {
+		return
+	}
+	s.pins = slices.Delete(s.pins, i, i+1)
+	snap.pinned = false
+	at, _ := slices.BinarySearchFunc(s.recent, snap.At, func(r *Snapshot, t time.Time) int {
+		return r.At.Compare(t)
+	})
+	s.recent = slices.Insert(s.recent, at, snap)
+	s.evict(nil)
+}
+
+// resultSize estimates the memory a result takes: its strings and the
+// headers of the slices holding them.
+func resultSize(r *database.QueryResult) int64 {
+	const header = 16
+	size := int64(64)
+	for _, c := range r.Columns {
+		size += header + int64(len(c))
+	}
+	for _, row := range r.Rows {
+		size += 24
+		for _, v := range row {
+			size += header + int64(len(v))
+		}
+	}
+	return size
+}
+
+// FormatBytes renders a byte count for humans, e.g. "12.3 MB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
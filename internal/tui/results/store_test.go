@@ -0,0 +1,188 @@
+package results
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joacominatel/minadb/internal/database"
+)
+
+// snapshots returns n snapshots of equal size, a second apart, and that
+// size.
+func snapshots(n int) ([]*Snapshot, int64) {
+	out := make([]*Snapshot, n)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range out {
+		r := &database.QueryResult{Columns: []string{"v"}, Rows: [][]string{{strings.Repeat("x", 100)}}}
+		out[i] = &Snapshot{Query: string(rune('a' + i)), Result: r, At: at.Add(time.Duration(i) * time.Second)}
+	}
+	return out, resultSize(out[0].Result)
+}
+
+func TestStore(t *testing.T) {
+	type op struct {
+		do   string // keep, use, unuse, pin, unpin, release
+		snap int
+	}
+	tests := []struct {
+		name string
+		fits int // results that fit under the cap
+		ops  []op
+		held string // queries of the snapshots still holding a result
+	}{
+		{
+			name: "oldest evicted first",
+			fits: 2,
+			ops:  []op{{"keep", 0}, {"keep", 1}, {"keep", 2}},
+			held: "bc",
+		},
+		{
+			name: "newest kept when over the cap alone",
+			fits: 0,
+			ops:  []op{{"keep", 0}, {"keep", 1}},
+			held: "b",
+		},
+		{
+			name: "results in use are not evicted",
+			fits: 2,
+			ops:  []op{{"keep", 0}, {"use", 0}, {"keep", 1}, {"keep", 2}},
+			held: "ac",
+		},
+		{
+			name: "evicted once no longer used",
+			fits: 2,
+			ops:  []op{{"keep", 0}, {"use", 0}, {"keep", 1}, {"use", 1}, {"keep", 2}, {"unuse", 0}},
+			held: "bc",
+		},
+		{
+			name: "pinned results are not evicted",
+			fits: 2,
+			ops:  []op{{"keep", 0}, {"pin", 0}, {"keep", 1}, {"keep", 2}},
+			held: "ac",
+		},
+		{
+			name: "unpinned results are evicted again",
+			fits: 1,
+			ops:  []op{{"keep", 0}, {"pin", 0}, {"keep", 1}, {"keep", 2}, {"unpin", 0}},
+			held: "c",
+		},
+		{
+			name: "release drops unused results",
+			fits: 3,
+			ops:  []op{{"keep", 0}, {"keep", 1}, {"keep", 2}, {"use", 1}, {"pin", 2}, {"release", 0}, {"release", 1}, {"release", 2}},
+			held: "bc",
+		},
+	}
+	for _, tt := range tests {
+		snaps, size := snapshots(3)
+		s := NewStore(int64(tt.fits) * size)
+		for _, o := range tt.ops {
+			snap := snaps[o.snap]
+			switch o.do {
+			case "keep":
+				s.keep(snap)
+			case "use":
+				s.use(snap)
+			case "unuse":
+				s.unuse(snap)
+			case "pin":
+				if err := s.Pin(snap, "pin"); err != nil {
+					t.Fatalf("%s: Pin: %v", tt.name, err)
+				}
+			case "unpin":
+				s.Unpin(snap)
+			case "release":
+				s.release([]*Snapshot{snap})
+			}
+		}
+
+		var held strings.Builder
+		var want int64
+		for i, snap := range snaps {
+			kept := slices.ContainsFunc(tt.ops, func(o op) bool { return o.do == "keep" && o.snap == i })
+			if kept && snap.Result != nil {
+				held.WriteString(snap.Query)
+				want += size
+			}
+		}
+		if held.String() != tt.held {
+			t.Errorf("%s: held %q, want %q", tt.name, held.String(), tt.held)
+		}
+		if used, _ := s.Usage(); used != want {
+			t.Errorf("%s: used %d, want %d for the results held", tt.name, used, want)
+		}
+	}
+}
+
+func TestStorePinCap(t *testing.T) {
+	snaps, size := snapshots(3)
+	s := NewStore(2 * size)
+	for _, snap := range snaps[:2] {
+		s.keep(snap)
+		if err := s.Pin(snap, snap.Query); err != nil {
+			t.Fatalf("Pin(%s): %v", snap.Query, err)
+		}
+	}
+	s.keep(snaps[2])
+	if err := s.Pin(snaps[2], "c"); err == nil {
+		t.Error("Pin over the cap succeeded")
+	}
+	if err := s.Pin(snaps[0], "renamed"); err != nil || snaps[0].Name != "renamed" {
+		t.Errorf("renaming a pin: %v, name %q", err, snaps[0].Name)
+	}
+	if got := s.Pins(); !slices.Equal(got, snaps[:2]) {
+		t.Errorf("Pins() = %v, want the first two", got)
+	}
+
+	// unpinned, the oldest result is evicted to fit the cap
+	s.Unpin(snaps[0])
+	if snaps[0].Result != nil {
+		t.Error("unpinned result over the cap was not evicted")
+	}
+	if err := s.Pin(snaps[0], "again"); err == nil {
+		t.Error("pinned an evicted result")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 << 20, "5.0 MB"},
+		{3 << 30, "3.0 GB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestModelHoldsResultOnScreen(t *testing.T) {
+	snaps, size := snapshots(2)
+	s := NewStore(size)
+	a, b := New(), New()
+	a.SetStore(s)
+	b.SetStore(s)
+
+	a.SetResult(snaps[0].Result)
+	b.SetResult(snaps[1].Result)
+	if used, _ := s.Usage(); used != 2*size {
+		t.Fatalf("used %d with two results on screen, want %d", used, 2*size)
+	}
+	if a.current() == nil {
+		t.Fatal("the result tab a shows was evicted")
+	}
+
+	a.Release()
+	if used, _ := s.Usage(); used != size {
+		t.Errorf("used %d after tab a let go, want %d", used, size)
+	}
+}
@@ -0,0 +1,265 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/history"
+	"github.com/joacominatel/minadb/internal/tui/editor"
+	"github.com/joacominatel/minadb/internal/tui/results"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/tui/widget"
+)
+
+// tab is one editor buffer with the result of its last query. The
+// active tab's editor and results live in Model.editor and
+// Model.results; the copies here are only current for the others.
+type tab struct {
+	id         int // stable across closing other tabs, to route query results
+	name       string
+	target     int // index into the saved connections, -1 for the main connection
+	editor     editor.Model
+	results    results.Model
+	running    bool
+	lastParams []history.Param // bound to the last query run
+	path       string          // file the tab was opened from or saved to
+	saved      string          // content as last read from or written to path
+}
+
+// newTab adds an empty tab after the active one and switches to it.
+func (m *Model) newTab() {
+	m.nextTab++
+	t := tab{
+		id:      m.nextTab,
+		name:    fmt.Sprintf("Query %d", m.nextTab),
+		target:  -1,
+		editor:  m.editor.Blank(),
+		results: m.newResults(),
+	}
+	at := m.activeTab + 1
+	m.tabs = append(m.tabs[:at], append([]tab{t}, m.tabs[at:]...)...)
+	m.switchTab(at)
+}
+
+// newResults returns an empty results pane for a tab.
+func (m Model) newResults() results.Model {
+	r := results.New()
+	r.SetConnections(m.connectionNames())
+	r.SetStore(m.store)
+	r.SetLayouts(m.layouts)
+	return r
+}
+
+// closeTab closes the active tab. The last tab is never closed; it is
+// emptied instead.
+func (m *Model) closeTab() {
+	m.results.Release()
+	if len(m.tabs) == 1 {
+		m.editor.Clear()
+		m.results = m.newResults()
+		// a file's name goes with its content
+		id := m.tabs[0].id
+		m.tabs[0] = tab{id: id, name: fmt.Sprintf("Query %d", id), target: m.tabs[0].target}
+		m.layout()
+		m.setFocus(m.activePane)
+		return
+	}
+	closed := m.activeTab
+	m.tabs = append(m.tabs[:closed], m.tabs[closed+1:]...)
+	next := min(closed, len(m.tabs)-1)
+	// the closed tab's models are dropped rather than saved
+	m.activeTab = next
+	m.loadTab()
+}
+
+// switchTab makes tab i the active one, keeping the state of the one
+// it replaces.
+func (m *Model) switchTab(i int) {
+	if i < 0 || i >= len(m.tabs) || i == m.activeTab {
+		return
+	}
+	m.tabs[m.activeTab].editor = m.editor
+	m.tabs[m.activeTab].results = m.results
+	m.activeTab = i
+	m.loadTab()
+}
+
+// loadTab moves the active tab's models into place.
+func (m *Model) loadTab() {
+	t := &m.tabs[m.activeTab]
+	m.editor = t.editor
+	m.results = t.results
+	m.editor.SetHistory(m.service.History())
+	m.layout()
+	m.setFocus(m.activePane)
+}
+
+// tabIndex returns the index of the tab with the given id, or -1 when it
+// was closed.
+func (m Model) tabIndex(id int) int {
+	for i, t := range m.tabs {
+		if t.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// tabResults returns the results of tab i, wherever they live.
+func (m *Model) tabResults(i int) *results.Model {
+	if i == m.activeTab {
+		return &m.results
+	}
+	return &m.tabs[i].results
+}
+
+// eachEditor applies f to the editor of every tab.
+func (m *Model) eachEditor(f func(*editor.Model)) {
+	f(&m.editor)
+	for i := range m.tabs {
+		if i != m.activeTab {
+			f(&m.tabs[i].editor)
+		}
+	}
+}
+
+// eachResults applies f to the results of every tab.
+func (m *Model) eachResults(f func(*results.Model)) {
+	f(&m.results)
+	for i := range m.tabs {
+		if i != m.activeTab {
+			f(&m.tabs[i].results)
+		}
+	}
+}
+
+// tabDSN returns the DSN queries of tab i run on, "" for the main
+// connection.
+func (m Model) tabDSN(i int) string {
+	t := m.tabs[i]
+	if t.target < 0 || t.target >= len(m.cfg.Connections) {
+		return ""
+	}
+	return m.cfg.Connections[t.target].DSN()
+}
+
+// cycleTarget points the active tab at the next saved connection, or
+// back at the main one.
+func (m *Model) cycleTarget() {
+	t := &m.tabs[m.activeTab]
+	t.target++
+	if t.target >= len(m.cfg.Connections) {
+		t.target = -1
+	}
+	if t.target < 0 {
+		m.statusbar.SetMessage(t.name + " runs on the main connection")
+	} else {
+		m.statusbar.SetMessage(t.name + " runs on " + m.cfg.Connections[t.target].Name)
+	}
+}
+
+// updateTabs handles the tab keys, reporting whether msg was one.
+func (m Model) updateTabs(msg tea.KeyMsg) (Model, bool) {
+	key := msg.String()
+	switch key {
+	case "alt+t":
+		m.newTab()
+	case "alt+w":
+		tm, _ := m.closeActiveTab()
+		return tm.(Model), true
+	case "alt+r":
+		ti := widget.NewInput()
+		ti.Prompt = "Rename tab: "
+		ti.CharLimit = 40
+		ti.SetValue(m.tabs[m.activeTab].name)
+		ti.CursorEnd()
+		ti.Focus()
+		m.tabInput = ti
+		m.renamingTab = true
+	case "alt+c":
+		m.cycleTarget()
+	case "alt+]", "ctrl+pgdown":
+		m.switchTab((m.activeTab + 1) % len(m.tabs))
+	case "alt+[", "ctrl+pgup":
+		m.switchTab((m.activeTab + len(m.tabs) - 1) % len(m.tabs))
+	default:
+		n, ok := strings.CutPrefix(key, "alt+")
+		i, err := strconv.Atoi(n)
+		if !ok || err != nil || i < 1 || i > 9 {
+			return m, false
+		}
+		m.switchTab(i - 1)
+	}
+	return m, true
+}
+
+// updateRename handles keys while the active tab's name is edited.
+func (m Model) updateRename(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		if name := strings.TrimSpace(m.tabInput.Value()); name != "" {
+			m.tabs[m.activeTab].name = name
+		}
+		m.renamingTab = false
+		return m, nil
+	case "esc":
+		m.renamingTab = false
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.tabInput, cmd = m.tabInput.Update(msg)
+	return m, cmd
+}
+
+// viewTabs renders the tab strip above the editor.
+func (m Model) viewTabs(width int) string {
+	if m.renamingTab {
+		return " " + m.tabInput.View()
+	}
+	active := lipgloss.NewStyle().
+		Foreground(theme.ColorHighlight).
+		Background(lipgloss.Color("236")).
+		Bold(true)
+	running := lipgloss.NewStyle().Foreground(theme.ColorWarning).Render("●")
+
+	var parts []string
+	for i, t := range m.tabs {
+		label := fmt.Sprintf(" %d %s", i+1, t.name)
+		if m.dirty(i) {
+			label += "*"
+		}
+		if t.target >= 0 && t.target < len(m.cfg.Connections) {
+			label += " @" + m.cfg.Connections[t.target].Name
+		}
+		if i == m.activeTab {
+			label = active.Render(label + " ")
+		} else {
+			label = theme.StyleMuted.Render(label + " ")
+		}
+		if t.running {
+			label += running
+		}
+		parts = append(parts, label)
+	}
+	strip := strings.Join(parts, theme.StyleMuted.Render("│"))
+	if lipgloss.Width(strip) > width {
+		// keep the active tab in view
+		strip = m.viewTabsFrom(parts, width)
+	}
+	return strip
+}
+
+// viewTabsFrom drops tabs from the left until the active one fits.
+func (m Model) viewTabsFrom(parts []string, width int) string {
+	sep := theme.StyleMuted.Render("│")
+	for from := 1; from <= m.activeTab; from++ {
+		strip := theme.StyleMuted.Render("…") + sep + strings.Join(parts[from:], sep)
+		if lipgloss.Width(strip) <= width {
+			return strip
+		}
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(strings.Join(parts[m.activeTab:], sep))
+}
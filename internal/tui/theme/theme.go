@@ -14,6 +14,17 @@ var (
 	ColorHighlight = lipgloss.Color("229") // Yellow
 )
 
+// Syntax colours for SQL in the query editor.
+var (
+	ColorKeyword  = lipgloss.Color("75")  // Blue
+	ColorIdent    = lipgloss.Color("252") // Off-white
+	ColorString   = lipgloss.Color("114") // Green
+	ColorNumber   = lipgloss.Color("173") // Salmon
+	ColorComment  = lipgloss.Color("243") // Gray
+	ColorOperator = lipgloss.Color("180") // Tan
+	ColorParam    = lipgloss.Color("176") // Pink
+)
+
 // Shared styles used across TUI components.
 var (
 	StyleBorder = lipgloss.NewStyle().
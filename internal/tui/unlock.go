@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"errors"
+	"os"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joacominatel/minadb/internal/tui/theme"
+	"github.com/joacominatel/minadb/internal/vault"
+)
+
+// vaultUnlockedMsg carries the vault opened with the passphrase typed at
+// startup.
+type vaultUnlockedMsg struct {
+	vault *vault.Vault
+	err   error
+}
+
+// openStorage loads the vault that history and the schema cache are kept
+// with. A passphrase vault without MINADB_PASSPHRASE set switches to the
+// unlock prompt, which asks twice when the vault is new; any other
+// failure leaves storage disabled with a warning.
+func (m *Model) openStorage() {
+	prefs := m.cfg.Preferences.Encryption
+	v, err := vault.Load(prefs, os.Getenv(vault.PassphraseEnv))
+	if errors.Is(err, vault.ErrLocked) || errors.Is(err, vault.ErrNewPassphrase) {
+		m.newVault = errors.Is(err, vault.ErrNewPassphrase)
+		ti := textinput.New()
+		ti.Placeholder = "passphrase"
+		ti.EchoMode = textinput.EchoPassword
+		ti.EchoCharacter = '•'
+		ti.Width = 40
+		ti.Focus()
+		m.unlockInput = ti
+		m.nextMode = m.mode
+		m.mode = ModeUnlock
+		return
+	}
+	m.useVault(v, err)
+}
+
+// useVault hands the vault to the service, or records why storage is
+// unavailable.
+func (m *Model) useVault(v *vault.Vault, err error) {
+	if err == nil {
+		err = m.service.UseVault(v)
+	}
+	m.storageErr = err
+	m.editor.SetHistory(m.service.History())
+	m.layouts.Load(m.service.Layouts())
+}
+
+func (m Model) unlockCmd(passphrase string) tea.Cmd {
+	prefs := m.cfg.Preferences.Encryption
+	return func() tea.Msg {
+		v, err := vault.Load(prefs, passphrase)
+		return vaultUnlockedMsg{vault: v, err: err}
+	}
+}
+
+func (m Model) updateUnlock(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.unlocking {
+		return m, nil
+	}
+	switch msg.String() {
+	case "enter":
+		if m.unlockInput.Value() == "" {
+			return m, nil
+		}
+		if m.newVault && m.firstEntry == "" {
+			m.firstEntry = m.unlockInput.Value()
+			m.unlockInput.Reset()
+			m.err = nil
+			return m, nil
+		}
+		if m.newVault && m.unlockInput.Value() != m.firstEntry {
+			m.firstEntry = ""
+			m.unlockInput.Reset()
+			m.err = errors.New("passphrases do not match")
+			return m, nil
+		}
+		m.unlocking = true
+		m.err = nil
+		return m, m.unlockCmd(m.unlockInput.Value())
+	case "esc":
+		// go on without history or cache rather than lock the user out
+		m.useVault(nil, errors.New("local data is locked"))
+		return m.leaveUnlock()
+	}
+
+	var cmd tea.Cmd
+	m.unlockInput, cmd = m.unlockInput.Update(msg)
+	return m, cmd
+}
+
+// leaveUnlock continues to the screen startup would have shown, making
+// the connection given on the command line now that storage is settled.
+func (m Model) leaveUnlock() (tea.Model, tea.Cmd) {
+	m.mode = m.nextMode
+	m.unlockInput.Reset()
+	m.firstEntry = ""
+	if m.initialDSN != "" {
+		return m, m.connectCmd(m.initialDSN)
+	}
+	return m, nil
+}
+
+func (m Model) viewUnlock() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Bold(true).
+		Padding(1, 0)
+
+	title := titleStyle.Render("minadb")
+	label := "Passphrase for history and cached data:"
+	switch {
+	case m.newVault && m.firstEntry == "":
+		label = "New passphrase to encrypt history and cached data:"
+	case m.newVault:
+		label = "Repeat the new passphrase:"
+	}
+	prompt := lipgloss.NewStyle().
+		Foreground(theme.ColorPrimary).
+		Render(label)
+
+	status := ""
+	switch {
+	case m.unlocking:
+		status = "\n" + theme.StyleMuted.Render("  Unlocking...")
+	case m.err != nil:
+		status = "\n" + theme.StyleError.Render("  Error: "+m.err.Error())
+	}
+	hint := theme.StyleMuted.Render("  Enter: Unlock │ Esc: Continue without history │ Ctrl+C: Quit")
+
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		"",
+		title,
+		"",
+		prompt,
+		"  "+m.unlockInput.View(),
+		status,
+		"",
+		hint,
+	)
+
+	return lipgloss.Place(m.width, m.height,
+		lipgloss.Center, lipgloss.Center,
+		content,
+	)
+}
+
+// storageWarning explains, on the connection screens, that history and
+// the schema cache are not being saved.
+func (m Model) storageWarning() string {
+	if m.storageErr == nil {
+		return ""
+	}
+	return theme.StyleWarning.Render("  History and schema cache are not saved: " + m.storageErr.Error())
+}
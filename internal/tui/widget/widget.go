@@ -0,0 +1,46 @@
+// Package widget holds small helpers shared by the TUI's list screens
+// and prompts.
+package widget
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// NewInput returns a text input with a solid cursor. The app does not
+// route blink messages to its components, so a blinking cursor would
+// stay hidden.
+func NewInput() textinput.Model {
+	ti := textinput.New()
+	ti.Cursor.SetMode(cursor.CursorStatic)
+	return ti
+}
+
+// Truncate shortens s to width display columns, ending it with an
+// ellipsis when it is cut. Tabs count as four spaces.
+func Truncate(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	if width <= 0 || lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes)) > width-1 {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// Scroll returns the first row to show of a list height rows tall so the
+// cursor stays in view, moving it from scroll as little as possible.
+func Scroll(cursor, scroll, height int) int {
+	if cursor < scroll {
+		return cursor
+	}
+	if cursor >= scroll+height {
+		return cursor - height + 1
+	}
+	return scroll
+}
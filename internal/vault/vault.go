@@ -0,0 +1,422 @@
+// Package vault encrypts the data minadb keeps on disk — query history
+// and cached schema metadata — with AES-256-GCM. The key is either a
+// random key kept in the OS keyring or derived from a passphrase with
+// PBKDF2. ~/.minadb/vault.json records which, with the salt for a
+// passphrase and an identifier of the key, but never the key itself.
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joacominatel/minadb/internal/config"
+)
+
+// Source is where the key comes from.
+type Source string
+
+const (
+	Keyring    Source = "keyring"
+	Passphrase Source = "passphrase"
+	None       Source = "none" // data is stored in plain text
+)
+
+// ParseSource validates a key source name. Empty means Keyring.
+func ParseSource(s string) (Source, error) {
+	switch src := Source(strings.ToLower(s)); src {
+	case "":
+		return Keyring, nil
+	case Keyring, Passphrase, None:
+		return src, nil
+	}
+	return "", fmt.Errorf("unknown key source %q: use keyring, passphrase or none", s)
+}
+
+// PassphraseEnv is the environment variable a passphrase is read from
+// before asking for one.
+const PassphraseEnv = "MINADB_PASSPHRASE"
+
+var (
+	// ErrLocked means the key is derived from a passphrase that was not
+	// given.
+	ErrLocked = errors.New("a passphrase is needed to unlock local data")
+	// ErrNewPassphrase means the vault does not exist yet and its key is
+	// to be derived from a passphrase that was not given.
+	ErrNewPassphrase = errors.New("a passphrase is needed to encrypt local data")
+	// ErrWrongPassphrase means the passphrase does not derive the key the
+	// data was encrypted with.
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrUnknownKey means data was encrypted with a key this vault does
+	// not have.
+	ErrUnknownKey = errors.New("data was encrypted with another key")
+)
+
+const (
+	keySize    = 32
+	saltSize   = 16
+	iterations = 600_000
+)
+
+// magic starts every sealed blob, followed by the key id, the nonce and
+// the ciphertext.
+var magic = []byte("MDB1")
+
+// meta is the content of vault.json.
+type meta struct {
+	Version    int    `json:"version"`
+	Source     Source `json:"source"`
+	KeyID      string `json:"key_id"`
+	Salt       []byte `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	// Previous holds the keys replaced by a rotation still in progress,
+	// one after the other and sealed with the current key, so data not
+	// yet re-encrypted stays readable. A rotation run again after it was
+	// interrupted adds to them.
+	Previous []byte `json:"previous,omitempty"`
+}
+
+// Vault seals and opens data with the current key.
+type Vault struct {
+	source Source
+	id     string
+	key    []byte
+	older  map[string][]byte // keys of a rotation in progress, by id
+	meta   meta
+}
+
+// Source returns where the vault's key comes from.
+func (v *Vault) Source() Source {
+	return v.source
+}
+
+// KeyID identifies the current key, or is empty for plain text.
+func (v *Vault) KeyID() string {
+	return v.id
+}
+
+// Rotating reports whether data sealed with a key replaced by Rotate may
+// still be on disk.
+func (v *Vault) Rotating() bool {
+	return len(v.older) > 0
+}
+
+// Encrypted reports whether the vault encrypts what it seals.
+func (v *Vault) Encrypted() bool {
+	return v.key != nil
+}
+
+// Load opens the vault described by vault.json, creating it as the
+// preferences say when it does not exist yet. passphrase is only used for
+// a passphrase vault; without it Load returns ErrLocked, or
+// ErrNewPassphrase when the vault is yet to be created.
+func Load(prefs config.Encryption, passphrase string) (*Vault, error) {
+	m, err := readMeta()
+	if errors.Is(err, fs.ErrNotExist) {
+		src, err := ParseSource(prefs.KeySource)
+		if err != nil {
+			return nil, err
+		}
+		return create(src, passphrase, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := unlock(m, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Previous) > 0 {
+		keys, err := v.open(m.Previous)
+		if err == nil && len(keys)%keySize != 0 {
+			err = errors.New("not a list of keys")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read previous keys: %w", err)
+		}
+		v.older = make(map[string][]byte)
+		for ; len(keys) > 0; keys = keys[keySize:] {
+			v.older[keyID(keys[:keySize])] = keys[:keySize]
+		}
+	}
+	return v, nil
+}
+
+// unlock obtains the key m describes.
+func unlock(m meta, passphrase string) (*Vault, error) {
+	v := &Vault{source: m.Source, meta: m}
+	switch m.Source {
+	case None:
+		return v, nil
+	case Keyring:
+		encoded, err := config.GetDataKey(m.KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("read key from keyring: %w", err)
+		}
+		if v.key, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("read key from keyring: %w", err)
+		}
+	case Passphrase:
+		if passphrase == "" {
+			return nil, ErrLocked
+		}
+		key, err := pbkdf2.Key(sha256.New, passphrase, m.Salt, m.Iterations, keySize)
+		if err != nil {
+			return nil, err
+		}
+		if keyID(key) != m.KeyID {
+			return nil, ErrWrongPassphrase
+		}
+		v.key = key
+	default:
+		return nil, fmt.Errorf("unknown key source %q in vault.json", m.Source)
+	}
+	if keyID(v.key) != m.KeyID {
+		return nil, ErrUnknownKey
+	}
+	v.id = m.KeyID
+	return v, nil
+}
+
+// create makes a new vault with a fresh key and saves its description.
+// When previous is given, its key and those it kept from an unfinished
+// rotation are kept so data they sealed can still be opened until Finish
+// is called.
+func create(src Source, passphrase string, previous *Vault) (*Vault, error) {
+	v := &Vault{source: src, meta: meta{Version: 1, Source: src}}
+	switch src {
+	case None:
+	case Keyring:
+		v.key = make([]byte, keySize)
+		if _, err := rand.Read(v.key); err != nil {
+			return nil, err
+		}
+	case Passphrase:
+		if passphrase == "" {
+			return nil, ErrNewPassphrase
+		}
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+		if err != nil {
+			return nil, err
+		}
+		v.key = key
+		v.meta.Salt = salt
+		v.meta.Iterations = iterations
+	}
+
+	if v.key != nil {
+		v.id = keyID(v.key)
+		v.meta.KeyID = v.id
+	}
+	if previous != nil {
+		v.older = make(map[string][]byte)
+		for id, key := range previous.older {
+			v.older[id] = key
+		}
+		if previous.key != nil {
+			v.older[previous.id] = previous.key
+		}
+		delete(v.older, v.id)
+		if len(v.older) == 0 {
+			v.older = nil
+		}
+	}
+	if v.key != nil && len(v.older) > 0 {
+		var keys []byte
+		for _, key := range v.older {
+			keys = append(keys, key...)
+		}
+		sealed, err := v.Seal(keys)
+		if err != nil {
+			return nil, err
+		}
+		v.meta.Previous = sealed
+	}
+
+	// the key goes to the keyring before vault.json names it, so a
+	// failure leaves the old vault in place
+	if src == Keyring {
+		if err := config.SaveDataKey(v.id, base64.StdEncoding.EncodeToString(v.key)); err != nil {
+			return nil, fmt.Errorf("store key in keyring: %w", err)
+		}
+	}
+	// going back to plain text keeps the old description until Finish,
+	// as there is nowhere to keep the old keys meanwhile
+	if src == None && len(v.older) > 0 {
+		return v, nil
+	}
+	if err := writeMeta(v.meta); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Rotate replaces the vault's key with a new one from src, which may
+// differ from the current source. The old key stays available for
+// opening data until Finish is called, so callers re-encrypt their files
+// in between and a crash part way loses nothing.
+func Rotate(old *Vault, src Source, passphrase string) (*Vault, error) {
+	return create(src, passphrase, old)
+}
+
+// Finish forgets the keys replaced by Rotate once all data has been
+// re-encrypted, removing any the keyring holds.
+func (v *Vault) Finish() error {
+	v.meta.Previous = nil
+	if err := writeMeta(v.meta); err != nil {
+		return err
+	}
+	for id := range v.older {
+		// keys derived from a passphrase were never in the keyring
+		_ = config.DeleteDataKey(id)
+	}
+	v.older = nil
+	return nil
+}
+
+// Seal encrypts data with the current key. A plain vault returns data
+// unchanged.
+func (v *Vault) Seal(data []byte) ([]byte, error) {
+	if v.key == nil {
+		return data, nil
+	}
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := hex.DecodeString(v.id)
+	header := append(append([]byte{}, magic...), id...)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(header, nonce...)
+	return gcm.Seal(out, nonce, data, header), nil
+}
+
+// Open decrypts data sealed by this vault or, during a rotation, by the
+// one it replaces. Data that was never sealed is returned unchanged, so
+// files written before encryption was enabled stay readable.
+func (v *Vault) Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	return v.open(data)
+}
+
+func (v *Vault) open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return nil, errors.New("not sealed data")
+	}
+	header := data[:len(magic)+8]
+	id := hex.EncodeToString(header[len(magic):])
+	key := v.older[id]
+	if id == v.id {
+		key = v.key
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	rest := data[len(header):]
+	if len(rest) < gcm.NonceSize() {
+		return nil, errors.New("sealed data is truncated")
+	}
+	return gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], header)
+}
+
+// IsSealed reports whether data was produced by Seal with a key.
+func IsSealed(data []byte) bool {
+	return len(data) >= len(magic)+8 && bytes.HasPrefix(data, magic)
+}
+
+// SealLine seals data into a single line of text, for files that are
+// appended to one record at a time. A plain vault returns data as is,
+// which must then not contain newlines.
+func (v *Vault) SealLine(data []byte) (string, error) {
+	sealed, err := v.Seal(data)
+	if err != nil || v.key == nil {
+		return string(sealed), err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenLine reverses SealLine. Lines that are not base64, such as JSON
+// written before encryption was enabled, are returned unchanged.
+func (v *Vault) OpenLine(line string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || !IsSealed(raw) {
+		return []byte(line), nil
+	}
+	return v.open(raw)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyID names a key without revealing it: the first 8 bytes of its
+// SHA-256, in hex.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func readMeta() (meta, error) {
+	var m meta
+	path, err := config.VaultPath()
+	if err != nil {
+		return m, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("read %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func writeMeta(m meta) error {
+	path, err := config.VaultPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,196 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/joacominatel/minadb/internal/config"
+)
+
+// newVault creates a vault under a fresh home directory.
+func newVault(t *testing.T, src Source, passphrase string) *Vault {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	v, err := Load(config.Encryption{KeySource: string(src)}, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestSealOpen(t *testing.T) {
+	v := newVault(t, Passphrase, "secret")
+	tests := [][]byte{
+		[]byte(""),
+		[]byte("select 1"),
+		bytes.Repeat([]byte{0, 1, 2, '\n'}, 1000),
+	}
+	for _, data := range tests {
+		sealed, err := v.Seal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsSealed(sealed) {
+			t.Errorf("Seal(%q) is not sealed", data)
+		}
+		if len(data) > 0 && bytes.Contains(sealed, data) {
+			t.Errorf("Seal(%q) holds the plain text", data)
+		}
+		got, err := v.Open(sealed)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Open(Seal(%q)) = %q, %v", data, got, err)
+		}
+	}
+
+	// plain text written before encryption was enabled reads as is
+	if got, err := v.Open([]byte(`{"a":1}`)); err != nil || string(got) != `{"a":1}` {
+		t.Errorf("Open(plain) = %q, %v", got, err)
+	}
+
+	sealed, _ := v.Seal([]byte("data"))
+	sealed[len(sealed)-1] ^= 1
+	if _, err := v.Open(sealed); err == nil {
+		t.Error("Open accepted tampered data")
+	}
+	if _, err := v.Open(sealed[:len(magic)+10]); err == nil {
+		t.Error("Open accepted truncated data")
+	}
+}
+
+func TestSealLine(t *testing.T) {
+	tests := []struct {
+		src        Source
+		passphrase string
+	}{
+		{Passphrase, "secret"},
+		{None, ""},
+	}
+	for _, tt := range tests {
+		v := newVault(t, tt.src, tt.passphrase)
+		data := []byte(`{"query":"select 1"}`)
+		line, err := v.SealLine(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(line, "\n") {
+			t.Errorf("%s: SealLine wrote a newline", tt.src)
+		}
+		if got, err := v.OpenLine(line); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: OpenLine(SealLine) = %q, %v", tt.src, got, err)
+		}
+		if v.Encrypted() != (tt.src != None) {
+			t.Errorf("%s: Encrypted() = %v", tt.src, v.Encrypted())
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prefs := config.Encryption{KeySource: "passphrase"}
+	if _, err := Load(prefs, ""); !errors.Is(err, ErrNewPassphrase) {
+		t.Errorf("Load of a new vault without a passphrase: %v, want ErrNewPassphrase", err)
+	}
+	v, err := Load(prefs, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := v.Seal([]byte("data"))
+
+	tests := []struct {
+		passphrase string
+		err        error
+	}{
+		{"", ErrLocked},
+		{"wrong", ErrWrongPassphrase},
+		{"secret", nil},
+	}
+	for _, tt := range tests {
+		again, err := Load(prefs, tt.passphrase)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Load(%q) error = %v, want %v", tt.passphrase, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if again.KeyID() != v.KeyID() {
+			t.Errorf("Load(%q) key id = %s, want %s", tt.passphrase, again.KeyID(), v.KeyID())
+		}
+		if got, err := again.Open(sealed); err != nil || string(got) != "data" {
+			t.Errorf("reloaded vault Open = %q, %v", got, err)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	a := newVault(t, Passphrase, "a")
+	fromA, _ := a.Seal([]byte("a"))
+
+	// a rotation interrupted before Finish, run again with another key
+	if _, err := Rotate(a, Passphrase, "b"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(config.Encryption{}, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Rotating() {
+		t.Fatal("interrupted rotation not reported")
+	}
+	fromB, _ := b.Seal([]byte("b"))
+	c, err := Rotate(b, Passphrase, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := Load(config.Encryption{}, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []*Vault{c, reloaded} {
+		for _, sealed := range [][]byte{fromA, fromB} {
+			if _, err := v.Open(sealed); err != nil {
+				t.Errorf("data sealed before the rotation: %v", err)
+			}
+		}
+	}
+
+	if err := c.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Rotating() {
+		t.Error("still rotating after Finish")
+	}
+	if _, err := c.Open(fromA); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open with a forgotten key: %v, want ErrUnknownKey", err)
+	}
+	finished, err := Load(config.Encryption{}, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finished.Rotating() {
+		t.Error("finished rotation still recorded in vault.json")
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Source
+		ok   bool
+	}{
+		{"", Keyring, true},
+		{"keyring", Keyring, true},
+		{"Passphrase", Passphrase, true},
+		{"none", None, true},
+		{"aes", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSource(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseSource(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}